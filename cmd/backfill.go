@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"github.com/cstobie/ai-commit/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// backfillCmd represents the backfill command
+var backfillCmd = &cobra.Command{
+	Use:   "backfill --range <from>..<to>",
+	Short: "Generate new messages for a range of existing commits",
+	Long: `Generate a new message for each non-merge commit in a range from its diff, and
+write a plan of the old and new messages as JSON, to stdout or --plan. Commits
+whose message cannot be generated are listed with the error. History is not changed
+unless --apply is given.
+
+With --apply the commits are reworded after confirmation: the commits from the oldest
+one to HEAD are recreated with the same content and authors, so their hashes change.
+The range must end at an ancestor of HEAD with no merges in between, and the working
+tree must be clean. Avoid rewriting commits that were already pushed.
+
+Examples:
+  ai-commit backfill --range HEAD~10..HEAD
+  ai-commit backfill --range v1.0..HEAD --plan plan.json
+  ai-commit backfill --range HEAD~3..HEAD --apply`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Configure logging from --log-level and --verbose
+		verbose := setupLogging(cmd)
+
+		// Get flag values
+		revRange, _ := cmd.Flags().GetString("range")
+		planFile, _ := cmd.Flags().GetString("plan")
+		apply, _ := cmd.Flags().GetBool("apply")
+
+		// Cancel on Ctrl-C; API requests are bounded by the configured timeout
+		ctx, stop := signalContext()
+		defer stop()
+
+		return handleAbort(ctx, app.RunBackfill(ctx, cfg, verbose, revRange, planFile, apply))
+	},
+}
+
+func init() {
+	// Define flags
+	backfillCmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging (same as --log-level debug)")
+	backfillCmd.Flags().String("range", "", "Commits to regenerate messages for, e.g. HEAD~10..HEAD")
+	backfillCmd.Flags().String("plan", "", "Write the plan to this file instead of stdout")
+	backfillCmd.Flags().Bool("apply", false, "Reword the commits with the new messages after confirmation")
+	backfillCmd.MarkFlagRequired("range")
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/cstobie/ai-commit/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that ai-commit is set up correctly",
+	Long: `Check that git is installed, the current directory is a git repository, the API key,
+model and template are configured, and the API endpoint is reachable.
+
+Prints a checklist with a suggested fix for each failed check and exits with a
+non-zero status if any check failed.
+
+Examples:
+  ai-commit doctor`,
+	Args: cobra.NoArgs,
+	// Failed checks are not usage errors
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Configure logging from --log-level and --verbose
+		setupLogging(cmd)
+
+		// Cancel on Ctrl-C; the connectivity check is bounded by the configured timeout
+		ctx, stop := signalContext()
+		defer stop()
+
+		return handleAbort(ctx, app.RunDoctor(ctx, cfg))
+	},
+}
+
+func init() {
+	// Define flags
+	doctorCmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging (same as --log-level debug)")
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/cstobie/ai-commit/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// explainCmd represents the explain command
+var explainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Summarize staged changes in plain English",
+	Long: `Summarize staged changes in plain English, e.g. to paste into a pull request description.
+
+Examples:
+  ai-commit explain
+  ai-commit explain --output json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Configure logging from --log-level and --verbose
+		verbose := setupLogging(cmd)
+
+		// Get flag values
+		output, _ := cmd.Flags().GetString("output")
+
+		// Cancel on Ctrl-C; API requests are bounded by the configured timeout
+		ctx, stop := signalContext()
+		defer stop()
+
+		return handleAbort(ctx, app.RunExplain(ctx, cfg, verbose, output))
+	},
+}
+
+func init() {
+	// Define flags
+	explainCmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging (same as --log-level debug)")
+	explainCmd.Flags().StringP("output", "o", app.OutputText, "Output format: text or json")
+}
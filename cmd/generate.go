@@ -1,13 +1,17 @@
 package cmd
 
 import (
-	"context"
+	"fmt"
 	"io"
-	"log"
-	"time"
+	"os"
+	"regexp"
+	"strings"
 
 	"github.com/cstobie/ai-commit/internal/app"
+	"github.com/cstobie/ai-commit/internal/commit"
+	"github.com/cstobie/ai-commit/internal/config"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // generateCmd represents the generate command
@@ -17,35 +21,194 @@ var generateCmd = &cobra.Command{
 	Short:   "Generate commit message for staged changes",
 	Long: `Generate commit message for staged changes based on the specified template.
 
+By default the message is shown with a menu to commit, edit, regenerate or
+abort. With --no-interactive (-n) it is only printed, and nothing is
+committed. With --yes (-y) it is printed and committed without reading stdin,
+for scripts; checks that would ask, like the secret scan, act as they do with
+--no-interactive.
+
 Examples:
   ai-commit generate
   ai-commit gen -v
-  AICOMMIT_TEMPLATE_NAME=simple ai-commit gen`,
+  ai-commit gen --model anthropic/claude-3-haiku --temperature 0.2
+  git diff main | ai-commit gen --diff-stdin
+  ai-commit gen -n -q | pbcopy
+  ai-commit gen -y --hint "bump the API client"
+  ai-commit gen --push
+  ai-commit gen --hint "users were logged out on every deploy"
+  ai-commit gen -n --output-file "$1"   # in a prepare-commit-msg hook
+  ai-commit gen -t simple`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// Configure logging from --log-level and --verbose
+		verbose := setupLogging(cmd)
+
 		// Get flag values
-		verbose, _ := cmd.Flags().GetBool("verbose")
 		noInteractive, _ := cmd.Flags().GetBool("no-interactive")
-		
-		// Configure logging based on verbose flag
-		if !verbose {
-			log.SetOutput(io.Discard)
-		}
 
-		// Create a context with timeout
-		ctx, cancel := context.WithTimeout(
-			context.Background(), 
-			time.Duration(cfg.TimeoutSeconds)*time.Second,
-		)
-		defer cancel()
+		// Apply per-invocation flag overrides to a copy of the global config
+		runCfg, err := effectiveConfig(cmd)
+		if err != nil {
+			return err
+		}
+		
+		// Cancel on Ctrl-C; API requests are bounded by the configured timeout
+		ctx, stop := signalContext()
+		defer stop()
 
 		// Run the generate command with interactive mode by default
 		interactive := !noInteractive
-		return app.RunGenerate(ctx, cfg, verbose, interactive)
+		return handleAbort(ctx, app.RunGenerate(ctx, runCfg, verbose, interactive))
 	},
 }
 
+// authorRegex matches an author given as "Name <email>"
+var authorRegex = regexp.MustCompile(`^[^<>\s][^<>]* <[^<>\s]+>$`)
+
+// effectiveConfig returns the global config with any explicitly set flags applied
+func effectiveConfig(cmd *cobra.Command) (config.Config, error) {
+	runCfg := cfg
+	flags := cmd.Flags()
+
+	if showWhitespace, _ := flags.GetBool("show-whitespace"); showWhitespace {
+		runCfg.IgnoreWhitespace = false
+	}
+	if flags.Changed("pathspec") {
+		runCfg.Pathspecs, _ = flags.GetStringSlice("pathspec")
+	}
+	runCfg.Quiet, _ = flags.GetBool("quiet")
+	if noAttribution, _ := flags.GetBool("no-attribution"); noAttribution {
+		runCfg.MessageFooter = ""
+	}
+	if flags.Changed("diff-file") {
+		runCfg.DiffFile, _ = flags.GetString("diff-file")
+	}
+	if diffStdin, _ := flags.GetBool("diff-stdin"); diffStdin {
+		runCfg.DiffFile = "-"
+	}
+	if flags.Changed("hint") {
+		hint, _ := flags.GetString("hint")
+		if hint == "-" {
+			if runCfg.DiffFile == "-" {
+				return config.Config{}, fmt.Errorf("--hint - and --diff-stdin cannot both read stdin")
+			}
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return config.Config{}, fmt.Errorf("failed to read hint: %w", err)
+			}
+			hint = string(data)
+		}
+		runCfg.Hint = strings.TrimSpace(hint)
+	}
+	runCfg.DebugPrompt, _ = flags.GetBool("debug-prompt")
+	runCfg.PromptOut, _ = flags.GetString("prompt-out")
+	runCfg.OutputFile, _ = flags.GetString("output-file")
+	if flags.Changed("gpg-sign") {
+		runCfg.GPGSign, _ = flags.GetString("gpg-sign")
+	}
+	if flags.Changed("author") {
+		runCfg.Author, _ = flags.GetString("author")
+		if !authorRegex.MatchString(runCfg.Author) {
+			return config.Config{}, fmt.Errorf("invalid author '%s': expected \"Name <email>\"", runCfg.Author)
+		}
+	}
+	runCfg.Date, _ = flags.GetString("date")
+	if flags.Changed("issue") {
+		issue, _ := flags.GetString("issue")
+		key, err := commit.ParseIssueKey(issue)
+		if err != nil {
+			return config.Config{}, err
+		}
+		runCfg.Issue, runCfg.SmartCommit = key, true
+	}
+	runCfg.Yes, _ = flags.GetBool("yes")
+	runCfg.Push, _ = flags.GetBool("push")
+	if flags.Changed("push-to") {
+		pushTo, _ := flags.GetString("push-to")
+		remote, branch, _ := strings.Cut(pushTo, ":")
+		if remote == "" {
+			return config.Config{}, fmt.Errorf("invalid --push-to '%s': expected <remote>[:<branch>]", pushTo)
+		}
+		runCfg.Push, runCfg.PushRemote, runCfg.PushBranch = true, remote, branch
+	}
+	if flags.Changed("template") {
+		// An explicit template also replaces the choice by change size
+		runCfg.TemplateName, _ = flags.GetString("template")
+		runCfg.ParsedTemplateRules = nil
+	}
+	if flags.Changed("model") {
+		model, _ := flags.GetString("model")
+		runCfg.UseModel(model)
+	}
+	if flags.Changed("temperature") {
+		temperature, _ := flags.GetFloat64("temperature")
+		if temperature < 0 || temperature > 2 {
+			return config.Config{}, fmt.Errorf("temperature must be between 0 and 2, got %g", temperature)
+		}
+		runCfg.SetTemperature(temperature)
+	}
+
+	return runCfg, nil
+}
+
 func init() {
 	// Define flags
-	generateCmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging")
-	generateCmd.Flags().BoolP("no-interactive", "n", false, "Generate message without interactive confirmation")
+	generateCmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging (same as --log-level debug)")
+	generateCmd.Flags().BoolP("no-interactive", "n", false, "Generate and print the message without committing")
+	generateCmd.Flags().BoolP("yes", "y", false, "Commit the generated message without asking; prompts get their non-interactive answers")
+	generateCmd.Flags().Bool("push", false, "Push to the upstream after committing; asks first unless --yes is set")
+	generateCmd.Flags().String("push-to", "", "Like --push, pushing to <remote>[:<branch>] instead of the upstream")
+	generateCmd.Flags().BoolP("quiet", "q", false, "Print only the message on stdout; notes and usage go to stderr")
+	generateCmd.Flags().Int("context", 3, "Lines of diff context to send around each change")
+	generateCmd.Flags().Bool("show-whitespace", false, "Include whitespace-only changes in the diff")
+	generateCmd.Flags().Bool("ignore-binary", false, "Leave binary files out of the diff and file list")
+	generateCmd.Flags().StringP("template", "t", "", "Template for this invocation, e.g. simple (overrides AICOMMIT_TEMPLATE_NAME and AICOMMIT_TEMPLATE_RULES)")
+	generateCmd.Flags().String("model", "", "Model to use for this invocation (overrides AICOMMIT_LLM_MODEL)")
+	generateCmd.Flags().Float64("temperature", 0, "Temperature between 0 and 2 for this invocation (overrides AICOMMIT_TEMPERATURE)")
+	generateCmd.Flags().Bool("deterministic", false, "Use temperature 0 and a fixed seed for reproducible output (if the provider honors seeds)")
+	generateCmd.Flags().Bool("structured", false, "Request JSON output from the model and format the message locally")
+	generateCmd.Flags().Bool("detailed", false, "Add a body with one bullet per significant file (two LLM calls)")
+	generateCmd.Flags().Bool("subject-only", false, "Generate a one-line subject without a body")
+	generateCmd.Flags().Bool("no-llm", false, "Build the message from the changed files alone, without calling the API")
+	generateCmd.Flags().StringSlice("pathspec", nil, "Only describe and commit these paths (repeatable); commits their working tree state")
+	generateCmd.Flags().String("hint", "", "Why the change was made, to guide the message; \"-\" reads it from stdin")
+	generateCmd.Flags().String("lang", "", "Natural language for the message, e.g. Japanese (default English)")
+	generateCmd.Flags().Bool("localize-type", false, "Translate the conventional commit type prefix as well")
+	generateCmd.Flags().Bool("smart-commit", false, "Format the message as a Jira smart commit for the issue key in the branch name")
+	generateCmd.Flags().String("issue", "", "Jira issue key for a smart commit, e.g. PROJ-123 (implies --smart-commit)")
+	generateCmd.Flags().Bool("no-attribution", false, "Do not add AICOMMIT_MESSAGE_FOOTER to the message")
+	generateCmd.Flags().String("diff-file", "", "Generate a message for the diff in this file instead of staged changes; nothing is committed")
+	generateCmd.Flags().Bool("diff-stdin", false, "Like --diff-file, reading the diff from stdin")
+	generateCmd.Flags().Bool("debug-prompt", false, "Write the full prompt to stderr before calling the API, with likely secrets masked")
+	generateCmd.Flags().String("prompt-out", "", "Like --debug-prompt, writing the prompt to this file instead")
+	generateCmd.Flags().String("output-file", "", "Write the message to this file instead of printing it, and do not commit (for prepare-commit-msg hooks)")
+	generateCmd.Flags().Int("retry-empty", 2, "Regenerate up to this many times when the model returns an empty message")
+	generateCmd.Flags().Bool("fallback-editor", false, "If generation fails, offer to write the message in your editor and commit it")
+	generateCmd.Flags().String("author", "", "Override the commit author, as \"Name <email>\"")
+	generateCmd.Flags().String("date", "", "Override the author date, in any format git commit --date accepts")
+	generateCmd.Flags().String("gpg-sign", "", "GPG-sign the commit, optionally with this key ID (signs anyway when commit.gpgsign is set)")
+	generateCmd.Flags().Lookup("gpg-sign").NoOptDefVal = config.GPGSignDefaultKey
+	generateCmd.MarkFlagsMutuallyExclusive("deterministic", "temperature")
+	generateCmd.MarkFlagsMutuallyExclusive("detailed", "subject-only")
+	generateCmd.MarkFlagsMutuallyExclusive("no-llm", "detailed")
+	generateCmd.MarkFlagsMutuallyExclusive("no-llm", "structured")
+	generateCmd.MarkFlagsMutuallyExclusive("diff-file", "diff-stdin", "pathspec")
+	generateCmd.MarkFlagsMutuallyExclusive("yes", "no-interactive")
+	generateCmd.MarkFlagsMutuallyExclusive("yes", "output-file")
+	generateCmd.MarkFlagsMutuallyExclusive("push", "push-to")
+	generateCmd.MarkFlagsMutuallyExclusive("push", "no-interactive")
+	generateCmd.MarkFlagsMutuallyExclusive("push-to", "no-interactive")
+
+	// Flags override the matching AICOMMIT_ environment variables when set
+	viper.BindPFlag("DIFF_CONTEXT", generateCmd.Flags().Lookup("context"))
+	viper.BindPFlag("IGNORE_BINARY", generateCmd.Flags().Lookup("ignore-binary"))
+	viper.BindPFlag("STRUCTURED", generateCmd.Flags().Lookup("structured"))
+	viper.BindPFlag("DETERMINISTIC", generateCmd.Flags().Lookup("deterministic"))
+	viper.BindPFlag("DETAILED", generateCmd.Flags().Lookup("detailed"))
+	viper.BindPFlag("SUBJECT_ONLY", generateCmd.Flags().Lookup("subject-only"))
+	viper.BindPFlag("NO_LLM", generateCmd.Flags().Lookup("no-llm"))
+	viper.BindPFlag("RETRY_EMPTY", generateCmd.Flags().Lookup("retry-empty"))
+	viper.BindPFlag("FALLBACK_EDITOR", generateCmd.Flags().Lookup("fallback-editor"))
+	viper.BindPFlag("SMART_COMMIT", generateCmd.Flags().Lookup("smart-commit"))
+	viper.BindPFlag("LANGUAGE", generateCmd.Flags().Lookup("lang"))
+	viper.BindPFlag("LOCALIZE_TYPE", generateCmd.Flags().Lookup("localize-type"))
 }
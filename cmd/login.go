@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/cstobie/ai-commit/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// loginCmd represents the login command
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store the API key in the OS keychain",
+	Long: `Store the API key for the configured provider (AICOMMIT_PROVIDER) in the OS
+keychain: the macOS Keychain, the Secret Service on Linux, or the Windows Credential
+Manager. The key is read without echo, or from stdin when piped, so it stays out of
+shell history and config files. Set AICOMMIT_SECRET_SOURCE=keychain to use it; a key
+set in the environment still takes precedence.
+
+Examples:
+  ai-commit login
+  AICOMMIT_PROVIDER=azure ai-commit login
+  pass show openrouter | ai-commit login`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Configure logging from --log-level and --verbose
+		setupLogging(cmd)
+
+		ctx, stop := signalContext()
+		defer stop()
+		return handleAbort(ctx, app.RunLogin(ctx, cfg))
+	},
+}
+
+func init() {
+	// Define flags
+	loginCmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging (same as --log-level debug)")
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/cstobie/ai-commit/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// modelsCmd represents the models command
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List the model ids available from the API",
+	Long: `List the model ids available from the API, for use in AICOMMIT_LLM_MODEL or --model.
+The list is cached for a day.
+
+Examples:
+  ai-commit models
+  ai-commit models --filter claude`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Configure logging from --log-level and --verbose
+		setupLogging(cmd)
+
+		// Get flag values
+		filter, _ := cmd.Flags().GetString("filter")
+
+		// Cancel on Ctrl-C; the request is bounded by the configured timeout
+		ctx, stop := signalContext()
+		defer stop()
+
+		return handleAbort(ctx, app.RunModels(ctx, cfg, filter))
+	},
+}
+
+func init() {
+	// Define flags
+	modelsCmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging (same as --log-level debug)")
+	modelsCmd.Flags().String("filter", "", "Only list model ids containing this text")
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/cstobie/ai-commit/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// prCmd represents the pr command
+var prCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "Generate a pull request title and description for the current branch",
+	Long: `Generate a GitHub pull request title and description, a "## Summary" heading
+and a bullet list, for the commits on HEAD since it diverged from a base revision,
+from their combined diff (git diff <base>...HEAD) and messages. The base defaults
+to origin/main, falling back to origin/HEAD, origin/master, main and master.
+Nothing is committed.
+
+Examples:
+  ai-commit pr
+  ai-commit pr --base origin/develop
+  ai-commit pr --output-file pr.md && gh pr create --body-file pr.md --title "$(head -1 pr.md)"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Configure logging from --log-level and --verbose
+		verbose := setupLogging(cmd)
+
+		// Get flag values
+		base, _ := cmd.Flags().GetString("base")
+		runCfg := cfg
+		runCfg.OutputFile, _ = cmd.Flags().GetString("output-file")
+
+		// Cancel on Ctrl-C; API requests are bounded by the configured timeout
+		ctx, stop := signalContext()
+		defer stop()
+
+		return handleAbort(ctx, app.RunPR(ctx, runCfg, verbose, base))
+	},
+}
+
+func init() {
+	// Define flags
+	prCmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging (same as --log-level debug)")
+	prCmd.Flags().String("base", "", "Base revision the branch will merge into (default origin/main)")
+	prCmd.Flags().String("output-file", "", "Write the title and description to this file instead of printing them")
+}
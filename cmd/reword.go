@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/cstobie/ai-commit/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// rewordCmd represents the reword command
+var rewordCmd = &cobra.Command{
+	Use:   "reword <rev>",
+	Short: "Generate a new message for an existing commit",
+	Long: `Generate a new message for an existing commit from its diff and rewrite it.
+
+The commit must be HEAD or an ancestor of HEAD reached through single-parent commits.
+The commits after it are recreated with the same content and authors, so their hashes
+change: avoid rewording commits that were already pushed. The working tree must be clean.
+
+Examples:
+  ai-commit reword HEAD
+  ai-commit reword HEAD~1
+  ai-commit reword HEAD~1 -n   # only print the new message`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Configure logging from --log-level and --verbose
+		verbose := setupLogging(cmd)
+
+		// Get flag values
+		noInteractive, _ := cmd.Flags().GetBool("no-interactive")
+
+		// Cancel on Ctrl-C; API requests are bounded by the configured timeout
+		ctx, stop := signalContext()
+		defer stop()
+
+		return handleAbort(ctx, app.RunReword(ctx, cfg, verbose, !noInteractive, args[0]))
+	},
+}
+
+func init() {
+	// Define flags
+	rewordCmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging (same as --log-level debug)")
+	rewordCmd.Flags().BoolP("no-interactive", "n", false, "Print the new message without rewriting the commit")
+}
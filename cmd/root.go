@@ -1,19 +1,26 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/logging"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
-// Version information
-const version = "0.1.0"
-
 // Global configuration variable
 var cfg config.Config
 
+// Path to the dotenv file given with --env-file
+var envFile string
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "ai-commit",
@@ -35,9 +42,30 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(initConfig)
 
-	// Add the generate command
+	// Add the subcommands
 	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(suggestSplitsCmd)
+	rootCmd.AddCommand(rewordCmd)
+	rootCmd.AddCommand(backfillCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(trailerCmd)
+	rootCmd.AddCommand(modelsCmd)
+	rootCmd.AddCommand(summarizeCmd)
+	rootCmd.AddCommand(prCmd)
+	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(versionCmd)
 	
+	// Add env file flag, shared by all subcommands
+	rootCmd.PersistentFlags().StringVar(&envFile, "env-file", "", "Path to a .env file to load (default \".env\" in the current directory)")
+
+	// Add log level flag, shared by all subcommands
+	rootCmd.PersistentFlags().String("log-level", "warn", "Log level: debug, info, warn or error (logs go to stderr)")
+	viper.BindPFlag("LOG_LEVEL", rootCmd.PersistentFlags().Lookup("log-level"))
+	// Add color flag; colors are also off when NO_COLOR is set or output is not a terminal
+	rootCmd.PersistentFlags().Bool("no-color", false, "Disable colored output")
+	viper.BindPFlag("NO_COLOR", rootCmd.PersistentFlags().Lookup("no-color"))
+
 	// Add version flag
 	rootCmd.Flags().BoolP("version", "V", false, "Print version information and exit")
 	rootCmd.RunE = func(cmd *cobra.Command, args []string) error {
@@ -53,10 +81,38 @@ func init() {
 	}
 }
 
+// setupLogging configures the logger from the config and the command's
+// --verbose flag, which maps to debug level. It reports whether debug
+// logging is enabled.
+func setupLogging(cmd *cobra.Command) bool {
+	// The level was validated when the config was loaded
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+	if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
+		level = slog.LevelDebug
+	}
+	logging.Setup(level)
+	return level <= slog.LevelDebug
+}
+
+// signalContext returns a context that is cancelled on SIGINT or SIGTERM
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// handleAbort turns an error caused by a cancelled ctx into a short "Aborted."
+// message and the conventional exit status for an interrupt
+func handleAbort(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "Aborted.")
+		os.Exit(130)
+	}
+	return err
+}
+
 // initConfig reads in config file and ENV variables if set
 func initConfig() {
 	var err error
-	cfg, err = config.LoadConfig()
+	cfg, err = config.LoadConfig(envFile)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/cstobie/ai-commit/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// suggestSplitsCmd represents the suggest-splits command
+var suggestSplitsCmd = &cobra.Command{
+	Use:   "suggest-splits",
+	Short: "Propose splitting staged changes into several commits",
+	Long: `Group staged changes by top-level directory and change type and propose a commit message for each group.
+
+Nothing is committed unless --apply is given. With --apply the index is reset and each
+group is staged and committed in turn, as its files were staged. Changes left unstaged stay
+unstaged.
+
+Examples:
+  ai-commit suggest-splits
+  ai-commit suggest-splits --apply`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Configure logging from --log-level and --verbose
+		verbose := setupLogging(cmd)
+
+		// Get flag values
+		apply, _ := cmd.Flags().GetBool("apply")
+
+		// Cancel on Ctrl-C; API requests are bounded by the configured timeout
+		ctx, stop := signalContext()
+		defer stop()
+
+		return handleAbort(ctx, app.RunSuggestSplits(ctx, cfg, verbose, apply))
+	},
+}
+
+func init() {
+	// Define flags
+	suggestSplitsCmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging (same as --log-level debug)")
+	suggestSplitsCmd.Flags().Bool("apply", false, "Reset the index and create one commit per group")
+}
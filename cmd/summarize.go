@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/cstobie/ai-commit/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// summarizeCmd represents the summarize command
+var summarizeCmd = &cobra.Command{
+	Use:   "summarize --since <rev>",
+	Short: "Generate one message for all commits since a base revision",
+	Long: `Generate one commit message describing all commits on HEAD since it diverged from
+a base revision, from their combined diff (git diff <rev>...HEAD) and messages. Useful
+as the message of a squash merge. Only the message is printed; nothing is committed.
+
+Examples:
+  ai-commit summarize --since origin/main
+  ai-commit summarize --since HEAD~5 | pbcopy`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Configure logging from --log-level and --verbose
+		verbose := setupLogging(cmd)
+
+		// Get flag values
+		since, _ := cmd.Flags().GetString("since")
+
+		// Cancel on Ctrl-C; API requests are bounded by the configured timeout
+		ctx, stop := signalContext()
+		defer stop()
+
+		return handleAbort(ctx, app.RunSummarize(ctx, cfg, verbose, since))
+	},
+}
+
+func init() {
+	// Define flags
+	summarizeCmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging (same as --log-level debug)")
+	summarizeCmd.Flags().String("since", "", "Base revision, e.g. origin/main")
+	summarizeCmd.MarkFlagRequired("since")
+}
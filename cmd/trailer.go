@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/cstobie/ai-commit/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// trailerCmd represents the trailer command
+var trailerCmd = &cobra.Command{
+	Use:   "trailer <trailer>...",
+	Short: "Append trailers to the last commit without regenerating its message",
+	Long: `Append git trailers such as "Refs: #123" or "Co-authored-by: Name <email>" to the
+message of the last commit and amend it. The rest of the message is kept as it is and the
+API is not called. Trailers already in the message are skipped. Nothing may be staged.
+
+Examples:
+  ai-commit trailer "Refs: #123"
+  ai-commit trailer "Co-authored-by: Jane Doe <jane@example.com>" "Reviewed-by: Bob <bob@example.com>"`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Configure logging from --log-level and --verbose
+		setupLogging(cmd)
+
+		return app.RunTrailer(args)
+	},
+}
+
+func init() {
+	// Define flags
+	trailerCmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging (same as --log-level debug)")
+}
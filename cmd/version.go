@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// Build metadata, set at build time with e.g.
+// -ldflags "-X github.com/cstobie/ai-commit/cmd.gitCommit=$(git rev-parse HEAD)"
+var (
+	version   = "0.1.0"
+	gitCommit = "" // Git commit the binary was built from
+	buildDate = "" // Build date
+)
+
+// versionCmd represents the version command
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version and build information",
+	Long: `Print the version, the git commit and date it was built from, and the Go version
+and platform, e.g. to include in bug reports.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		printVersion(cmd.OutOrStdout())
+	},
+}
+
+// printVersion writes the version and build metadata. Without a commit or
+// date set at build time, the commit and commit date Go embeds in binaries
+// built from a checkout are used.
+func printVersion(w io.Writer) {
+	revision, built, modified := gitCommit, buildDate, false
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if revision == "" {
+					revision = setting.Value
+				}
+			case "vcs.time":
+				if built == "" {
+					built = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true" && gitCommit == ""
+			}
+		}
+	}
+	if revision == "" {
+		revision = "unknown"
+	} else if modified {
+		revision += " (modified)"
+	}
+	if built == "" {
+		built = "unknown"
+	}
+
+	fmt.Fprintf(w, "ai-commit version %s\n", version)
+	fmt.Fprintf(w, "  commit:   %s\n", revision)
+	fmt.Fprintf(w, "  date:     %s\n", built)
+	fmt.Fprintf(w, "  go:       %s\n", runtime.Version())
+	fmt.Fprintf(w, "  platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPrintVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		gitCommit string
+		buildDate string
+		want      []string
+	}{
+		{"injected metadata", "abc1234", "2026-01-02T03:04:05Z", []string{"commit:   abc1234\n", "date:     2026-01-02T03:04:05Z\n"}},
+		{"build info fallback", "", "", []string{"commit:   ", "date:     "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldCommit, oldDate := gitCommit, buildDate
+			t.Cleanup(func() { gitCommit, buildDate = oldCommit, oldDate })
+			gitCommit, buildDate = tt.gitCommit, tt.buildDate
+
+			var out bytes.Buffer
+			printVersion(&out)
+			want := append([]string{
+				"ai-commit version " + version + "\n",
+				"go:       " + runtime.Version() + "\n",
+				"platform: " + runtime.GOOS + "/" + runtime.GOARCH + "\n",
+			}, tt.want...)
+			for _, w := range want {
+				if !strings.Contains(out.String(), w) {
+					t.Errorf("output does not include %q:\n%s", w, out.String())
+				}
+			}
+		})
+	}
+}
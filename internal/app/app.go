@@ -1,146 +1,529 @@
 package app
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
-	"os/exec"
+	"path"
+	"path/filepath"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/cstobie/ai-commit/internal/commit"
 	"github.com/cstobie/ai-commit/internal/config"
 	"github.com/cstobie/ai-commit/internal/git"
 	"github.com/cstobie/ai-commit/internal/llm"
+	"github.com/cstobie/ai-commit/internal/secrets"
 	"github.com/cstobie/ai-commit/internal/template"
+	"github.com/cstobie/ai-commit/internal/tokenizer"
+	"github.com/cstobie/ai-commit/internal/ui"
 )
 
 // RunGenerate orchestrates the commit message generation process
 func RunGenerate(ctx context.Context, cfg config.Config, verbose bool, interactive bool) error {
-	// Step 1: Find the git repository root
-	repoRoot, err := git.GetRepoRoot(".")
+	// Fail before reading the diff or calling the API
+	if !template.Exists(cfg.TemplateName) {
+		return fmt.Errorf("unknown template '%s': available templates are %s",
+			cfg.TemplateName, strings.Join(template.Names(), ", "))
+	}
+	generator := NewGenerator(cfg)
+	if cfg.DiffFile != "" {
+		// A diff from outside git leaves nothing to commit
+		return runDiffInput(ctx, generator, cfg.DiffFile, verbose)
+	}
+
+	// Steps 1-3: Find the repository, get the staged diff and build the prompt
+	prepared, err := generator.Prepare(".")
+	if errors.Is(err, ErrNoStagedChanges) {
+		fmt.Fprintln(messageOutput(cfg), "No staged changes found. Stage changes first with 'git add'.")
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("This command must be run inside a git repository. %w", err)
+		return err
 	}
-	
-	if verbose {
-		log.Printf("Found git repository at: %s", repoRoot)
+	cfg = prepared.cfg
+	out := messageOutput(cfg)
+	// With --yes nothing may wait for input, so checks act as without a terminal
+	ask := interactive && !cfg.Yes
+
+	// Commits in the middle of another operation are rarely intended
+	state, err := git.GetRepoState(prepared.RepoRoot)
+	if err != nil {
+		return err
+	}
+	proceed, err := confirmRepoState(ctx, cfg, state, ask)
+	if err != nil {
+		return err
+	}
+	if !proceed {
+		fmt.Fprintln(out, "Commit aborted.")
+		return nil
 	}
 
-	// Step 2: Get the staged diff (check if using smart diff for large commits)
-	var diff string
-	// First, get a quick count of changed files
-	filesList, err := git.GetStagedFilesList(repoRoot)
+	// Give the user a chance to unstage accidentally huge files
+	proceed, err = confirmLargeDiff(ctx, cfg, prepared.files, ask)
 	if err != nil {
-		return fmt.Errorf("failed to get staged files list: %w", err)
+		return err
 	}
-	
-	// Count files by counting newlines
-	fileCount := 0
-	if filesList != "" {
-		fileCount = len(strings.Split(strings.TrimSpace(filesList), "\n"))
+	if !proceed {
+		fmt.Fprintln(out, "Commit aborted.")
+		return nil
 	}
-	
-	// Check if there are any staged changes
-	if filesList == "" {
-		fmt.Println("No staged changes found. Stage changes first with 'git add'.")
+	proceed, err = confirmSecrets(ctx, cfg, prepared.Diff, ask)
+	if err != nil {
+		return err
+	}
+	if !proceed {
+		fmt.Fprintln(out, "Commit aborted.")
 		return nil
 	}
-	
-	// For multi-file commits, use smart diff to preserve context
-	if fileCount > 5 { // Threshold for "large" commits
-		if verbose {
-			log.Printf("Large commit detected (%d files). Using smart diff processing.", fileCount)
+
+	if err := dumpPrompt(cfg, prepared.Prompt); err != nil {
+		return err
+	}
+
+	// Steps 4-6: Generate, print and confirm, looping while the user asks to
+	// regenerate. The prompt is reused, only the temperature is bumped.
+	var usage *llm.Usage
+	opts := GenerateOptions{Prepared: prepared, Temperature: &cfg.Temperature}
+	for {
+		result, attemptUsage, ok, err := generateCheckedMessage(ctx, generator, opts, verbose, ask)
+		usage = llm.AddUsage(usage, attemptUsage)
+		if err != nil && cfg.FallbackEditor && ask && ctx.Err() == nil {
+			return commitFromScaffold(ctx, prepared, err, verbose)
 		}
-		// Use the smart diff processor with the configured token limit
-		smartDiff, err := git.PrepareSmartDiff(repoRoot, cfg.MaxInputTokens)
 		if err != nil {
-			return fmt.Errorf("failed to prepare smart diff: %w", err)
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(out, "Commit aborted.")
+			return nil
+		}
+
+		if result.OverlongChars > 0 {
+			fmt.Fprintf(messageOutput(cfg), "Warning: the model returned a %d character message; it was shortened to %d characters (AICOMMIT_MAX_MESSAGE_CHARS=%d).\n",
+				result.OverlongChars, utf8.RuneCountInString(result.Message), cfg.MaxMessageChars)
+		}
+
+		if result.Message, err = decorateMessage(cfg, result.RepoRoot, result.Message); err != nil {
+			return err
+		}
+		// Keep git's merge subject so the merge commit is recognizable
+		if state.MergeSubject != "" {
+			result.Message = state.MergeSubject + "\n\n" + result.Message
+		}
+
+		// With --output-file the message is only written out, e.g. for a
+		// prepare-commit-msg hook, and git commits it
+		if cfg.OutputFile != "" {
+			if err := writeOutputFile(cfg.OutputFile, result.Message); err != nil {
+				return err
+			}
+			if verbose {
+				printMessage(cfg, "Generated commit message", result.Message)
+			}
+			printUsage(os.Stderr, cfg.ShowUsage, result.Model, usage)
+			return nil
+		}
+
+		// Step 5: Print the generated message, alone on stdout in quiet mode
+		if cfg.Quiet {
+			fmt.Println(result.Message)
+		} else {
+			printMessage(cfg, "Generated commit message", result.Message)
+		}
+		printUsage(messageOutput(cfg), cfg.ShowUsage, result.Model, usage)
+
+		// Step 6: Handle interactive flow or not
+		if !interactive {
+			// Just print the message in non-interactive mode
+			slog.Debug("Running in non-interactive mode, message generated but not committed")
+			return nil
+		}
+		if cfg.Yes {
+			return commitAndPush(ctx, cfg, result.RepoRoot, result.Message, verbose, ask)
+		}
+
+		// Ask what to do, showing the message again after each edit
+		message := result.Message
+		choice, err := commitMenu(ctx, out, cfg.InteractiveDefault == config.InteractiveAbort)
+		for err == nil && choice == choiceEdit {
+			if message, err = editMessage(result.RepoRoot, message); err != nil {
+				return err
+			}
+			if message == "" {
+				fmt.Fprintln(out, "Empty message, commit aborted.")
+				return nil
+			}
+			if cfg.Quiet {
+				fmt.Println(message)
+			} else {
+				printMessage(cfg, "Edited commit message", message)
+			}
+			choice, err = commitMenu(ctx, out, cfg.InteractiveDefault == config.InteractiveAbort)
 		}
-		diff = smartDiff
-	} else {
-		// For smaller commits, use the standard diff
-		standardDiff, err := git.GetStagedDiff(repoRoot)
 		if err != nil {
-			return fmt.Errorf("failed to get staged changes: %w", err)
+			return err
+		}
+		switch choice {
+		case choiceCommit:
+			return commitAndPush(ctx, cfg, result.RepoRoot, message, verbose, ask)
+		case choiceRegenerate:
+			cfg.Temperature = min(cfg.Temperature+cfg.RegenerateTemperatureStep, maxTemperature)
+			slog.Debug("Regenerating commit message", "temperature", cfg.Temperature)
+		default:
+			fmt.Fprintln(out, "Commit aborted.")
+			return nil
 		}
-		diff = standardDiff
 	}
-	
-	if verbose {
-		log.Printf("Retrieved staged diff (%d characters)", len(diff))
+}
+
+// dumpPrompt writes the prompt about to be sent, with likely secrets masked,
+// to the --prompt-out file or, with --debug-prompt, to stderr unless in
+// quiet mode
+func dumpPrompt(cfg config.Config, prompt string) error {
+	if cfg.NoLLM || (cfg.PromptOut == "" && (!cfg.DebugPrompt || cfg.Quiet)) {
+		return nil
+	}
+	scanner, err := secrets.NewScanner(strings.Fields(cfg.SecretPatterns), strings.Fields(cfg.SecretAllowlist))
+	if err != nil {
+		return err
+	}
+	prompt = scanner.Redact(prompt)
+
+	if cfg.PromptOut != "" {
+		if err := os.WriteFile(cfg.PromptOut, []byte(prompt), 0o600); err != nil {
+			return fmt.Errorf("failed to write prompt: %w", err)
+		}
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Prompt (%d characters):\n---\n%s\n---\n", len(prompt), prompt)
+	return nil
+}
+
+// printMessage prints a message under a heading using the configured output
+// template, between --- fences by default, with its subject in bold when
+// colors are on
+func printMessage(cfg config.Config, heading, message string) {
+	colors := ui.NewColors(os.Stdout, cfg.NoColor)
+	tmpl, _ := ui.ParseOutput(cfg.OutputTemplate) // Validated at config load
+	vars := ui.OutputVars{Heading: heading, Message: colors.Message(message), Fence: colors.Fence()}
+	if err := tmpl.Execute(os.Stdout, vars); err != nil {
+		slog.Warn("Failed to print message", "error", err)
+	}
+}
+
+// messageOutput returns where notes around the message go: stdout, or
+// stderr in quiet mode so stdout carries only the message
+func messageOutput(cfg config.Config) io.Writer {
+	if cfg.Quiet {
+		return os.Stderr
 	}
+	return os.Stdout
+}
 
-	// Step 3: Load and execute the template
-	fullPrompt, err := template.LoadAndExecuteTemplate(cfg.TemplateName, diff)
+// maxTemperature is the highest temperature accepted by the API
+const maxTemperature = 2.0
+
+// runDiffInput generates and prints a message for a diff read from path, or
+// from stdin if path is "-", without touching git
+func runDiffInput(ctx context.Context, generator *Generator, path string, verbose bool) error {
+	diff, err := readDiffInput(path)
 	if err != nil {
-		return fmt.Errorf("failed to prepare prompt: %w", err)
+		return err
 	}
-	
-	if verbose {
-		log.Printf("Using template: %s", cfg.TemplateName)
-		log.Printf("Prepared prompt (%d characters)", len(fullPrompt))
-	}
-
-	// Step 4: Generate commit message using the LLM
-	generatedMsg, err := llm.GenerateCommitMessage(
-		ctx,
-		cfg.OpenRouterAPIKey,
-		cfg.LLMModel,
-		cfg.MaxOutputTokens,
-		cfg.Temperature,
-		fullPrompt,
-		cfg.MaxInputTokens,
-	)
-	
+	prepared, err := generator.PrepareDiff(diff)
 	if err != nil {
-		return fmt.Errorf("failed to generate commit message: %w", err)
+		return err
 	}
+	return printPreparedMessage(ctx, generator, prepared, verbose)
+}
 
-	// Step 5: Print the generated message
-	fmt.Println("Generated commit message:")
-	fmt.Println("---")
-	fmt.Println(generatedMsg)
-	fmt.Println("---")
-	
-	// Step 6: Handle interactive flow or not
-	if interactive {
-		// Verify that there are changes to commit
-		if diff == "" {
-			fmt.Println("No staged changes to commit. Stage changes first with 'git add'.")
-			return nil
-		}
-		
-		// Prompt for confirmation
-		fmt.Print("Press Enter to commit with this message (or any key to abort): ")
-		reader := bufio.NewReader(os.Stdin)
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(response)
-		
-		// Process response - empty means Enter was pressed
-		if response == "" {
-			// User confirmed, proceed with commit
-			if err := performCommit(repoRoot, generatedMsg, verbose); err != nil {
-				return err
-			}
-		} else {
-			fmt.Println("Commit aborted.")
+// printPreparedMessage generates a message for a prepared diff that is not
+// going to be committed and prints only the message on stdout
+func printPreparedMessage(ctx context.Context, generator *Generator, prepared *Prepared, verbose bool) error {
+	if _, err := confirmSecrets(ctx, prepared.cfg, prepared.Diff, false); err != nil {
+		return err
+	}
+	if err := dumpPrompt(prepared.cfg, prepared.Prompt); err != nil {
+		return err
+	}
+
+	result, usage, _, err := generateCheckedMessage(ctx, generator, GenerateOptions{Prepared: prepared}, verbose, false)
+	if err != nil {
+		return err
+	}
+	if result.OverlongChars > 0 {
+		slog.Warn("Model returned an overlong message, shortened it", "characters", result.OverlongChars, "max", prepared.cfg.MaxMessageChars)
+	}
+	message, err := decorateMessage(prepared.cfg, "", result.Message)
+	if err != nil {
+		return err
+	}
+	if prepared.cfg.OutputFile != "" {
+		if err := writeOutputFile(prepared.cfg.OutputFile, message); err != nil {
+			return err
 		}
+	}
+	if prepared.cfg.OutputFile == "" || verbose {
+		fmt.Println(message)
+	}
+	printUsage(os.Stderr, prepared.cfg.ShowUsage, result.Model, usage)
+	return nil
+}
+
+// normalizeNewlines converts CRLF and lone CR line endings to LF
+func normalizeNewlines(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+}
+
+// normalizeMessage gives message the LF line endings and layout git expects,
+// on every platform like git itself and with a blank line after the subject
+func normalizeMessage(message string) string {
+	return commit.NormalizeLayout(normalizeNewlines(message))
+}
+
+// writeOutputFile writes message to path, replacing any existing content
+func writeOutputFile(path, message string) error {
+	if err := os.WriteFile(path, []byte(message+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// readDiffInput reads a diff from path, or from stdin if path is "-"
+func readDiffInput(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
 	} else {
-		// Just print the message in non-interactive mode
-		if verbose {
-			log.Println("Running in non-interactive mode, message generated but not committed.")
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read diff: %w", err)
+	}
+	return string(data), nil
+}
+
+// generateCheckedMessage runs the generator behind a spinner. In interactive
+// mode the user may regenerate a message that fails the checks; ok is false
+// if they chose to abort instead. Usage is returned for every attempt.
+func generateCheckedMessage(ctx context.Context, generator *Generator, opts GenerateOptions,
+	verbose, interactive bool) (GenerateResult, *llm.Usage, bool, error) {
+	var usage *llm.Usage
+	for {
+		spinner := ui.NewSpinner("Generating commit message...", interactive && !verbose)
+		spinner.Start(ctx)
+		result, err := generator.Generate(ctx, opts)
+		spinner.Stop()
+		usage = llm.AddUsage(usage, result.Usage)
+
+		var invalid *InvalidMessageError
+		if !errors.As(err, &invalid) {
+			return result, usage, err == nil, err
+		}
+		if !interactive {
+			return result, usage, false, fmt.Errorf("refusing to use generated message: %w:\n%s", invalid.Reason, invalid.Message)
+		}
+
+		out := messageOutput(generator.cfg)
+		fmt.Fprintf(out, "Warning: the generated message looks invalid (%v):\n%s\n", invalid.Reason, invalid.Message)
+		ok, err := confirm(ctx, out, "Regenerate the message?", true)
+		if err != nil || !ok {
+			return result, usage, false, err
 		}
 	}
-	
+}
+
+// templateData maps the configuration and diff onto the template data
+func templateData(cfg config.Config, diff string) template.Data {
+	var lintRules []string
+	if cfg.Commitlint != nil {
+		lintRules = cfg.Commitlint.Describe()
+	}
+	return template.Data{
+		LintRules:        lintRules,
+		AllowedScopes:    allowedScopes(cfg),
+		Diff:             diff,
+		Hint:             cfg.Hint,
+		Language:         cfg.Language,
+		LocalizeType:     cfg.LocalizeType,
+		SubjectMaxTokens: cfg.SubjectTokens,
+		BodyMaxTokens:    cfg.BodyTokens,
+	}
+}
+
+// allowedScopes returns the scopes messages may use: ALLOWED_SCOPES, or the
+// scope-enum of the repository's commitlint config
+func allowedScopes(cfg config.Config) []string {
+	if len(cfg.ParsedScopes) > 0 {
+		return cfg.ParsedScopes
+	}
+	if cfg.Commitlint != nil {
+		return cfg.Commitlint.Scopes
+	}
 	return nil
 }
 
-// performCommit executes the git commit with the provided message
-func performCommit(repoRoot, message string, verbose bool) error {
-	if verbose {
-		log.Println("Committing changes with the generated message...")
+// diffOptions maps the configuration onto the git diff options
+func diffOptions(cfg config.Config) git.DiffOptions {
+	return git.DiffOptions{
+		ContextLines:     cfg.DiffContext,
+		IgnoreWhitespace: cfg.IgnoreWhitespace,
+		HeadLines:        cfg.SmartDiffHeadLines,
+		MaxChunks:        cfg.SmartDiffMaxChunks,
+		TailHunks:        cfg.SmartDiffTailHunks,
+		TailLines:        cfg.SmartDiffTailLines,
+		Pathspecs:        cfg.Pathspecs,
+		MaxLineChars:     cfg.MaxLineChars,
+		MaxFiles:         cfg.MaxFiles,
+		IgnoreBinary:     cfg.IgnoreBinary,
+		Tokenizer:        configTokenizer(cfg),
+	}
+}
+
+// configTokenizer returns the tokenizer named in the config. The name was
+// validated when the config was loaded.
+func configTokenizer(cfg config.Config) tokenizer.Tokenizer {
+	tok, err := tokenizer.New(cfg.Tokenizer)
+	if err != nil {
+		return tokenizer.Default
+	}
+	return tok
+}
+
+// rootPathspecs makes pathspecs relative to the current directory relative to
+// the repository root. Pathspecs using git's magic syntax (":(...)") are kept
+// as they are.
+func rootPathspecs(prefix string, pathspecs []string) []string {
+	rooted := make([]string, 0, len(pathspecs))
+	for _, spec := range pathspecs {
+		if strings.HasPrefix(spec, ":") {
+			rooted = append(rooted, spec)
+			continue
+		}
+		rooted = append(rooted, path.Clean(path.Join(prefix, filepath.ToSlash(spec))))
+	}
+	return rooted
+}
+
+// withRequestTimeout bounds a single API request by the configured timeout,
+// so time spent at interactive prompts does not count against it. Chat
+// requests get the timeout through llmOptions instead, so that it starts
+// after any rate limit wait.
+func withRequestTimeout(ctx context.Context, cfg config.Config) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(cfg.TimeoutSeconds)*time.Second)
+}
+
+// generateMessage produces a commit message for the prompt, regenerating
+// with a slightly higher temperature when the model returns an empty message
+func generateMessage(ctx context.Context, cfg config.Config, prompt string) (string, *llm.Usage, error) {
+	for attempt := 1; ; attempt++ {
+		message, usage, err := generateMessageOnce(ctx, cfg, prompt)
+		if !errors.Is(err, llm.ErrEmptyResponse) || attempt > cfg.RetryEmpty {
+			return message, usage, err
+		}
+		cfg.Temperature = min(cfg.Temperature+cfg.RegenerateTemperatureStep, maxTemperature)
+		slog.Info("Model returned an empty message, regenerating", "attempt", attempt, "retry_empty", cfg.RetryEmpty, "temperature", cfg.Temperature)
+	}
+}
+
+// generateMessageOnce produces a commit message for the prompt, using
+// structured JSON output when configured
+func generateMessageOnce(ctx context.Context, cfg config.Config, prompt string) (string, *llm.Usage, error) {
+	if cfg.Structured {
+		commit, usage, err := llm.GenerateStructuredCommit(ctx, llmOptions(cfg), prompt)
+		if err != nil {
+			return "", nil, err
+		}
+		return commit.Format(), usage, nil
+	}
+	return llm.GenerateCommitMessage(ctx, llmOptions(cfg), prompt)
+}
+
+// patternFeedback builds the prompt addendum telling the model why its
+// previous message was rejected
+func patternFeedback(previous, pattern string) string {
+	return fmt.Sprintf("\n\nYour previous commit message was rejected because it does not match "+
+		"the required regular expression %s:\n%s\n\nGenerate a new commit message that matches it.", pattern, previous)
+}
+
+// llmOptions maps the configuration onto the LLM request options
+func llmOptions(cfg config.Config) llm.Options {
+	opts := llm.Options{
+		Provider:         cfg.Provider,
+		APIKey:           cfg.APIKey(),
+		BaseURL:          cfg.APIBaseURL,
+		Model:            cfg.LLMModel,
+		MaxInputTokens:   cfg.MaxInputTokens,
+		MaxOutputTokens:  cfg.MaxOutputTokens,
+		Temperature:      cfg.Temperature,
+		Proxy:            cfg.HTTPProxy,
+		MaxContinuations: cfg.MaxContinuations,
+		MaxRPM:           cfg.MaxRPM,
+		Timeout:          time.Duration(cfg.TimeoutSeconds) * time.Second,
+		PromptRole:       cfg.PromptRole,
+		Headers:          cfg.Headers,
+		Tokenizer:        configTokenizer(cfg),
+		AzureEndpoint:    cfg.AzureEndpoint,
+		AzureDeployment:  cfg.AzureDeployment,
+		AzureAPIVersion:  cfg.AzureAPIVersion,
+	}
+	if cfg.Deterministic {
+		seed := llm.DeterministicSeed
+		opts.Seed = &seed
+	}
+	for _, example := range cfg.Examples {
+		opts.Examples = append(opts.Examples,
+			llm.OpenRouterMessage{Role: "user", Content: example.Diff},
+			llm.OpenRouterMessage{Role: "assistant", Content: example.Message})
+	}
+	return opts
+}
+
+// commitOptions are the settings passed through to git commit, and where
+// the note that the commit succeeded goes
+type commitOptions struct {
+	pathspecs []string  // Commit only these paths, matching the scope of the diff
+	gpgSign   string    // Empty to follow commit.gpgsign, config.GPGSignDefaultKey, or a key ID
+	author    string    // "Name <email>" overriding the author, if set
+	date      string    // Author date override, in any format git accepts
+	notes     io.Writer // Stdout if nil
+}
+
+// commitOptionsFor returns the commit options set in cfg
+func commitOptionsFor(cfg config.Config) commitOptions {
+	return commitOptions{pathspecs: cfg.Pathspecs, gpgSign: cfg.GPGSign, author: cfg.Author, date: cfg.Date, notes: messageOutput(cfg)}
+}
+
+// commitFlags returns the extra git commit flags for opts. Git signs on its own when commit.gpgsign is set, so -S is only
+// added when signing was requested explicitly.
+func commitFlags(opts commitOptions) []string {
+	var args []string
+	switch opts.gpgSign {
+	case "":
+	case config.GPGSignDefaultKey:
+		args = append(args, "-S")
+	default:
+		args = append(args, "-S"+opts.gpgSign)
 	}
+	if opts.author != "" {
+		args = append(args, "--author="+opts.author)
+	}
+	if opts.date != "" {
+		args = append(args, "--date="+opts.date)
+	}
+	return args
+}
+
+// performCommit executes the git commit with the provided message and options
+func performCommit(repoRoot, message string, opts commitOptions, verbose bool) error {
+	slog.Debug("Committing changes with the generated message")
 	
 	// Create a temporary file to store the commit message
 	tmpFile, err := os.CreateTemp("", "ai-commit-*.txt")
@@ -149,8 +532,8 @@ func performCommit(repoRoot, message string, verbose bool) error {
 	}
 	defer os.Remove(tmpFile.Name())
 	
-	// Write the commit message to the temporary file
-	if _, err := tmpFile.WriteString(message); err != nil {
+	// Write the normalized commit message to the temporary file
+	if _, err := tmpFile.WriteString(normalizeMessage(message)); err != nil {
 		return fmt.Errorf("failed to write commit message to temporary file: %w", err)
 	}
 	if err := tmpFile.Close(); err != nil {
@@ -158,17 +541,52 @@ func performCommit(repoRoot, message string, verbose bool) error {
 	}
 	
 	// Execute the git commit command using the file
-	cmd := exec.Command("git", "-C", repoRoot, "commit", "-F", tmpFile.Name())
-	commitOutput, err := cmd.CombinedOutput()
+	commitOutput, err := git.Commit(repoRoot, tmpFile.Name(), commitFlags(opts), opts.pathspecs)
+	if err != nil && strings.Contains(commitOutput, "failed to sign") {
+		return fmt.Errorf("git could not sign the commit; check the signing key (user.signingkey) and your gpg setup:\n%s", strings.TrimSpace(commitOutput))
+	}
 	if err != nil {
-		return fmt.Errorf("failed to commit changes: %w\n%s", err, string(commitOutput))
+		return fmt.Errorf("failed to commit changes: %w\n%s", err, commitOutput)
 	}
 	
 	if verbose {
-		log.Printf("Commit successful:\n%s", string(commitOutput))
+		slog.Debug("Commit successful", "output", commitOutput)
+	} else if opts.notes != nil {
+		fmt.Fprintln(opts.notes, "Changes committed successfully!")
 	} else {
 		fmt.Println("Changes committed successfully!")
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// printUsage writes the token and cost footer in the configured mode
+func printUsage(w io.Writer, mode, model string, usage *llm.Usage) {
+	if mode == config.UsageOff || mode == "" {
+		return
+	}
+	if usage == nil {
+		fmt.Fprintln(w, "Usage information not reported by the API.")
+		return
+	}
+
+	switch mode {
+	case config.UsageCompact:
+		line := fmt.Sprintf("~%d in / %d out tokens", usage.PromptTokens, usage.CompletionTokens)
+		if usage.Cost > 0 {
+			line += fmt.Sprintf(", ~$%.3f", usage.Cost)
+		}
+		fmt.Fprintln(w, line)
+	case config.UsageFull:
+		fmt.Fprintln(w, "Usage:")
+		fmt.Fprintf(w, "  Model:             %s\n", model)
+		fmt.Fprintf(w, "  Prompt tokens:     %d\n", usage.PromptTokens)
+		fmt.Fprintf(w, "  Completion tokens: %d\n", usage.CompletionTokens)
+		fmt.Fprintf(w, "  Total tokens:      %d\n", usage.TotalTokens)
+		if usage.Cost > 0 {
+			fmt.Fprintf(w, "  Cost:              $%.6f\n", usage.Cost)
+		} else {
+			fmt.Fprintln(w, "  Cost:              not reported")
+		}
+	}
+}
@@ -0,0 +1,530 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/llm"
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestPrintUsage(t *testing.T) {
+	usage := &llm.Usage{PromptTokens: 1200, CompletionTokens: 85, TotalTokens: 1285, Cost: 0.003}
+	tests := []struct {
+		name  string
+		mode  string
+		usage *llm.Usage
+		want  string
+	}{
+		{"off", config.UsageOff, usage, ""},
+		{"unset", "", usage, ""},
+		{"compact", config.UsageCompact, usage, "~1200 in / 85 out tokens, ~$0.003\n"},
+		{"compact without cost", config.UsageCompact, &llm.Usage{PromptTokens: 10, CompletionTokens: 2}, "~10 in / 2 out tokens\n"},
+		{"not reported", config.UsageCompact, nil, "Usage information not reported by the API.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			printUsage(&out, tt.mode, "test/model", tt.usage)
+			if got := out.String(); got != tt.want {
+				t.Errorf("printUsage() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintUsageFull(t *testing.T) {
+	var out strings.Builder
+	printUsage(&out, config.UsageFull, "test/model", &llm.Usage{PromptTokens: 1200, CompletionTokens: 85, TotalTokens: 1285})
+	for _, want := range []string{"Model:             test/model", "Prompt tokens:     1200", "Completion tokens: 85", "Total tokens:      1285", "Cost:              not reported"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("full usage has no %q:\n%s", want, out.String())
+		}
+	}
+}
+
+// chatServer is a fake chat completions API that answers with replies in
+// turn, repeating the last one, and records the last message of each request
+type chatServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	prompts []string
+}
+
+func newChatServer(t *testing.T, replies ...string) *chatServer {
+	t.Helper()
+	s := &chatServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.OpenRouterChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.prompts = append(s.prompts, req.Messages[len(req.Messages)-1].Content)
+		reply := replies[min(len(s.prompts), len(replies))-1]
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": reply}, "finish_reason": "stop"}},
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// requests returns the last message of each request so far
+func (s *chatServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.prompts...)
+}
+
+// serverConfig returns testConfig pointed at server
+func serverConfig(server *chatServer) config.Config {
+	cfg := testConfig()
+	cfg.OpenRouterAPIKey = "test-key"
+	cfg.APIBaseURL = server.URL
+	return cfg
+}
+
+func TestGenerateValidMessageRetriesPattern(t *testing.T) {
+	tests := []struct {
+		name       string
+		replies    []string
+		maxRetries int
+		want       string
+		wantCalls  int
+		wantErr    bool
+	}{
+		{"first passes", []string{"feat: add [PROJ-1] login"}, 2, "feat: add [PROJ-1] login", 1, false},
+		{"second passes", []string{"feat: add login", "feat: add [PROJ-1] login"}, 2, "feat: add [PROJ-1] login", 2, false},
+		{"never passes", []string{"feat: add login"}, 2, "", 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newChatServer(t, tt.replies...)
+			cfg := serverConfig(server)
+			cfg.RequirePattern = `\[PROJ-\d+\]`
+			cfg.MaxRetries = tt.maxRetries
+
+			got, _, err := generateValidMessage(context.Background(), cfg, "describe the diff")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("generateValidMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("generateValidMessage() = %q, want %q", got, tt.want)
+			}
+			prompts := server.requests()
+			if len(prompts) != tt.wantCalls {
+				t.Fatalf("made %d requests, want %d", len(prompts), tt.wantCalls)
+			}
+			for _, prompt := range prompts[1:] {
+				if !strings.Contains(prompt, "was rejected because it does not match") || !strings.Contains(prompt, "feat: add login") {
+					t.Errorf("retry prompt has no feedback on the rejected message:\n%s", prompt)
+				}
+			}
+		})
+	}
+}
+
+func TestPathspecScopesDiffAndCommit(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	testutil.WriteFile(t, repo, "a.txt", "a changed\n")
+	testutil.WriteFile(t, repo, "b.txt", "b changed\n")
+	testutil.RunGit(t, repo, "add", "a.txt", "b.txt")
+
+	cfg := testConfig()
+	cfg.Pathspecs = []string{"a.txt"}
+	prepared, err := NewGenerator(cfg).Prepare(repo)
+	if err != nil {
+		t.Fatalf("Prepare error = %v", err)
+	}
+	if !strings.Contains(prepared.Diff, "+a changed") || strings.Contains(prepared.Diff, "b.txt") {
+		t.Errorf("diff is not limited to a.txt:\n%s", prepared.Diff)
+	}
+
+	if err := performCommit(prepared.RepoRoot, "chore: change a", commitOptionsFor(prepared.cfg), false); err != nil {
+		t.Fatalf("performCommit error = %v", err)
+	}
+	if got := testutil.RunGit(t, repo, "show", "--name-only", "--format=", "HEAD"); got != "a.txt" {
+		t.Errorf("commit changed %q, want only a.txt", got)
+	}
+	if got := testutil.RunGit(t, repo, "diff", "--staged", "--name-only"); got != "b.txt" {
+		t.Errorf("still staged = %q, want b.txt", got)
+	}
+}
+
+func TestRunGenerateDiffStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = stdin })
+	diff := "diff --git a/main.go b/main.go\r\n--- a/main.go\r\n+++ b/main.go\r\n@@ -1 +1 @@\r\n-old\r\n+new\r\n"
+	go func() {
+		io.WriteString(w, diff)
+		w.Close()
+	}()
+
+	server := newChatServer(t, "fix: use the new value")
+	cfg := serverConfig(server)
+	cfg.DiffFile = "-"
+	cfg.OutputFile = filepath.Join(t.TempDir(), "message.txt")
+	// Run outside any repository, since git is not used
+	t.Chdir(t.TempDir())
+
+	if err := RunGenerate(context.Background(), cfg, false, false); err != nil {
+		t.Fatalf("RunGenerate error = %v", err)
+	}
+	message, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(message) != "fix: use the new value\n" {
+		t.Errorf("message = %q, want the model's reply", message)
+	}
+	prompts := server.requests()
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "-old\n+new\n") {
+		t.Errorf("prompt does not carry the piped diff: %q", prompts)
+	}
+}
+
+func TestLLMOptionsAzure(t *testing.T) {
+	cfg := testConfig()
+	cfg.Provider = llm.ProviderAzure
+	cfg.OpenRouterAPIKey = "openrouter-key"
+	cfg.AzureAPIKey = "azure-key"
+	cfg.AzureEndpoint = "https://res.openai.azure.com"
+	cfg.AzureDeployment = "gpt-4o"
+	cfg.AzureAPIVersion = "2024-06-01"
+
+	opts := llmOptions(cfg)
+	if opts.Provider != llm.ProviderAzure || opts.APIKey != "azure-key" {
+		t.Errorf("provider and key = %q, %q, want azure and the Azure key", opts.Provider, opts.APIKey)
+	}
+	if opts.AzureEndpoint != cfg.AzureEndpoint || opts.AzureDeployment != "gpt-4o" || opts.AzureAPIVersion != "2024-06-01" {
+		t.Errorf("Azure options = %q, %q, %q", opts.AzureEndpoint, opts.AzureDeployment, opts.AzureAPIVersion)
+	}
+	if got := llm.Endpoint(opts); got != "https://res.openai.azure.com" {
+		t.Errorf("Endpoint = %q, want the Azure resource", got)
+	}
+}
+
+// captureStdout returns what fn writes to os.Stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestRunGenerateQuiet(t *testing.T) {
+	tests := []struct {
+		name        string
+		showUsage   string
+		interactive bool
+		yes         bool
+	}{
+		{"plain", config.UsageOff, false, false},
+		{"with usage", config.UsageCompact, false, false},
+		// The commit note goes to stderr
+		{"yes commits", config.UsageOff, true, true},
+		// Test stdin is not a terminal, so the menu aborts with a note
+		{"menu without a terminal", config.UsageOff, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testutil.NewRepo(t, nil)
+			testutil.WriteFile(t, repo, "main.go", "package main\n")
+			testutil.RunGit(t, repo, "add", "main.go")
+			t.Chdir(repo)
+
+			server := newChatServer(t, "feat: add main package")
+			cfg := serverConfig(server)
+			cfg.Quiet = true
+			cfg.ShowUsage = tt.showUsage
+			cfg.Yes = tt.yes
+
+			var runErr error
+			stdout := captureStdout(t, func() {
+				runErr = RunGenerate(context.Background(), cfg, false, tt.interactive)
+			})
+			if runErr != nil {
+				t.Fatalf("RunGenerate error = %v", runErr)
+			}
+			if stdout != "feat: add main package\n" {
+				t.Errorf("stdout = %q, want only the message", stdout)
+			}
+		})
+	}
+}
+
+func TestCommitFlagsGPGSign(t *testing.T) {
+	tests := []struct {
+		gpgSign string
+		want    []string
+	}{
+		{"", nil},
+		{config.GPGSignDefaultKey, []string{"-S"}},
+		{"ABCD1234", []string{"-SABCD1234"}},
+	}
+	for _, tt := range tests {
+		if got := commitFlags(commitOptions{gpgSign: tt.gpgSign}); !slices.Equal(got, tt.want) {
+			t.Errorf("commitFlags with gpgSign %q = %q, want %q", tt.gpgSign, got, tt.want)
+		}
+	}
+}
+
+func TestPerformCommitSigningFails(t *testing.T) {
+	repo := testutil.NewRepo(t, nil)
+	// Follow commit.gpgsign with a signing program that always fails
+	testutil.RunGit(t, repo, "config", "commit.gpgsign", "true")
+	testutil.RunGit(t, repo, "config", "gpg.program", "false")
+	testutil.WriteFile(t, repo, "a.txt", "a\n")
+	testutil.RunGit(t, repo, "add", "a.txt")
+
+	err := performCommit(repo, "chore: add a", commitOptions{}, false)
+	if err == nil || !strings.Contains(err.Error(), "could not sign the commit") {
+		t.Errorf("performCommit error = %v, want a signing error", err)
+	}
+}
+
+func TestCommitFlagsAuthorAndDate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts commitOptions
+		want []string
+	}{
+		{"author", commitOptions{author: "Ada <ada@example.com>"},
+			[]string{"--author=Ada <ada@example.com>"}},
+		{"date", commitOptions{date: "2020-01-02T03:04:05"},
+			[]string{"--date=2020-01-02T03:04:05"}},
+		{"both, without pathspecs", commitOptions{author: "Ada <ada@example.com>", date: "yesterday", pathspecs: []string{"src"}},
+			[]string{"--author=Ada <ada@example.com>", "--date=yesterday"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commitFlags(tt.opts); !slices.Equal(got, tt.want) {
+				t.Errorf("commitFlags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerformCommitAuthorAndDate(t *testing.T) {
+	repo := testutil.NewRepo(t, nil)
+	testutil.WriteFile(t, repo, "a.txt", "a\n")
+	testutil.RunGit(t, repo, "add", "a.txt")
+
+	opts := commitOptions{author: "Ada Lovelace <ada@example.com>", date: "2020-01-02T03:04:05Z"}
+	if err := performCommit(repo, "chore: add a", opts, false); err != nil {
+		t.Fatalf("performCommit error = %v", err)
+	}
+	got := strings.TrimSpace(testutil.RunGit(t, repo, "log", "-1", "--format=%an <%ae> %aI"))
+	if want := "Ada Lovelace <ada@example.com> 2020-01-02T03:04:05+00:00"; got != want {
+		t.Errorf("commit author = %q, want %q", got, want)
+	}
+}
+
+func TestRunGenerateOutputFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		verbose    bool
+		wantStdout bool
+	}{
+		{"quiet stdout", false, false},
+		{"verbose also prints", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testutil.NewRepo(t, nil)
+			testutil.WriteFile(t, repo, "main.go", "package main\n")
+			testutil.RunGit(t, repo, "add", "main.go")
+			t.Chdir(repo)
+
+			server := newChatServer(t, "feat: add main package")
+			cfg := serverConfig(server)
+			cfg.OutputFile = filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+			// A hook gets a file holding git's template, which is replaced
+			if err := os.WriteFile(cfg.OutputFile, []byte("# Please enter the commit message\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			var runErr error
+			stdout := captureStdout(t, func() {
+				runErr = RunGenerate(context.Background(), cfg, tt.verbose, false)
+			})
+			if runErr != nil {
+				t.Fatalf("RunGenerate error = %v", runErr)
+			}
+			message, err := os.ReadFile(cfg.OutputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(message) != "feat: add main package\n" {
+				t.Errorf("output file = %q, want the message", message)
+			}
+			if got := strings.Contains(stdout, "feat: add main package"); got != tt.wantStdout {
+				t.Errorf("stdout = %q, message printed = %v, want %v", stdout, got, tt.wantStdout)
+			}
+			if out := testutil.RunGit(t, repo, "log", "--format=%s"); strings.Contains(out, "feat: add main package") {
+				t.Error("a commit was made with an output file")
+			}
+		})
+	}
+}
+
+func TestLLMOptionsDeterministic(t *testing.T) {
+	cfg := testConfig()
+	if opts := llmOptions(cfg); opts.Seed != nil {
+		t.Errorf("seed = %d without deterministic mode, want none", *opts.Seed)
+	}
+	cfg.Deterministic = true
+	if opts := llmOptions(cfg); opts.Seed == nil || *opts.Seed != llm.DeterministicSeed {
+		t.Errorf("seed = %v in deterministic mode, want %d", opts.Seed, llm.DeterministicSeed)
+	}
+}
+
+func TestPerformCommitNormalizesNewlines(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{"CRLF", "feat: add a\r\n\r\nFirst line.\r\nSecond line.\r\n"},
+		{"CR", "feat: add a\r\rFirst line.\rSecond line.\r"},
+		{"LF", "feat: add a\n\nFirst line.\nSecond line.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testutil.NewRepo(t, nil)
+			testutil.WriteFile(t, repo, "a.txt", "a\n")
+			testutil.RunGit(t, repo, "add", "a.txt")
+			if err := performCommit(repo, tt.message, commitOptions{}, false); err != nil {
+				t.Fatalf("performCommit error = %v", err)
+			}
+			raw := testutil.RunGit(t, repo, "cat-file", "-p", "HEAD")
+			if _, message, _ := strings.Cut(raw, "\n\n"); !strings.HasPrefix(message, "feat: add a\n\nFirst line.\nSecond line.") {
+				t.Errorf("commit message = %q, want LF line endings", message)
+			}
+		})
+	}
+}
+
+func TestRunGenerateModes(t *testing.T) {
+	tests := []struct {
+		name        string
+		interactive bool
+		yes         bool
+		wantCommit  bool
+	}{
+		{"yes commits", true, true, true},
+		{"no-interactive only prints", false, false, false},
+		// Test stdin is not a terminal, so the menu aborts
+		{"interactive without a terminal aborts", true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testutil.NewRepo(t, nil)
+			testutil.WriteFile(t, repo, "main.go", "package main\n")
+			testutil.RunGit(t, repo, "add", "main.go")
+			t.Chdir(repo)
+
+			server := newChatServer(t, "feat: add main package")
+			cfg := serverConfig(server)
+			cfg.Yes = tt.yes
+			var err error
+			captureStdout(t, func() {
+				err = RunGenerate(context.Background(), cfg, false, tt.interactive)
+			})
+			if err != nil {
+				t.Fatalf("RunGenerate error = %v", err)
+			}
+			subject := strings.TrimSpace(testutil.RunGit(t, repo, "log", "-1", "--format=%s"))
+			if got := subject == "feat: add main package"; got != tt.wantCommit {
+				t.Errorf("latest commit = %q, committed = %v, want %v", subject, got, tt.wantCommit)
+			}
+		})
+	}
+}
+
+func TestGenerateMessageRetryEmpty(t *testing.T) {
+	tests := []struct {
+		name         string
+		replies      []string
+		retryEmpty   int
+		want         string
+		wantErr      error
+		wantRequests int
+	}{
+		{"empty then valid", []string{"  ", "fix: handle empty input"}, 2, "fix: handle empty input", nil, 2},
+		{"valid first", []string{"fix: handle empty input"}, 2, "fix: handle empty input", nil, 1},
+		{"retries disabled", []string{"", "fix: handle empty input"}, 0, "", llm.ErrEmptyResponse, 1},
+		{"always empty", []string{""}, 2, "", llm.ErrEmptyResponse, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newChatServer(t, tt.replies...)
+			cfg := serverConfig(server)
+			cfg.RetryEmpty = tt.retryEmpty
+			cfg.RegenerateTemperatureStep = 0.1
+			message, _, err := generateMessage(context.Background(), cfg, "Describe this change")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("generateMessage error = %v, want %v", err, tt.wantErr)
+			}
+			if message != tt.want {
+				t.Errorf("generateMessage() = %q, want %q", message, tt.want)
+			}
+			if got := len(server.requests()); got != tt.wantRequests {
+				t.Errorf("made %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRunGenerateUnknownTemplate(t *testing.T) {
+	repo := testutil.NewRepo(t, nil)
+	testutil.WriteFile(t, repo, "main.go", "package main\n")
+	testutil.RunGit(t, repo, "add", "main.go")
+	t.Chdir(repo)
+
+	server := newChatServer(t, "feat: add main package")
+	cfg := serverConfig(server)
+	cfg.TemplateName = "missing"
+	err := RunGenerate(context.Background(), cfg, false, false)
+	if err == nil {
+		t.Fatal("RunGenerate accepted an unknown template")
+	}
+	for _, want := range []string{"unknown template 'missing'", "conventional", "simple"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not include %q", err, want)
+		}
+	}
+	if prompts := server.requests(); len(prompts) != 0 {
+		t.Errorf("model was asked %d times, want none", len(prompts))
+	}
+}
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/git"
+)
+
+// BackfillEntry is one commit of a backfill plan
+type BackfillEntry struct {
+	Commit     string `json:"commit"`
+	OldMessage string `json:"old_message"`
+	NewMessage string `json:"new_message,omitempty"`
+	Error      string `json:"error,omitempty"` // Why no message was generated
+}
+
+// RunBackfill generates a new message for each non-merge commit in revRange,
+// e.g. A..B, from the commit's diff, and writes the plan of old and new
+// messages as JSON to planFile, or stdout if it is empty. A commit whose
+// message cannot be generated is recorded with the error and skipped. With
+// apply set, the commits are then reworded once confirmed, like reword.
+func RunBackfill(ctx context.Context, cfg config.Config, verbose bool, revRange, planFile string, apply bool) error {
+	if err := git.EnsureGitAvailable(); err != nil {
+		return err
+	}
+	repoRoot, err := git.GetRepoRoot(".")
+	if err != nil {
+		return fmt.Errorf("This command must be run inside a git repository. %w", err)
+	}
+	if !strings.Contains(revRange, "..") {
+		return fmt.Errorf("invalid range '%s': expected <from>..<to>, e.g. HEAD~10..HEAD", revRange)
+	}
+	if apply {
+		// Rewriting history under uncommitted changes is asking for trouble
+		clean, err := git.IsWorkingTreeClean(repoRoot)
+		if err != nil {
+			return err
+		}
+		if !clean {
+			return git.ErrDirtyWorkingTree
+		}
+	}
+
+	commits, err := git.GetRangeCommits(repoRoot, revRange)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		fmt.Fprintf(os.Stderr, "No commits in %s.\n", revRange)
+		return nil
+	}
+
+	plan := make([]BackfillEntry, 0, len(commits))
+	for i, commit := range commits {
+		fmt.Fprintf(os.Stderr, "[%d/%d] %.7s\n", i+1, len(commits), commit)
+		entry, err := backfillCommit(ctx, cfg, repoRoot, commit, verbose)
+		if err != nil {
+			return err
+		}
+		if entry.Error != "" {
+			slog.Warn("Skipping commit", "commit", commit[:7], "error", entry.Error)
+		}
+		plan = append(plan, entry)
+	}
+
+	if err := writePlan(planFile, plan); err != nil {
+		return err
+	}
+	if !apply {
+		return nil
+	}
+
+	messages := make(map[string]string)
+	for _, entry := range plan {
+		if entry.NewMessage != "" {
+			messages[entry.Commit] = normalizeMessage(entry.NewMessage)
+		}
+	}
+	if len(messages) == 0 {
+		fmt.Fprintln(os.Stderr, "No new messages to apply.")
+		return nil
+	}
+	ok, err := confirm(ctx, os.Stderr,
+		fmt.Sprintf("Reword %d commits and recreate the commits after them?", len(messages)), false)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Backfill aborted.")
+		return nil
+	}
+	newHead, err := git.RewordCommits(repoRoot, messages)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Reworded %d commits; HEAD is now %.7s.\n", len(messages), newHead)
+	return nil
+}
+
+// backfillCommit generates a new message for commit from its diff. Failures
+// to generate one are recorded in the entry, so the rest of the range is
+// still planned; only an interruption is returned as an error.
+func backfillCommit(ctx context.Context, cfg config.Config, repoRoot, commit string, verbose bool) (BackfillEntry, error) {
+	entry := BackfillEntry{Commit: commit}
+	var err error
+	if entry.OldMessage, err = git.GetCommitMessage(repoRoot, commit); err != nil {
+		return entry, err
+	}
+	diff, err := git.GetCommitDiff(repoRoot, commit, diffOptions(cfg))
+	if err != nil {
+		return entry, err
+	}
+
+	generator := NewGenerator(cfg)
+	message, err := func() (string, error) {
+		prepared, err := generator.PrepareDiff(diff)
+		if err != nil {
+			return "", err
+		}
+		if _, err := confirmSecrets(ctx, prepared.cfg, prepared.Diff, false); err != nil {
+			return "", err
+		}
+		result, _, _, err := generateCheckedMessage(ctx, generator, GenerateOptions{Prepared: prepared}, verbose, false)
+		if err != nil {
+			return "", err
+		}
+		return decorateMessage(prepared.cfg, repoRoot, result.Message)
+	}()
+	if ctx.Err() != nil {
+		return entry, ctx.Err()
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		return entry, nil
+	}
+	entry.NewMessage = message
+	return entry, nil
+}
+
+// writePlan writes plan as indented JSON to path, or stdout if path is empty
+func writePlan(path string, plan []BackfillEntry) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	data = append(data, '\n')
+	if path == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote the plan for %d commits to %s.\n", len(plan), path)
+	return nil
+}
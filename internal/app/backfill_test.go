@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestRunBackfill(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{"main.go": "package main\n"})
+	testutil.WriteFile(t, repo, "login.go", "package main\n\nfunc login() {}\n")
+	testutil.RunGit(t, repo, "add", "login.go")
+	testutil.RunGit(t, repo, "commit", "--quiet", "-m", "wip")
+	testutil.WriteFile(t, repo, "logout.go", "package main\n\nfunc logout() {}\n")
+	testutil.RunGit(t, repo, "add", "logout.go")
+	testutil.RunGit(t, repo, "commit", "--quiet", "-m", "more stuff")
+	commits := strings.Fields(testutil.RunGit(t, repo, "rev-list", "--reverse", "HEAD~2..HEAD"))
+	head := testutil.RunGit(t, repo, "rev-parse", "HEAD")
+	t.Chdir(repo)
+
+	tests := []struct {
+		name     string
+		revRange string
+		wantErr  bool
+		want     []BackfillEntry
+	}{
+		{"two commits", "HEAD~2..HEAD", false, []BackfillEntry{
+			{Commit: commits[0], OldMessage: "wip", NewMessage: "feat: add login"},
+			{Commit: commits[1], OldMessage: "more stuff", NewMessage: "feat: add logout"},
+		}},
+		{"not a range", "HEAD", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newChatServer(t, "feat: add login", "feat: add logout")
+			planFile := filepath.Join(t.TempDir(), "plan.json")
+			err := RunBackfill(context.Background(), serverConfig(server), false, tt.revRange, planFile, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunBackfill error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := testutil.RunGit(t, repo, "rev-parse", "HEAD"); got != head {
+				t.Errorf("HEAD moved from %s to %s without --apply", head, got)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			data, err := os.ReadFile(planFile)
+			if err != nil {
+				t.Fatalf("reading plan: %v", err)
+			}
+			var plan []BackfillEntry
+			if err := json.Unmarshal(data, &plan); err != nil {
+				t.Fatalf("decoding plan: %v\n%s", err, data)
+			}
+			if len(plan) != len(tt.want) {
+				t.Fatalf("plan has %d entries, want %d:\n%s", len(plan), len(tt.want), data)
+			}
+			for i, want := range tt.want {
+				if plan[i] != want {
+					t.Errorf("plan[%d] = %+v, want %+v", i, plan[i], want)
+				}
+			}
+			prompts := server.requests()
+			if len(prompts) != 2 {
+				t.Fatalf("model was asked %d times, want twice", len(prompts))
+			}
+			if !strings.Contains(prompts[0], "+func login() {}") || strings.Contains(prompts[0], "logout") {
+				t.Errorf("first prompt is not for the first commit's diff:\n%s", prompts[0])
+			}
+		})
+	}
+}
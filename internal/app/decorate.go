@@ -0,0 +1,85 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+
+	"github.com/cstobie/ai-commit/internal/commit"
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/git"
+	prompttemplate "github.com/cstobie/ai-commit/internal/template"
+)
+
+// messageVars are the variables available in MESSAGE_HEADER and MESSAGE_FOOTER
+type messageVars struct {
+	Branch string // Current branch, empty when detached or outside a repository
+	Model  string
+}
+
+// decorateMessage formats message as a Jira smart commit in smart commit
+// mode, then adds the configured header and footer. The footer goes before a
+// trailing block of git trailers, or joins it if the footer is made of
+// trailers itself.
+func decorateMessage(cfg config.Config, repoRoot, message string) (string, error) {
+	if cfg.MessageHeader == "" && cfg.MessageFooter == "" && !cfg.SmartCommit {
+		return message, nil
+	}
+
+	vars := messageVars{Model: cfg.LLMModel}
+	if repoRoot != "" {
+		branch, err := git.GetCurrentBranch(repoRoot)
+		if err != nil {
+			return "", err
+		}
+		vars.Branch = branch
+	}
+	if cfg.SmartCommit {
+		issue := cfg.Issue
+		if issue == "" {
+			issue = commit.IssueFromBranch(vars.Branch)
+		}
+		if issue == "" {
+			slog.Warn("No Jira issue key in the branch name, so the message is not a smart commit; pass one with --issue", "branch", vars.Branch)
+		} else {
+			var err error
+			if message, err = prompttemplate.SmartCommit(issue, message); err != nil {
+				return "", err
+			}
+		}
+	}
+	header, err := renderDecoration("MESSAGE_HEADER", cfg.MessageHeader, vars)
+	if err != nil {
+		return "", err
+	}
+	footer, err := renderDecoration("MESSAGE_FOOTER", cfg.MessageFooter, vars)
+	if err != nil {
+		return "", err
+	}
+
+	// The footer goes first so the subject is never mistaken for a trailer
+	if footer != "" {
+		message = commit.AppendFooter(message, footer)
+	}
+	if header != "" {
+		message = header + "\n\n" + message
+	}
+	return message, nil
+}
+
+// renderDecoration executes a header or footer template and trims it
+func renderDecoration(name, text string, vars messageVars) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(prompttemplate.Funcs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", name, err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, vars); err != nil {
+		return "", fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestDecorateMessage(t *testing.T) {
+	repo := testutil.NewRepo(t, nil)
+	testutil.RunGit(t, repo, "checkout", "--quiet", "-b", "feature/PROJ-7-login")
+
+	tests := []struct {
+		name    string
+		header  string
+		footer  string
+		message string
+		want    string
+	}{
+		{"nothing to add", "", "", "feat: add login", "feat: add login"},
+		{"header", "[{{.Branch}}]", "", "feat: add login", "[feature/PROJ-7-login]\n\nfeat: add login"},
+		{"footer", "", "Generated with ai-commit ({{.Model}})", "feat: add login\n\nAdds a form.",
+			"feat: add login\n\nAdds a form.\n\nGenerated with ai-commit (test/model)"},
+		{"footer before trailers", "", "Generated with ai-commit", "feat: add login\n\nSigned-off-by: A <a@b>",
+			"feat: add login\n\nGenerated with ai-commit\n\nSigned-off-by: A <a@b>"},
+		{"trailer footer joins trailers", "", "Refs: {{.Branch}}", "feat: add login\n\nSigned-off-by: A <a@b>",
+			"feat: add login\n\nSigned-off-by: A <a@b>\nRefs: feature/PROJ-7-login"},
+		{"header and footer", "Ticket", "Footer", "feat: add login", "Ticket\n\nfeat: add login\n\nFooter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.MessageHeader = tt.header
+			cfg.MessageFooter = tt.footer
+			got, err := decorateMessage(cfg, repo, tt.message)
+			if err != nil {
+				t.Fatalf("decorateMessage error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("decorateMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecorateMessageInvalidTemplate(t *testing.T) {
+	cfg := testConfig()
+	cfg.MessageFooter = "{{.Ticket}}"
+	if _, err := decorateMessage(cfg, "", "feat: add login"); err == nil {
+		t.Error("decorateMessage accepted a footer with an unknown variable")
+	}
+}
+
+func TestDecorateMessageSmartCommit(t *testing.T) {
+	tests := []struct {
+		name   string
+		branch string
+		issue  string
+		footer string
+		want   string
+	}{
+		{"issue from branch", "feature/PROJ-7-login", "", "", "PROJ-7 #comment feat: add login\n\nAdds a form."},
+		{"issue flag wins", "feature/PROJ-7-login", "OPS-42", "", "OPS-42 #comment feat: add login\n\nAdds a form."},
+		{"no issue", "login", "", "", "feat: add login\n\nAdds a form."},
+		{"with footer", "feature/PROJ-7-login", "", "Generated with ai-commit",
+			"PROJ-7 #comment feat: add login\n\nAdds a form.\n\nGenerated with ai-commit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testutil.NewRepo(t, nil)
+			testutil.RunGit(t, repo, "checkout", "--quiet", "-b", tt.branch)
+			cfg := testConfig()
+			cfg.SmartCommit = true
+			cfg.Issue = tt.issue
+			cfg.MessageFooter = tt.footer
+			got, err := decorateMessage(cfg, repo, "feat: add login\n\nAdds a form.")
+			if err != nil {
+				t.Fatalf("decorateMessage error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("decorateMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
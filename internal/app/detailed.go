@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/llm"
+	"github.com/cstobie/ai-commit/internal/template"
+)
+
+// bulletsTemplate is the template used for per-file body bullets
+const bulletsTemplate = "bullets"
+
+// maxBulletFiles caps the number of files that get a bullet in detailed mode
+const maxBulletFiles = 10
+
+// splitBudget returns a copy of cfg with half of the input and output token
+// budgets, so the subject and bullet passes together stay within the limits
+func splitBudget(cfg config.Config) config.Config {
+	cfg.MaxInputTokens = max(cfg.MaxInputTokens/2, 1)
+	cfg.MaxOutputTokens = max(cfg.MaxOutputTokens/2, 1)
+	return cfg
+}
+
+// addFileBullets appends a body with one bullet per significant staged file
+// to the subject line of message
+func addFileBullets(ctx context.Context, cfg config.Config, repoRoot, diff, message string) (string, *llm.Usage, error) {
+	subject, _, _ := strings.Cut(message, "\n")
+
+	files, err := significantFiles(repoRoot, cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(files) == 0 {
+		return subject, nil, nil
+	}
+
+	prompt, err := template.Execute(bulletsTemplate, withFiles(templateData(cfg, diff), files))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to prepare bullets prompt: %w", err)
+	}
+
+	slog.Debug("Generating body bullets", "files", len(files))
+	output, usage, err := llm.GenerateCommitMessage(ctx, llmOptions(cfg), prompt)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate body bullets: %w", err)
+	}
+
+	// Keep only bullet lines and normalize the bullet character
+	var bullets []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
+			bullets = append(bullets, "- "+strings.TrimSpace(line[2:]))
+		}
+	}
+	if len(bullets) == 0 {
+		slog.Warn("Model returned no body bullets, using subject only")
+		return subject, usage, nil
+	}
+
+	return subject + "\n\n" + strings.Join(bullets, "\n"), usage, nil
+}
+
+// withFiles returns data with the file list set
+func withFiles(data template.Data, files []string) template.Data {
+	data.Files = files
+	return data
+}
+
+// significantFiles returns the paths of the staged text files with the
+// largest diffs, up to maxBulletFiles, in their original order
+func significantFiles(repoRoot string, cfg config.Config) ([]string, error) {
+	fileChanges, err := git.GetStagedDiffFiles(repoRoot, diffOptions(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staged files: %w", err)
+	}
+
+	var candidates []int
+	for i, fc := range fileChanges {
+		if !fc.IsBinary && fc.Diff != "" {
+			candidates = append(candidates, i)
+		}
+	}
+
+	// Prefer the largest diffs when there are too many files
+	if len(candidates) > maxBulletFiles {
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return len(fileChanges[candidates[a]].Diff) > len(fileChanges[candidates[b]].Diff)
+		})
+		candidates = candidates[:maxBulletFiles]
+		sort.Ints(candidates)
+	}
+
+	files := make([]string, 0, len(candidates))
+	for _, i := range candidates {
+		files = append(files, fileChanges[i].Path)
+	}
+	return files, nil
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/credentials"
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/llm"
+	"github.com/cstobie/ai-commit/internal/secrets"
+	"github.com/cstobie/ai-commit/internal/template"
+)
+
+// doctorCheck is the outcome of one environment check
+type doctorCheck struct {
+	name   string
+	detail string // What was found
+	fix    string // How to fix a failed check; empty if the check passed
+}
+
+// RunDoctor checks that git, the repository, the API key, the model, the
+// template and the API endpoint are usable, and prints a checklist. It
+// returns an error if any check failed.
+func RunDoctor(ctx context.Context, cfg config.Config) error {
+	apiKey, keyVar := cfg.APIKey(), "AICOMMIT_"+config.APIKeyName(cfg.Provider)
+
+	var checks []doctorCheck
+	gitErr := git.EnsureGitAvailable()
+	if gitErr != nil {
+		checks = append(checks, doctorCheck{"git", "not found on PATH", "Install git and make sure it is on your PATH."})
+	} else {
+		checks = append(checks, doctorCheck{"git", "found on PATH", ""})
+	}
+
+	switch repoRoot, err := git.GetRepoRoot("."); {
+	case gitErr != nil:
+		checks = append(checks, doctorCheck{"repository", "not checked without git", "Install git first."})
+	case err != nil:
+		checks = append(checks, doctorCheck{"repository", "not inside a git repository", "Run ai-commit from inside a git repository, or create one with 'git init'."})
+	default:
+		checks = append(checks, doctorCheck{"repository", repoRoot, ""})
+	}
+
+	if apiKey == "" {
+		fix := "Set " + keyVar + " in the environment or a .env file."
+		if cfg.SecretSource == credentials.SourceKeychain {
+			fix = "Store the key in the keychain with 'ai-commit login', or set " + keyVar + "."
+		}
+		checks = append(checks, doctorCheck{"API key", keyVar + " is not set", fix})
+	} else {
+		checks = append(checks, doctorCheck{"API key", secrets.RedactKey(apiKey), ""})
+	}
+
+	if cfg.LLMModel == "" {
+		checks = append(checks, doctorCheck{"model", "empty", "Set AICOMMIT_LLM_MODEL, e.g. openai/gpt-4o-mini."})
+	} else {
+		checks = append(checks, doctorCheck{"model", cfg.LLMModel, ""})
+	}
+
+	if !template.Exists(cfg.TemplateName) {
+		checks = append(checks, doctorCheck{"template", fmt.Sprintf("'%s' does not exist", cfg.TemplateName), "Set AICOMMIT_TEMPLATE_NAME to one of " + strings.Join(template.Names(), ", ") + "."})
+	} else {
+		checks = append(checks, doctorCheck{"template", cfg.TemplateName, ""})
+	}
+
+	connection := checkConnection(ctx, cfg, keyVar)
+	checks = append(checks, connection)
+	// Azure selects the model by deployment, so the model id is not listed
+	if connection.fix == "" && cfg.LLMModel != "" && cfg.Provider != llm.ProviderAzure {
+		checks = append(checks, checkModelListed(ctx, cfg))
+	}
+
+	failed := 0
+	for _, check := range checks {
+		if check.fix == "" {
+			fmt.Printf("✓ %s: %s\n", check.name, check.detail)
+			continue
+		}
+		failed++
+		fmt.Printf("✗ %s: %s\n    %s\n", check.name, check.detail, check.fix)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// checkModelListed verifies the configured model is offered by the API
+func checkModelListed(ctx context.Context, cfg config.Config) doctorCheck {
+	ctx, cancel := withRequestTimeout(ctx, cfg)
+	defer cancel()
+	ids, err := llm.ListModels(ctx, llmOptions(cfg))
+	if err != nil {
+		return doctorCheck{"model available", err.Error(), "Check your network connection and AICOMMIT_API_BASE_URL."}
+	}
+	if !slices.Contains(ids, cfg.LLMModel) {
+		return doctorCheck{"model available", fmt.Sprintf("'%s' is not in the API's model list", cfg.LLMModel),
+			"Run 'ai-commit models' to list the available ids and set AICOMMIT_LLM_MODEL to one of them."}
+	}
+	return doctorCheck{"model available", cfg.LLMModel + " is offered by the API", ""}
+}
+
+// checkConnection verifies the configured API endpoint accepts requests
+func checkConnection(ctx context.Context, cfg config.Config, keyVar string) doctorCheck {
+	opts := llmOptions(cfg)
+	endpoint := llm.Endpoint(opts)
+
+	ctx, cancel := withRequestTimeout(ctx, cfg)
+	defer cancel()
+	err := llm.CheckConnection(ctx, opts)
+	if err == nil {
+		return doctorCheck{"connectivity", endpoint + " is reachable", ""}
+	}
+
+	var apiErr *llm.APIError
+	switch {
+	case errors.As(err, &apiErr) && (apiErr.StatusCode == 401 || apiErr.StatusCode == 403):
+		return doctorCheck{"connectivity", fmt.Sprintf("%s rejected the API key (code %d)", endpoint, apiErr.StatusCode),
+			"Check that " + keyVar + " is a valid key for this provider."}
+	case errors.As(err, &apiErr):
+		return doctorCheck{"connectivity", fmt.Sprintf("%s responded with code %d", endpoint, apiErr.StatusCode),
+			"Check AICOMMIT_API_BASE_URL, or the AZURE_* settings when using Azure."}
+	default:
+		return doctorCheck{"connectivity", err.Error(),
+			"Check your network connection and proxy settings (AICOMMIT_HTTP_PROXY)."}
+	}
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/cstobie/ai-commit/internal/git"
+)
+
+// editorOS is the platform whose conventions editorCommand follows
+var editorOS = runtime.GOOS
+
+// editMessage opens the message in the user's git editor and returns the
+// edited text with surrounding whitespace removed
+func editMessage(repoRoot, message string) (string, error) {
+	editor, err := git.Var(repoRoot, "GIT_EDITOR")
+	if err != nil {
+		return "", fmt.Errorf("failed to find an editor: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp("", "ai-commit-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file for editing: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.WriteString(message + "\n"); err != nil {
+		tmpFile.Close()
+		return "", fmt.Errorf("failed to write temporary file for editing: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return "", fmt.Errorf("failed to close temporary file for editing: %w", err)
+	}
+
+	cmd, err := editorCommand(editor, tmpFile.Name())
+	if err != nil {
+		return "", err
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor failed: %w", err)
+	}
+
+	edited, err := os.ReadFile(tmpFile.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited message: %w", err)
+	}
+	// Editors on Windows may save with CRLF line endings
+	return strings.TrimSpace(normalizeNewlines(string(edited))), nil
+}
+
+// editorCommand returns the command that opens path in editor. GIT_EDITOR
+// may carry arguments, so the shell splits it like git does; Windows has no
+// sh to rely on, so there the words are split here and run directly.
+func editorCommand(editor, path string) (*exec.Cmd, error) {
+	if editorOS != "windows" {
+		return exec.Command("sh", "-c", editor+` "$@"`, "editor", path), nil
+	}
+	words, err := splitEditorCommand(editor)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, errors.New("failed to find an editor: GIT_EDITOR is empty")
+	}
+	return exec.Command(words[0], append(words[1:], path)...), nil
+}
+
+// splitEditorCommand splits an editor command line into words at unquoted
+// whitespace. Single and double quotes group words and are removed. A
+// backslash escapes a following quote or space and is kept otherwise, as it
+// separates the directories of Windows paths.
+func splitEditorCommand(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && quote != '\'' && i+1 < len(runes) && strings.ContainsRune(`"' `, runes[i+1]):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("invalid editor command %q: unterminated quote", line)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// commitFromScaffold offers to write the message by hand after generation
+// failed with genErr. The editor starts from the file list and a suggested
+// scope, and the result is committed as it is.
+func commitFromScaffold(ctx context.Context, prepared *Prepared, genErr error, verbose bool) error {
+	out := messageOutput(prepared.cfg)
+	fmt.Fprintf(out, "Error: %v\n", genErr)
+	ok, err := confirm(ctx, out, "Write the message in your editor instead?", true)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(out, "Commit aborted.")
+		return nil
+	}
+
+	scaffold, err := offlineMessage(prepared)
+	if err != nil {
+		return err
+	}
+	message, err := editMessage(prepared.RepoRoot, scaffold)
+	if err != nil {
+		return err
+	}
+	if message == "" {
+		fmt.Fprintln(out, "Empty message, commit aborted.")
+		return nil
+	}
+	return commitAndPush(ctx, prepared.cfg, prepared.RepoRoot, message, verbose, true)
+}
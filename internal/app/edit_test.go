@@ -0,0 +1,89 @@
+package app
+
+import (
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestSplitEditorCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "single word", line: "notepad", want: []string{"notepad"}},
+		{name: "arguments", line: "code  --wait\t-n", want: []string{"code", "--wait", "-n"}},
+		{
+			name: "double quoted windows path",
+			line: `"C:\Program Files\Notepad++\notepad++.exe" -multiInst`,
+			want: []string{`C:\Program Files\Notepad++\notepad++.exe`, "-multiInst"},
+		},
+		{
+			name: "single quoted path",
+			line: "'C:/Program Files/Sublime Text/subl.exe' -w",
+			want: []string{"C:/Program Files/Sublime Text/subl.exe", "-w"},
+		},
+		{name: "unquoted backslashes kept", line: `C:\Windows\notepad.exe`, want: []string{`C:\Windows\notepad.exe`}},
+		{name: "escaped space", line: `C:\My\ Tools\ed.exe`, want: []string{`C:\My Tools\ed.exe`}},
+		{name: "empty quotes are a word", line: `ed ""`, want: []string{"ed", ""}},
+		{name: "empty", line: "  ", want: nil},
+		{name: "unterminated quote", line: `"C:\Program Files\ed.exe`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitEditorCommand(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitEditorCommand(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("splitEditorCommand(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		goos string
+		want []string
+	}{
+		{"linux", []string{"sh", "-c", `"my editor" --wait "$@"`, "editor", "msg.txt"}},
+		{"windows", []string{"my editor", "--wait", "msg.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			goos := editorOS
+			editorOS = tt.goos
+			t.Cleanup(func() { editorOS = goos })
+
+			cmd, err := editorCommand(`"my editor" --wait`, "msg.txt")
+			if err != nil {
+				t.Fatalf("editorCommand error = %v", err)
+			}
+			if !slices.Equal(cmd.Args, tt.want) {
+				t.Errorf("editorCommand args = %q, want %q", cmd.Args, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditMessage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test editor is a shell command")
+	}
+	repo := testutil.NewRepo(t, nil)
+	// The editor appends a CRLF body line, as editors on Windows may
+	t.Setenv("GIT_EDITOR", `printf 'Body line.\r\n' >>`)
+
+	got, err := editMessage(repo, "feat: add a\n")
+	if err != nil {
+		t.Fatalf("editMessage error = %v", err)
+	}
+	if want := "feat: add a\n\nBody line."; got != want {
+		t.Errorf("editMessage() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/llm"
+	"github.com/cstobie/ai-commit/internal/template"
+	"github.com/cstobie/ai-commit/internal/ui"
+)
+
+// explainTemplate is the template used to summarize the staged diff
+const explainTemplate = "explain"
+
+// Output formats for RunExplain
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// explainResult is the JSON shape printed by RunExplain with --output json
+type explainResult struct {
+	Summary string     `json:"summary"`
+	Model   string     `json:"model"`
+	Usage   *llm.Usage `json:"usage,omitempty"`
+}
+
+// RunExplain summarizes the staged changes in prose, e.g. for a PR description
+func RunExplain(ctx context.Context, cfg config.Config, verbose bool, output string) error {
+	if output != OutputText && output != OutputJSON {
+		return fmt.Errorf("invalid output format '%s': must be text or json", output)
+	}
+
+	repoRoot, err := findRepo(&cfg, ".")
+	if err != nil {
+		return err
+	}
+	if err := applyTemplateDefaults(&cfg, explainTemplate); err != nil {
+		return err
+	}
+	// Summaries are longer than commit messages, and take a single request
+	cfg.MaxOutputTokens = cfg.ExplainMaxOutputTokens
+	cfg.Detailed = false
+
+	diff, _, err := stagedDiff(&cfg, repoRoot)
+	if errors.Is(err, ErrNoStagedChanges) {
+		fmt.Println("No staged changes found. Stage changes first with 'git add'.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	proceed, err := confirmSecrets(ctx, cfg, diff, ui.IsTerminal(os.Stdin))
+	if err != nil {
+		return err
+	}
+	if !proceed {
+		fmt.Fprintln(messageOutput(cfg), "Explain aborted.")
+		return nil
+	}
+
+	fullPrompt, err := template.Execute(explainTemplate, templateData(cfg, diff))
+	if err != nil {
+		return fmt.Errorf("failed to prepare prompt: %w", err)
+	}
+
+	slog.Debug("Prepared explain prompt", "characters", len(fullPrompt))
+
+	spinner := ui.NewSpinner("Summarizing staged changes...", output == OutputText && !verbose)
+	spinner.Start(ctx)
+	summary, usage, err := llm.GenerateCommitMessage(ctx, llmOptions(cfg), fullPrompt)
+	spinner.Stop()
+	if err != nil {
+		return fmt.Errorf("failed to generate summary: %w", err)
+	}
+
+	if output == OutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(explainResult{Summary: summary, Model: cfg.LLMModel, Usage: usage})
+	}
+
+	fmt.Println(summary)
+	printUsage(os.Stdout, cfg.ShowUsage, cfg.LLMModel, usage)
+	return nil
+}
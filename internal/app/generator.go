@@ -0,0 +1,647 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cstobie/ai-commit/internal/commit"
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/llm"
+	"github.com/cstobie/ai-commit/internal/template"
+	"github.com/cstobie/ai-commit/internal/tokenizer"
+)
+
+// subjectOnlyTemplate replaces the configured template in subject-only mode
+const subjectOnlyTemplate = "subject-only"
+
+// ErrNoStagedChanges is returned when there is nothing staged to describe
+var ErrNoStagedChanges = errors.New("no staged changes")
+
+// ErrEmptyDiff is returned by PrepareDiff for an empty diff
+var ErrEmptyDiff = errors.New("diff is empty")
+
+// InvalidMessageError is returned when the generated message looks like a
+// placeholder rather than a real commit message, or breaks the template's
+// header format
+type InvalidMessageError struct {
+	Message string // The rejected message
+	Reason  error  // Why it was rejected
+}
+
+func (e *InvalidMessageError) Error() string {
+	return fmt.Sprintf("generated message looks invalid: %v", e.Reason)
+}
+
+func (e *InvalidMessageError) Unwrap() error {
+	return e.Reason
+}
+
+// Generator produces commit messages for staged changes. It has no I/O side
+// effects beyond reading the repository and calling the API: it never prints,
+// prompts or commits.
+type Generator struct {
+	cfg config.Config
+}
+
+// NewGenerator returns a Generator using the given configuration
+func NewGenerator(cfg config.Config) *Generator {
+	return &Generator{cfg: cfg}
+}
+
+// Prepared holds the staged diff and rendered prompt for a repository, so
+// several messages can be generated without re-reading the diff
+type Prepared struct {
+	RepoRoot string // Empty for diffs from PrepareDiff
+	Diff     string
+	Prompt   string
+	cfg      config.Config    // Config with the input budget clamped and pathspecs rooted
+	files    []git.FileReport // Estimated size of each staged file; nil for diffs from outside git
+}
+
+// GenerateOptions controls a single call to Generate
+type GenerateOptions struct {
+	Dir         string    // Directory inside the repository; the current directory if empty
+	Prepared    *Prepared // Reuse a diff and prompt from Prepare instead of reading them again
+	Temperature *float64  // Overrides the configured temperature when set
+}
+
+// GenerateResult is a generated commit message and its metadata
+type GenerateResult struct {
+	Message  string
+	RepoRoot string
+	Model    string
+	Usage    *llm.Usage // Summed over all requests; nil if the API did not report it
+	// Length in characters of the model's message if it had to be shortened, else 0
+	OverlongChars int
+}
+
+// Prepare reads the staged diff of the repository containing dir and renders
+// the prompt. It returns ErrNoStagedChanges if nothing is staged.
+func (g *Generator) Prepare(dir string) (*Prepared, error) {
+	if dir == "" {
+		dir = "."
+	}
+	cfg := g.modeConfig()
+	repoRoot, err := findRepo(&cfg, dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := selectTemplate(&cfg, repoRoot); err != nil {
+		return nil, err
+	}
+	if err := applyTemplateDefaults(&cfg, cfg.TemplateName); err != nil {
+		return nil, err
+	}
+	diff, files, err := stagedDiff(&cfg, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	prepared, err := preparePrompt(cfg, repoRoot, diff, nil)
+	if err != nil {
+		return nil, err
+	}
+	prepared.files = files
+	return prepared, nil
+}
+
+// selectTemplate switches cfg to the template of the first TEMPLATE_RULES
+// rule the size of the staged change matches. Subject-only mode keeps its
+// own template.
+func selectTemplate(cfg *config.Config, repoRoot string) error {
+	if len(cfg.ParsedTemplateRules) == 0 || cfg.SubjectOnly {
+		return nil
+	}
+	files, lines, err := git.GetStagedChangeSize(repoRoot, cfg.Pathspecs)
+	if err != nil {
+		return err
+	}
+	for _, rule := range cfg.ParsedTemplateRules {
+		if rule.Matches(files, lines) {
+			slog.Debug("Selected template by change size", "template", rule.Template, "files", files, "lines", lines)
+			cfg.TemplateName = rule.Template
+			return nil
+		}
+	}
+	return nil
+}
+
+// applyTemplateDefaults applies the config block of the named template, once
+// it is the final choice, to cfg
+func applyTemplateDefaults(cfg *config.Config, templateName string) error {
+	defaults, err := template.LoadDefaults(templateName)
+	if err != nil {
+		return err
+	}
+	cfg.ApplyTemplateDefaults(defaults)
+	if defaults != (template.Defaults{}) {
+		slog.Debug("Applied template defaults", "template", templateName, "model", cfg.LLMModel,
+			"max_output_tokens", cfg.MaxOutputTokens, "temperature", cfg.Temperature)
+	}
+	return nil
+}
+
+// PrepareDiff renders the prompt for a diff obtained elsewhere, without
+// looking at any repository. Detailed mode needs a repository and is turned
+// off.
+func (g *Generator) PrepareDiff(diff string) (*Prepared, error) {
+	cfg := g.modeConfig()
+	cfg.Detailed = false
+	if err := applyTemplateDefaults(&cfg, cfg.TemplateName); err != nil {
+		return nil, err
+	}
+	if err := clampInputTokens(&cfg); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(diff) == "" {
+		return nil, ErrEmptyDiff
+	}
+	// Diffs saved on Windows may use CRLF line endings throughout
+	diff = normalizeNewlines(diff)
+	if !looksLikeDiff(diff) {
+		slog.Warn("Input does not look like a unified diff, using it anyway")
+	}
+	diff = git.ElideLongLines(diff, cfg.MaxLineChars)
+	return preparePrompt(cfg, "", diff, nil)
+}
+
+// PrepareRange renders the prompt for one message describing the commits on
+// HEAD since it diverged from base, in the repository containing dir, from
+// their combined diff and messages. As with PrepareDiff, the result is not
+// tied to the staged changes: RepoRoot is empty and detailed mode is off.
+func (g *Generator) PrepareRange(dir, base string) (*Prepared, error) {
+	if dir == "" {
+		dir = "."
+	}
+	cfg := g.modeConfig()
+	cfg.Detailed = false
+	if err := git.EnsureGitAvailable(); err != nil {
+		return nil, err
+	}
+	repoRoot, err := git.GetRepoRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("This command must be run inside a git repository. %w", err)
+	}
+	if _, err := git.ResolveCommit(repoRoot, base); err != nil {
+		return nil, err
+	}
+	if err := applyTemplateDefaults(&cfg, cfg.TemplateName); err != nil {
+		return nil, err
+	}
+	if err := clampInputTokens(&cfg); err != nil {
+		return nil, err
+	}
+
+	diff, err := git.GetRangeDiff(repoRoot, base, diffOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(diff) == "" {
+		return nil, ErrEmptyDiff
+	}
+	messages, err := git.GetRangeCommitMessages(repoRoot, base)
+	if err != nil {
+		return nil, err
+	}
+	return preparePrompt(cfg, "", diff, messages)
+}
+
+// checkTemplateOverhead fails if the prompt without its diff already fills
+// the input budget, since truncating the diff could then only send the
+// instructions with a truncation marker in place of the changes
+func checkTemplateOverhead(cfg config.Config, data template.Data, tok tokenizer.Tokenizer) error {
+	data.Diff = ""
+	empty, err := template.Execute(cfg.TemplateName, data)
+	if err != nil {
+		return fmt.Errorf("failed to prepare prompt: %w", err)
+	}
+	if overhead := tok.Count(empty); overhead >= cfg.MaxInputTokens {
+		return fmt.Errorf("MAX_INPUT_TOKENS (%d) is too small for template %s; it needs at least %d tokens besides the diff",
+			cfg.MaxInputTokens, cfg.TemplateName, overhead)
+	}
+	return nil
+}
+
+// modeConfig returns the config with the settings implied by its modes applied
+func (g *Generator) modeConfig() config.Config {
+	cfg := g.cfg
+	if cfg.SubjectOnly {
+		// A body is neither requested nor wanted
+		cfg.TemplateName = subjectOnlyTemplate
+		cfg.Detailed = false
+	}
+	if cfg.NoLLM {
+		// Bullets need the API
+		cfg.Detailed = false
+	}
+	return cfg
+}
+
+// preparePrompt renders the prompt for diff, combining the squashed commit
+// messages if any, suggests a commit type and scope from the changed files
+// and flags possibly breaking changes. With a repository, the diff is the
+// staged diff: its stat is shown first and the repository's commit
+// guidelines and commitlint rules are included. With HistoryCount set too,
+// recent commit messages are included as style examples, dropping the
+// oldest ones while the prompt exceeds the input budget. A diff that still
+// does not fit is truncated.
+func preparePrompt(cfg config.Config, repoRoot, diff string, squashed []string) (*Prepared, error) {
+	if repoRoot != "" {
+		rules, err := commit.LoadCommitlint(repoRoot)
+		if err != nil {
+			return nil, err
+		}
+		if rules != nil {
+			slog.Debug("Using commitlint rules", "path", rules.Source)
+		}
+		cfg.Commitlint = rules
+	}
+	data := templateData(cfg, diff)
+	data.SquashedCommits = squashed
+	if repoRoot != "" {
+		stat, err := git.GetStagedDiffStat(repoRoot, diffOptions(cfg))
+		if err != nil {
+			return nil, err
+		}
+		data.DiffStat = stat
+		if data.Guidelines, err = loadGuidelines(cfg, repoRoot); err != nil {
+			return nil, err
+		}
+	}
+	paths := git.DiffPaths(diff)
+	if repoRoot != "" {
+		// The staged diff may be a smart-diff summary rather than a patch
+		var err error
+		if paths, err = git.GetStagedPaths(repoRoot, cfg.Pathspecs); err != nil {
+			return nil, err
+		}
+	}
+	data.SuggestedType = commit.SuggestType(paths, cfg.ParsedTypeRules)
+	data.SuggestedScope = commit.SuggestScope(paths, data.AllowedScopes)
+	data.PossibleBreaking = commit.PossibleBreaking(diff)
+	decls, err := git.NewDeclMatcher(strings.Fields(cfg.DeclPatterns))
+	if err != nil {
+		return nil, err
+	}
+	data.APIAdditions = decls.Added(diff)
+	if repoRoot != "" && cfg.HistoryCount > 0 {
+		recent, err := git.GetRecentCommitMessages(repoRoot, cfg.HistoryCount)
+		if err != nil {
+			return nil, err
+		}
+		data.RecentCommits = recent
+	}
+
+	tok := configTokenizer(cfg)
+	var prompt string
+	for {
+		var err error
+		prompt, err = template.Execute(cfg.TemplateName, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare prompt: %w", err)
+		}
+		if len(data.RecentCommits) == 0 || tok.Count(prompt) <= cfg.MaxInputTokens {
+			break
+		}
+		data.RecentCommits = data.RecentCommits[:len(data.RecentCommits)-1]
+	}
+
+	// Cut the diff rather than the rendered prompt, so the instructions
+	// around it are never lost
+	if overflow := tok.Count(prompt) - cfg.MaxInputTokens; overflow > 0 {
+		if err := checkTemplateOverhead(cfg, data, tok); err != nil {
+			return nil, err
+		}
+		budget := max(tok.Count(data.Diff)-overflow, 0)
+		data.Diff, _ = llm.TruncateDiff(data.Diff, budget, tok)
+		slog.Warn("Diff was truncated to fit within token limits", "max_input_tokens", cfg.MaxInputTokens)
+		var err error
+		if prompt, err = template.Execute(cfg.TemplateName, data); err != nil {
+			return nil, fmt.Errorf("failed to prepare prompt: %w", err)
+		}
+	}
+	slog.Debug("Prepared prompt", "template", cfg.TemplateName, "characters", len(prompt),
+		"recent_commits", len(data.RecentCommits), "suggested_type", data.SuggestedType,
+		"suggested_scope", data.SuggestedScope, "possible_breaking", data.PossibleBreaking,
+		"api_additions", len(data.APIAdditions))
+
+	return &Prepared{RepoRoot: repoRoot, Diff: diff, Prompt: prompt, cfg: cfg}, nil
+}
+
+// looksLikeDiff reports whether text starts like a unified diff
+func looksLikeDiff(text string) bool {
+	text = strings.TrimLeft(text, "\n")
+	return strings.HasPrefix(text, "diff --git ") || strings.HasPrefix(text, "--- ")
+}
+
+// Generate produces a commit message for the staged changes. Messages that
+// fail the configured checks are returned as an *InvalidMessageError.
+func (g *Generator) Generate(ctx context.Context, opts GenerateOptions) (GenerateResult, error) {
+	prepared := opts.Prepared
+	if prepared == nil {
+		var err error
+		if prepared, err = g.Prepare(opts.Dir); err != nil {
+			return GenerateResult{}, err
+		}
+	}
+	cfg := prepared.cfg
+	if opts.Temperature != nil {
+		cfg.Temperature = *opts.Temperature
+	}
+
+	var message string
+	var usage *llm.Usage
+	var err error
+	if cfg.NoLLM {
+		message, err = offlineMessage(prepared)
+	} else if cfg.Detailed {
+		// Subject and per-file bullets are generated in two passes, with
+		// the budgets already split by stagedDiff
+		message, usage, err = generateValidMessage(ctx, cfg, prepared.Prompt)
+		if err == nil {
+			var bulletUsage *llm.Usage
+			message, bulletUsage, err = addFileBullets(ctx, cfg, prepared.RepoRoot, prepared.Diff, message)
+			usage = llm.AddUsage(usage, bulletUsage)
+		}
+	} else {
+		message, usage, err = generateValidMessage(ctx, cfg, prepared.Prompt)
+	}
+	if err != nil {
+		return GenerateResult{}, fmt.Errorf("failed to generate commit message: %w", err)
+	}
+	if cfg.SubjectOnly {
+		// Models do not always follow the single line instruction
+		message, _, _ = strings.Cut(message, "\n")
+	}
+	// Models sometimes glue the body to the subject
+	message = commit.NormalizeLayout(message)
+
+	result := GenerateResult{Message: message, RepoRoot: prepared.RepoRoot, Model: cfg.LLMModel}
+	if length := utf8.RuneCountInString(message); cfg.MaxMessageChars > 0 && length > cfg.MaxMessageChars {
+		var shortenUsage *llm.Usage
+		result.OverlongChars = length
+		message, shortenUsage, err = shortenMessage(ctx, cfg, prepared.Prompt, message)
+		usage = llm.AddUsage(usage, shortenUsage)
+		if err != nil {
+			return GenerateResult{}, fmt.Errorf("failed to shorten commit message: %w", err)
+		}
+		result.Message = message
+	}
+	result.Usage = usage
+	if problem := checkMessage(message, cfg); problem != nil {
+		return result, &InvalidMessageError{Message: message, Reason: problem}
+	}
+	return result, nil
+}
+
+// findRepo returns the root of the repository containing dir, failing clearly
+// if git is missing, and roots the pathspecs in cfg
+func findRepo(cfg *config.Config, dir string) (string, error) {
+	if err := git.EnsureGitAvailable(); err != nil {
+		return "", err
+	}
+	repoRoot, err := git.GetRepoRoot(dir)
+	if err != nil {
+		return "", fmt.Errorf("This command must be run inside a git repository. %w", err)
+	}
+	slog.Debug("Found git repository", "path", repoRoot)
+
+	// Pathspecs are given relative to dir but git runs at the root
+	if len(cfg.Pathspecs) > 0 {
+		prefix, err := git.GetPrefix(dir)
+		if err != nil {
+			return "", err
+		}
+		cfg.Pathspecs = rootPathspecs(prefix, cfg.Pathspecs)
+		slog.Debug("Limiting diff to pathspecs", "pathspecs", cfg.Pathspecs)
+	}
+	return repoRoot, nil
+}
+
+// stagedDiff returns the staged diff of the repository at repoRoot,
+// switching to the smart diff for large commits. The input token budget in
+// cfg is clamped to the model's context window first, so the model and
+// template must be final. The estimated size of each staged file is returned
+// too. It returns ErrNoStagedChanges if nothing is staged.
+func stagedDiff(cfg *config.Config, repoRoot string) (string, []git.FileReport, error) {
+	slog.Debug("Using configuration", "config", cfg.String())
+	if len(cfg.ModelDefaults) > 0 {
+		slog.Debug("Applied model defaults", "model", cfg.LLMModel, "settings", strings.Join(cfg.ModelDefaults, " "))
+	}
+
+	if err := clampInputTokens(cfg); err != nil {
+		return "", nil, err
+	}
+	if cfg.Detailed {
+		// The subject and bullet passes each get half of the budgets, so
+		// the diff is fitted to the half that is actually sent
+		*cfg = splitBudget(*cfg)
+	}
+
+	// Get the staged diff (check if using smart diff for large commits)
+	var diff string
+	var files []git.FileReport
+	diffOpts := diffOptions(*cfg)
+	// First, get a quick count of changed files
+	filesList, err := git.GetStagedFilesList(repoRoot, diffOpts.Pathspecs)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get staged files list: %w", err)
+	}
+
+	// Check if there are any staged changes
+	if filesList == "" {
+		return "", nil, ErrNoStagedChanges
+	}
+
+	// Count files by counting newlines
+	fileCount := len(strings.Split(strings.TrimSpace(filesList), "\n"))
+
+	// For multi-file commits, use smart diff to preserve context, or only
+	// the file list above the MaxFiles cap
+	if fileCount > 5 || (cfg.MaxFiles > 0 && fileCount > cfg.MaxFiles) { // Threshold for "large" commits
+		slog.Debug("Large commit detected, using smart diff processing", "files", fileCount)
+		// Use the smart diff processor with the configured token limit
+		smartDiff, report, err := git.PrepareSmartDiffWithReport(repoRoot, cfg.MaxInputTokens, diffOpts)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to prepare smart diff: %w", err)
+		}
+		slog.Debug(report.String())
+		diff = smartDiff
+		files = report.Files
+	} else {
+		// For smaller commits, use the standard diff
+		standardDiff, err := git.GetStagedDiff(repoRoot, diffOpts)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get staged changes: %w", err)
+		}
+		diff = standardDiff
+		files = git.EstimateFileTokens(diff, diffOpts.Tokenizer)
+	}
+
+	if diff == "" && cfg.IgnoreBinary {
+		return "", nil, fmt.Errorf("only binary files are staged, and AICOMMIT_IGNORE_BINARY leaves them out")
+	}
+
+	slog.Debug("Retrieved staged diff", "characters", len(diff))
+
+	return diff, files, nil
+}
+
+// clampInputTokens makes sure the input budget in cfg fits the model's
+// context window. Unless MAX_INPUT_TOKENS is set, a model with a known
+// window gets all of it that the output does not need.
+func clampInputTokens(cfg *config.Config) error {
+	modelLimits, err := llm.ParseModelLimits(cfg.ModelLimits)
+	if err != nil {
+		return fmt.Errorf("invalid MODEL_LIMITS: %w", err)
+	}
+	if cfg.AutoInputTokens {
+		if maxInputTokens, ok := llm.WindowInputTokens(cfg.LLMModel, cfg.MaxOutputTokens, modelLimits); ok {
+			slog.Debug("Using the model's context window for the input budget",
+				"model", cfg.LLMModel, "max_input_tokens", maxInputTokens)
+			cfg.MaxInputTokens = maxInputTokens
+			return nil
+		}
+	}
+	maxInputTokens, clamped, err := llm.ClampInputTokens(cfg.LLMModel, cfg.MaxInputTokens, cfg.MaxOutputTokens, modelLimits)
+	if err != nil {
+		return err
+	}
+	if clamped {
+		slog.Info("Clamped max input tokens to fit the model's context window",
+			"from", cfg.MaxInputTokens, "to", maxInputTokens, "model", cfg.LLMModel)
+		cfg.MaxInputTokens = maxInputTokens
+	}
+	return nil
+}
+
+// generateValidMessage generates a message and regenerates it with feedback
+// while it fails the required pattern
+func generateValidMessage(ctx context.Context, cfg config.Config, fullPrompt string) (string, *llm.Usage, error) {
+	generatedMsg, usage, err := generateMessage(ctx, cfg, fullPrompt)
+	if err != nil || cfg.RequirePattern == "" {
+		return generatedMsg, usage, err
+	}
+
+	requirePattern := regexp.MustCompile(cfg.RequirePattern) // Validated at config load
+	for attempt := 1; !requirePattern.MatchString(generatedMsg); attempt++ {
+		if attempt > cfg.MaxRetries {
+			return "", nil, fmt.Errorf("message does not match required pattern %q after %d retries:\n%s",
+				cfg.RequirePattern, cfg.MaxRetries, generatedMsg)
+		}
+		slog.Info("Message does not match required pattern, regenerating", "attempt", attempt, "max_retries", cfg.MaxRetries)
+
+		var retryUsage *llm.Usage
+		retryPrompt := fullPrompt + patternFeedback(generatedMsg, cfg.RequirePattern)
+		generatedMsg, retryUsage, err = generateMessage(ctx, cfg, retryPrompt)
+		if err != nil {
+			return "", nil, err
+		}
+		usage = llm.AddUsage(usage, retryUsage)
+	}
+
+	return generatedMsg, usage, nil
+}
+
+// shortenMessage brings a message over MaxMessageChars under the limit, by
+// asking the model for a shorter one first if configured. Whatever is still
+// too long is truncated.
+func shortenMessage(ctx context.Context, cfg config.Config, prompt, message string) (string, *llm.Usage, error) {
+	var usage *llm.Usage
+	if cfg.MessageOverflow == config.OverflowReprompt {
+		slog.Info("Message is too long, asking for a shorter one", "characters", utf8.RuneCountInString(message), "max", cfg.MaxMessageChars)
+		var err error
+		message, usage, err = generateMessage(ctx, cfg, prompt+lengthFeedback(message, cfg.MaxMessageChars))
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	return truncateMessage(message, cfg.MaxMessageChars), usage, nil
+}
+
+// lengthFeedback builds the prompt addendum asking the model for a shorter
+// message
+func lengthFeedback(previous string, maxChars int) string {
+	return fmt.Sprintf("\n\nYour previous commit message was %d characters long, but the limit is %d. "+
+		"Generate a much shorter commit message.", utf8.RuneCountInString(previous), maxChars)
+}
+
+// truncateMessage cuts message to at most maxChars characters, at the last
+// line or sentence boundary if there is one, otherwise at a word boundary
+func truncateMessage(message string, maxChars int) string {
+	runes := []rune(message)
+	if len(runes) <= maxChars {
+		return message
+	}
+	cut := string(runes[:maxChars])
+
+	boundary := max(strings.LastIndex(cut, "\n"), strings.LastIndex(cut, ". ")+1)
+	if boundary <= 0 {
+		boundary = strings.LastIndexAny(cut, " \t")
+	}
+	if boundary > 0 {
+		cut = cut[:boundary]
+	}
+	return strings.TrimSpace(cut)
+}
+
+// minBoilerplateOverlap is the shortest message that is rejected for merely
+// appearing somewhere in the template boilerplate. Shorter messages are only
+// rejected when they are the whole boilerplate, so a subject that happens to
+// match an example in the template still passes.
+const minBoilerplateOverlap = 40
+
+// repeatsBoilerplate reports whether message, ignoring case and whitespace,
+// is the template boilerplate or a long enough piece of it
+func repeatsBoilerplate(message, boilerplate string) bool {
+	message = normalizeText(message)
+	boilerplate = normalizeText(boilerplate)
+	if message == boilerplate {
+		return true
+	}
+	return utf8.RuneCountInString(message) >= minBoilerplateOverlap && strings.Contains(boilerplate, message)
+}
+
+// normalizeText lowercases text and collapses its whitespace to single spaces
+func normalizeText(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// checkMessage rejects messages that are too short, are the truncation
+// marker, only repeat the template instructions, or break the header format
+// of the template's commit spec, the repository's commitlint rules or the
+// allowed scopes
+func checkMessage(message string, cfg config.Config) error {
+	message = strings.TrimSpace(message)
+	if message == llm.TruncationMarker {
+		return fmt.Errorf("message is the truncation marker")
+	}
+	if utf8.RuneCountInString(message) < cfg.MinMessageLength {
+		return fmt.Errorf("message is shorter than %d characters", cfg.MinMessageLength)
+	}
+
+	// Rendering the template without a diff leaves only the boilerplate
+	if boilerplate, err := template.Execute(cfg.TemplateName, templateData(cfg, "")); err == nil && repeatsBoilerplate(message, boilerplate) {
+		return fmt.Errorf("message only repeats the template instructions")
+	}
+
+	// Translated types and offline messages cannot follow the spec's types
+	if spec, ok := commitSpecs[cfg.TemplateName]; ok && !cfg.LocalizeType && !cfg.NoLLM {
+		if cfg.Commitlint != nil {
+			spec = spec.withLintRules(*cfg.Commitlint)
+		}
+		if len(cfg.ParsedScopes) > 0 {
+			spec.scopes = cfg.ParsedScopes
+		}
+		if err := spec.check(message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
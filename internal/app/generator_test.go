@@ -0,0 +1,476 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/llm"
+	"github.com/cstobie/ai-commit/internal/template"
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+// testConfig returns the settings the generator needs, without reading the
+// environment
+func testConfig() config.Config {
+	return config.Config{
+		LLMModel:        "test/model",
+		TemplateName:    "conventional",
+		MaxInputTokens:  4000,
+		MaxOutputTokens: 200,
+		Tokenizer:       "words",
+		TimeoutSeconds:  30,
+	}
+}
+
+func TestPrepareDiffKeepsInstructions(t *testing.T) {
+	var diff strings.Builder
+	for f := 0; f < 10; f++ {
+		fmt.Fprintf(&diff, "diff --git a/f%d.go b/f%d.go\n--- a/f%d.go\n+++ b/f%d.go\n@@ -1,50 +1,50 @@\n", f, f, f, f)
+		for l := 0; l < 50; l++ {
+			fmt.Fprintf(&diff, "+changed line %d in file %d\n", l, f)
+		}
+	}
+
+	cfg := testConfig()
+	cfg.MaxInputTokens = 600
+	prepared, err := NewGenerator(cfg).PrepareDiff(diff.String())
+	if err != nil {
+		t.Fatalf("PrepareDiff error = %v", err)
+	}
+
+	full, err := template.Execute(cfg.TemplateName, template.Data{Diff: "DIFF"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, after, _ := strings.Cut(full, "DIFF")
+	if !strings.HasPrefix(prepared.Prompt, before) || !strings.HasSuffix(prepared.Prompt, after) {
+		t.Errorf("instructions around the diff were cut:\n%s", prepared.Prompt)
+	}
+	if !strings.Contains(prepared.Prompt, "\n"+llm.TruncationMarker+"\n") {
+		t.Errorf("prompt has no truncation marker line:\n%s", prepared.Prompt)
+	}
+	if !strings.Contains(prepared.Prompt, "+changed line 0 in file 0\n+changed line 1 in file 0\n") {
+		t.Errorf("diff lines were not kept on separate lines:\n%s", prepared.Prompt)
+	}
+	if n := len(strings.Fields(prepared.Prompt)); n > cfg.MaxInputTokens {
+		t.Errorf("prompt has %d words, want at most %d", n, cfg.MaxInputTokens)
+	}
+}
+
+func TestCheckMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		minLen  int
+		noLLM   bool
+		wantErr bool
+	}{
+		{"valid", "feat: add login form", 10, false, false},
+		{"truncation marker", llm.TruncationMarker, 10, false, true},
+		{"too short", "fix: typo", 10, false, true},
+		{"length counts runes", "feat: añadir", 13, false, true},
+		{"multibyte at length", "feat: añadir", 12, false, false},
+		{"repeats instruction", "  use the imperative, present tense\n(\"add\" not \"added\") ", 10, true, true},
+		{"short piece of boilerplate", "Add a colon and space", 10, true, false},
+		{"breaks spec", "Added the login form", 10, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.MinMessageLength = tt.minLen
+			cfg.NoLLM = tt.noLLM
+			if err := checkMessage(tt.message, cfg); (err != nil) != tt.wantErr {
+				t.Errorf("checkMessage(%q) error = %v, wantErr %v", tt.message, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrepareDiffClampsInputTokens(t *testing.T) {
+	tests := []struct {
+		name        string
+		modelLimits string
+		want        int
+	}{
+		{"small window", "test/model=1000", 800},
+		{"large window", "test/model=128000", 4000},
+		{"unknown window", "", 4000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.ModelLimits = tt.modelLimits
+			prepared, err := NewGenerator(cfg).PrepareDiff("diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-a\n+b\n")
+			if err != nil {
+				t.Fatalf("PrepareDiff error = %v", err)
+			}
+			if got := prepared.cfg.MaxInputTokens; got != tt.want {
+				t.Errorf("MaxInputTokens = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareWithoutGit(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if _, err := NewGenerator(testConfig()).Prepare(t.TempDir()); !errors.Is(err, git.ErrGitNotFound) {
+		t.Errorf("Prepare() without git error = %v, want %v", err, git.ErrGitNotFound)
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		maxChars int
+		want     string
+	}{
+		{"short enough", "fix: handle nil", 50, "fix: handle nil"},
+		{"line boundary", "fix: handle nil\n\nLong body text here.", 20, "fix: handle nil"},
+		{"sentence boundary", "fix: a. Second sentence is long", 15, "fix: a."},
+		{"word boundary", "fix: handle nil pointers", 18, "fix: handle nil"},
+		{"multibyte", "fix: ñandú ñandú ñandú", 12, "fix: ñandú"},
+		{"no boundary", "abcdefghij", 5, "abcde"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateMessage(tt.message, tt.maxChars); got != tt.want {
+				t.Errorf("truncateMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateOversizedMessage(t *testing.T) {
+	essay := "feat: add login\n\n" + strings.Repeat("This sentence goes on and on. ", 200)
+	tests := []struct {
+		name      string
+		overflow  string
+		replies   []string
+		want      string
+		wantCalls int
+	}{
+		{"truncate", config.OverflowTruncate, []string{essay}, "", 1},
+		{"reprompt", config.OverflowReprompt, []string{essay, "feat: add login\n\nAdds a login form."}, "feat: add login\n\nAdds a login form.", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newChatServer(t, tt.replies...)
+			cfg := serverConfig(server)
+			cfg.MaxMessageChars = 200
+			cfg.MessageOverflow = tt.overflow
+			generator := NewGenerator(cfg)
+			prepared, err := generator.PrepareDiff("diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-a\n+b\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := generator.Generate(context.Background(), GenerateOptions{Prepared: prepared})
+			if err != nil {
+				t.Fatalf("Generate error = %v", err)
+			}
+			if n := utf8.RuneCountInString(result.Message); n > cfg.MaxMessageChars {
+				t.Errorf("message has %d characters, want at most %d", n, cfg.MaxMessageChars)
+			}
+			if want := utf8.RuneCountInString(strings.TrimSpace(essay)); result.OverlongChars != want {
+				t.Errorf("OverlongChars = %d, want %d", result.OverlongChars, want)
+			}
+			if tt.want != "" && result.Message != tt.want {
+				t.Errorf("message = %q, want %q", result.Message, tt.want)
+			}
+			if !strings.HasPrefix(result.Message, "feat: add login") {
+				t.Errorf("message lost its subject: %q", result.Message)
+			}
+			if calls := len(server.requests()); calls != tt.wantCalls {
+				t.Errorf("made %d requests, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPreparePromptRecentCommits(t *testing.T) {
+	repo := testutil.NewRepo(t, nil)
+	for _, subject := range []string{"oldest", "middle", "newest"} {
+		testutil.RunGit(t, repo, "commit", "--quiet", "--allow-empty", "-m", "chore: "+subject+" "+strings.Repeat("word ", 50))
+	}
+	testutil.WriteFile(t, repo, "main.go", "package main\n")
+	testutil.RunGit(t, repo, "add", "main.go")
+	diff := "diff --git a/main.go b/main.go\n--- /dev/null\n+++ b/main.go\n@@ -0,0 +1 @@\n+package main\n"
+
+	base, err := preparePrompt(testConfig(), repo, diff, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseTokens := len(strings.Fields(base.Prompt))
+
+	tests := []struct {
+		name      string
+		count     int
+		maxTokens int
+		want      []string
+	}{
+		{"off", 0, 4000, nil},
+		{"all fit", 3, 4000, []string{"newest", "middle", "oldest"}},
+		{"oldest dropped over budget", 3, baseTokens + 80, []string{"newest"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.HistoryCount = tt.count
+			cfg.MaxInputTokens = tt.maxTokens
+			prepared, err := preparePrompt(cfg, repo, diff, nil)
+			if err != nil {
+				t.Fatalf("preparePrompt error = %v", err)
+			}
+			for _, subject := range []string{"newest", "middle", "oldest"} {
+				want := slices.Contains(tt.want, subject)
+				if got := strings.Contains(prepared.Prompt, "chore: "+subject); got != want {
+					t.Errorf("prompt includes %q commit = %v, want %v", subject, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPrepareIncludesDiffStat(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{"main.go": "package main\n"})
+	testutil.WriteFile(t, repo, "main.go", "package main\n\nfunc main() {}\n")
+	testutil.RunGit(t, repo, "add", "main.go")
+
+	prepared, err := NewGenerator(testConfig()).Prepare(repo)
+	if err != nil {
+		t.Fatalf("Prepare error = %v", err)
+	}
+	stat := " main.go | 2 ++\n 1 file changed, 2 insertions(+)"
+	if !strings.Contains(prepared.Prompt, "Files changed:\n```text\n"+stat+"\n```") {
+		t.Errorf("prompt does not include the diff stat %q:\n%s", stat, prepared.Prompt)
+	}
+}
+
+func TestPrepareDiffHint(t *testing.T) {
+	cfg := testConfig()
+	cfg.Hint = "Needed for the upcoming billing migration"
+	prepared, err := NewGenerator(cfg).PrepareDiff("diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-a\n+b\n")
+	if err != nil {
+		t.Fatalf("PrepareDiff error = %v", err)
+	}
+	if !strings.Contains(prepared.Prompt, "Author's intent: "+cfg.Hint) {
+		t.Errorf("prompt does not include the hint:\n%s", prepared.Prompt)
+	}
+}
+
+func TestPrepareDiffPossibleBreaking(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want bool
+	}{
+		{"removed exported function", "diff --git a/api.go b/api.go\n--- a/api.go\n+++ b/api.go\n@@ -1,2 +0,0 @@\n-func Parse(s string) error {\n-}\n", true},
+		{"internal change", "diff --git a/api.go b/api.go\n--- a/api.go\n+++ b/api.go\n@@ -1 +1 @@\n-\treturn nil\n+\treturn err\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prepared, err := NewGenerator(testConfig()).PrepareDiff(tt.diff)
+			if err != nil {
+				t.Fatalf("PrepareDiff error = %v", err)
+			}
+			if got := strings.Contains(prepared.Prompt, "BREAKING CHANGE:"); got != tt.want {
+				t.Errorf("prompt mentions breaking changes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckMessageAllowedScopes(t *testing.T) {
+	tests := []struct {
+		message string
+		wantErr bool
+	}{
+		{"feat(api): add login route", false},
+		{"feat: add login route", false},
+		{"feat(auth): add login route", true},
+	}
+	for _, tt := range tests {
+		cfg := testConfig()
+		cfg.ParsedScopes = []string{"web", "api", "db"}
+		if err := checkMessage(tt.message, cfg); (err != nil) != tt.wantErr {
+			t.Errorf("checkMessage(%q) error = %v, wantErr %v", tt.message, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPrepareDiffAllowedScopes(t *testing.T) {
+	cfg := testConfig()
+	cfg.ParsedScopes = []string{"web", "api", "db"}
+	prepared, err := NewGenerator(cfg).PrepareDiff("diff --git a/apps/api/server.go b/apps/api/server.go\n--- a/apps/api/server.go\n+++ b/apps/api/server.go\n@@ -1 +1 @@\n-a\n+b\n")
+	if err != nil {
+		t.Fatalf("PrepareDiff error = %v", err)
+	}
+	want := `The scope, if any, must be one of: web, api, db. The changed directories suggest "api".`
+	if !strings.Contains(prepared.Prompt, want) {
+		t.Errorf("prompt does not include %q:\n%s", want, prepared.Prompt)
+	}
+}
+
+func TestPrepareDiffAPIAdditions(t *testing.T) {
+	diff := "diff --git a/auth.go b/auth.go\n--- a/auth.go\n+++ b/auth.go\n@@ -1 +1,7 @@\n" +
+		"+func Login(user string) error {\n+\treturn nil\n+}\n+func Logout() {\n+}\n"
+	prepared, err := NewGenerator(testConfig()).PrepareDiff(diff)
+	if err != nil {
+		t.Fatalf("PrepareDiff error = %v", err)
+	}
+	want := "API additions (declarations this change adds, with their files):\n" +
+		"- func Login(user string) error (auth.go)\n- func Logout() (auth.go)\n"
+	if !strings.Contains(prepared.Prompt, want) {
+		t.Errorf("prompt does not list both functions:\n%s", prepared.Prompt)
+	}
+}
+
+func TestPrepareDiffTinyBudget(t *testing.T) {
+	var diff strings.Builder
+	diff.WriteString("diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1,500 @@\n")
+	for l := 0; l < 500; l++ {
+		fmt.Fprintf(&diff, "+line %d of the new file\n", l)
+	}
+	tests := []struct {
+		name    string
+		budget  int
+		wantErr bool
+	}{
+		{"below the template overhead", 10, true},
+		{"room for part of the diff", 1500, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.MaxInputTokens = tt.budget
+			prepared, err := NewGenerator(cfg).PrepareDiff(diff.String())
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "too small for template conventional") {
+					t.Errorf("PrepareDiff error = %v, want a too small budget error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PrepareDiff error = %v", err)
+			}
+			if !strings.Contains(prepared.Prompt, "+line 0 of the new file") {
+				t.Errorf("prompt lost the start of the diff:\n%s", prepared.Prompt)
+			}
+			if strings.Contains(prepared.Prompt, "+line 499 of the new file") {
+				t.Error("prompt was not truncated")
+			}
+		})
+	}
+}
+
+func TestSelectTemplate(t *testing.T) {
+	rules, err := config.ParseTemplateRules("simple=files<3;conventional")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		files       int
+		rules       []config.TemplateRule
+		subjectOnly bool
+		want        string
+	}{
+		{"one file", 1, rules, false, "simple"},
+		{"below the limit", 2, rules, false, "simple"},
+		{"at the limit", 3, rules, false, "conventional"},
+		{"above the limit", 4, rules, false, "conventional"},
+		{"no rules", 1, nil, false, "angular"},
+		{"subject only", 1, rules, true, "angular"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testutil.NewRepo(t, nil)
+			for i := 0; i < tt.files; i++ {
+				testutil.WriteFile(t, repo, fmt.Sprintf("f%d.go", i), "package main\n")
+			}
+			testutil.RunGit(t, repo, "add", "--all")
+			cfg := testConfig()
+			cfg.TemplateName = "angular"
+			cfg.ParsedTemplateRules = tt.rules
+			cfg.SubjectOnly = tt.subjectOnly
+			if err := selectTemplate(&cfg, repo); err != nil {
+				t.Fatalf("selectTemplate error = %v", err)
+			}
+			if cfg.TemplateName != tt.want {
+				t.Errorf("TemplateName = %q, want %q", cfg.TemplateName, tt.want)
+			}
+		})
+	}
+}
+
+func TestClampInputTokensAutoBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     string
+		auto      bool
+		maxInput  int
+		maxOutput int
+		want      int
+	}{
+		{"known large-context model", "openai/gpt-4o", true, 4000, 200, 127800},
+		{"reserves the output", "openai/gpt-4o", true, 4000, 8000, 120000},
+		{"unknown model", "unknown/model", true, 4000, 200, 4000},
+		{"explicit budget is not raised", "openai/gpt-4o", false, 4000, 200, 4000},
+		{"explicit budget is clamped", "openai/gpt-4o", false, 200000, 1000, 127000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.LLMModel = tt.model
+			cfg.AutoInputTokens = tt.auto
+			cfg.MaxInputTokens = tt.maxInput
+			cfg.MaxOutputTokens = tt.maxOutput
+			if err := clampInputTokens(&cfg); err != nil {
+				t.Fatalf("clampInputTokens error = %v", err)
+			}
+			if cfg.MaxInputTokens != tt.want {
+				t.Errorf("MaxInputTokens = %d, want %d", cfg.MaxInputTokens, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareDetailedSplitsBudget(t *testing.T) {
+	repo := testutil.NewRepo(t, nil)
+	// Enough files for the smart diff, together over half the budget
+	for i := range 6 {
+		name := fmt.Sprintf("file%d.go", i)
+		testutil.WriteFile(t, repo, name, strings.Repeat(fmt.Sprintf("var v%d = 1\n", i), 100))
+		testutil.RunGit(t, repo, "add", name)
+	}
+	cfg := testConfig()
+	cfg.MaxInputTokens = 3000
+	cfg.Detailed = true
+
+	prepared, err := NewGenerator(cfg).Prepare(repo)
+	if err != nil {
+		t.Fatalf("Prepare error = %v", err)
+	}
+	if prepared.cfg.MaxInputTokens != 1500 || prepared.cfg.MaxOutputTokens != 100 {
+		t.Errorf("budgets = %d in, %d out; want 1500, 100", prepared.cfg.MaxInputTokens, prepared.cfg.MaxOutputTokens)
+	}
+	if tokens := configTokenizer(prepared.cfg).Count(prepared.Prompt); tokens > 1500 {
+		t.Errorf("prompt has %d tokens, want at most the 1500 of one pass", tokens)
+	}
+	if strings.Contains(prepared.Prompt, llm.TruncationMarker) {
+		t.Errorf("prompt was truncated rather than fitted file by file:\n%s", prepared.Prompt)
+	}
+	for i := range 6 {
+		if name := fmt.Sprintf("file%d.go", i); !strings.Contains(prepared.Prompt, "### Added: "+name) {
+			t.Errorf("prompt lost %s", name)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/secrets"
+)
+
+// largestFilesShown is the number of files listed in the large diff warning
+const largestFilesShown = 5
+
+// confirmLargeDiff warns when the staged files, as estimated by Prepare, are
+// far larger than the input budget and, in interactive mode, asks whether to
+// continue. It returns false if the user aborted.
+func confirmLargeDiff(ctx context.Context, cfg config.Config, files []git.FileReport, interactive bool) (bool, error) {
+	// Nothing is sent to the API in no-LLM mode
+	if cfg.DiffWarnMultiplier <= 0 || cfg.NoLLM {
+		return true, nil
+	}
+
+	total := 0
+	for _, file := range files {
+		total += file.EstimatedTokens
+	}
+	budget := largeDiffBudget(cfg)
+	if total <= int(float64(budget)*cfg.DiffWarnMultiplier) {
+		return true, nil
+	}
+
+	largest := slices.Clone(files)
+	sort.SliceStable(largest, func(a, b int) bool {
+		return largest[a].EstimatedTokens > largest[b].EstimatedTokens
+	})
+
+	warning := fmt.Sprintf("Warning: staged changes are ~%d tokens, more than %gx the input limit of %d tokens.\nLargest files:\n",
+		total, cfg.DiffWarnMultiplier, budget)
+	for i, file := range largest {
+		if i >= largestFilesShown {
+			break
+		}
+		warning += fmt.Sprintf("  ~%d tokens  %s\n", file.EstimatedTokens, file.Path)
+	}
+
+	outcome := "a truncated diff"
+	if total <= cfg.MaxInputTokens {
+		outcome = "the full diff"
+	}
+	if !interactive {
+		slog.Warn(warning + "Proceeding with " + outcome + ".")
+		return true, nil
+	}
+
+	out := messageOutput(cfg)
+	fmt.Fprint(out, warning)
+	return confirm(ctx, out, "Continue with "+outcome+"?", true)
+}
+
+// largeDiffBudget returns the input budget the large diff warning is
+// relative to: MAX_INPUT_TOKENS, or at most the default when the budget
+// was grown to the model's context window
+func largeDiffBudget(cfg config.Config) int {
+	if cfg.AutoInputTokens {
+		return min(cfg.MaxInputTokens, config.DefaultMaxInputTokens)
+	}
+	return cfg.MaxInputTokens
+}
+
+// confirmSecrets checks the diff about to be sent for likely secrets. In
+// interactive mode it asks whether to send it anyway; otherwise it refuses.
+// It returns false if the user aborted.
+func confirmSecrets(ctx context.Context, cfg config.Config, diff string, interactive bool) (bool, error) {
+	if !cfg.SecretScan || cfg.NoLLM {
+		return true, nil
+	}
+
+	scanner, err := secrets.NewScanner(strings.Fields(cfg.SecretPatterns), strings.Fields(cfg.SecretAllowlist))
+	if err != nil {
+		return false, err
+	}
+	findings := scanner.Scan(diff)
+	if len(findings) == 0 {
+		return true, nil
+	}
+
+	warning := "Possible secrets in the staged changes:\n"
+	for _, finding := range findings {
+		warning += "  " + finding.String() + "\n"
+	}
+
+	if !interactive {
+		return false, fmt.Errorf("%srefusing to send them to the API; unstage them, or allow them with "+
+			"AICOMMIT_SECRET_ALLOWLIST or AICOMMIT_SECRET_SCAN=false", warning)
+	}
+
+	out := messageOutput(cfg)
+	fmt.Fprint(out, "Warning: "+warning)
+	return confirm(ctx, out, "Send the diff to the API anyway?", false)
+}
+
+// confirmRepoState warns about committing on a detached HEAD or during a
+// merge, and asks before committing in the middle of a rebase, cherry-pick or
+// revert. It returns false if the user aborted.
+func confirmRepoState(ctx context.Context, cfg config.Config, state git.RepoState, interactive bool) (bool, error) {
+	out := messageOutput(cfg)
+	if state.Detached {
+		fmt.Fprintln(out, "Warning: HEAD is detached; the commit will not be on any branch.")
+	}
+
+	switch state.Operation {
+	case "":
+		return true, nil
+	case git.OperationMerge:
+		if state.MergeSubject != "" {
+			fmt.Fprintf(out, "A merge is in progress; the message will start with %q.\n", state.MergeSubject)
+		} else {
+			fmt.Fprintln(out, "A merge is in progress; this will create the merge commit.")
+		}
+		return true, nil
+	}
+
+	warning := fmt.Sprintf("Warning: a %s is in progress; committing now adds a separate commit to it.", state.Operation)
+	if !interactive {
+		fmt.Fprintln(out, warning)
+		return true, nil
+	}
+	fmt.Fprintln(out, warning)
+	return confirm(ctx, out,
+		fmt.Sprintf("Commit anyway, instead of aborting to use 'git %s --continue'?", state.Operation), false)
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cstobie/ai-commit/internal/config"
+)
+
+// guidelinesCandidates are looked up in the repository root, in order, when
+// no guidelines file is configured
+var guidelinesCandidates = []string{".gitmessage", "CONTRIBUTING.md"}
+
+// loadGuidelines reads the repository's commit guidelines, trimmed to
+// GuidelinesChars. It returns an empty string if guidelines are disabled or
+// no candidate file exists; a configured file that is missing is an error.
+func loadGuidelines(cfg config.Config, repoRoot string) (string, error) {
+	if cfg.GuidelinesChars == 0 {
+		return "", nil
+	}
+
+	paths := guidelinesCandidates
+	if cfg.GuidelinesFile != "" {
+		paths = []string{cfg.GuidelinesFile}
+	}
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(repoRoot, path)
+		}
+		content, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) && cfg.GuidelinesFile == "" {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("unable to read guidelines file: %w", err)
+		}
+		slog.Debug("Using commit guidelines", "path", path, "characters", len(content))
+		return trimGuidelines(string(content), cfg.GuidelinesChars), nil
+	}
+	return "", nil
+}
+
+// trimGuidelines shortens text to at most maxChars characters, cutting at
+// the last line break that fits when there is one
+func trimGuidelines(text string, maxChars int) string {
+	text = strings.TrimSpace(text)
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text
+	}
+	trimmed := string(runes[:maxChars])
+	if cut := strings.LastIndex(trimmed, "\n"); cut > 0 {
+		trimmed = trimmed[:cut]
+	}
+	return strings.TrimSpace(trimmed) + "\n[...]"
+}
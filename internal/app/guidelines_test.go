@@ -0,0 +1,62 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestLoadGuidelines(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		file    string
+		chars   int
+		want    string
+		wantErr bool
+	}{
+		{"none", nil, "", 1000, "", false},
+		{"gitmessage first", map[string]string{".gitmessage": "Use the imperative mood.\n", "CONTRIBUTING.md": "Be nice.\n"}, "", 1000, "Use the imperative mood.", false},
+		{"contributing", map[string]string{"CONTRIBUTING.md": "Be nice.\n"}, "", 1000, "Be nice.", false},
+		{"configured file", map[string]string{"docs/commits.md": "Prefix the ticket.\n", ".gitmessage": "Ignored.\n"}, "docs/commits.md", 1000, "Prefix the ticket.", false},
+		{"configured file missing", nil, "docs/commits.md", 1000, "", true},
+		{"disabled", map[string]string{".gitmessage": "Ignored.\n"}, "", 0, "", false},
+		{"trimmed at a line break", map[string]string{".gitmessage": "First rule.\nSecond rule.\nThird rule.\n"}, "", 20, "First rule.\n[...]", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			for path, content := range tt.files {
+				testutil.WriteFile(t, repo, path, content)
+			}
+			cfg := testConfig()
+			cfg.GuidelinesFile = tt.file
+			cfg.GuidelinesChars = tt.chars
+			got, err := loadGuidelines(cfg, repo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadGuidelines error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("loadGuidelines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareIncludesGuidelines(t *testing.T) {
+	guidelines := "Reference the ticket in every subject, e.g. \"fix: handle nil (PROJ-12)\"."
+	repo := testutil.NewRepo(t, map[string]string{"CONTRIBUTING.md": "# Contributing\n\n" + guidelines + "\n"})
+	testutil.WriteFile(t, repo, "main.go", "package main\n")
+	testutil.RunGit(t, repo, "add", "main.go")
+
+	cfg := testConfig()
+	cfg.GuidelinesChars = 1000
+	prepared, err := NewGenerator(cfg).Prepare(repo)
+	if err != nil {
+		t.Fatalf("Prepare error = %v", err)
+	}
+	if !strings.Contains(prepared.Prompt, "guidelines win:") || !strings.Contains(prepared.Prompt, guidelines) {
+		t.Errorf("prompt does not include the guidelines:\n%s", prepared.Prompt)
+	}
+}
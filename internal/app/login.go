@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/credentials"
+	"github.com/cstobie/ai-commit/internal/ui"
+	"golang.org/x/term"
+)
+
+// RunLogin stores the API key for the configured provider in the OS
+// keychain. The key is read from the terminal without echo, or as the first
+// line of stdin when it is piped, so it never appears in shell history.
+func RunLogin(ctx context.Context, cfg config.Config) error {
+	name := config.APIKeyName(cfg.Provider)
+	key, err := readAPIKey(ctx, name)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("no API key given")
+	}
+
+	if err := (credentials.Keychain{}).Store(name, key); err != nil {
+		return err
+	}
+	fmt.Printf("Stored %s in the keychain.\n", name)
+	if cfg.SecretSource != credentials.SourceKeychain {
+		fmt.Println("Set AICOMMIT_SECRET_SOURCE=keychain to use it.")
+	}
+	return nil
+}
+
+// readAPIKey prompts for the key on a terminal, or reads a line of stdin
+func readAPIKey(ctx context.Context, name string) (string, error) {
+	if !ui.IsTerminal(os.Stdin) {
+		key, err := readResponse(ctx)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("failed to read API key: %w", err)
+		}
+		return key, nil
+	}
+
+	fmt.Printf("%s: ", name)
+	key, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read API key: %w", err)
+	}
+	return strings.TrimSpace(string(key)), nil
+}
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/llm"
+)
+
+// RunModels prints the ids of the models available from the API, limited to
+// those containing filter, case-insensitively
+func RunModels(ctx context.Context, cfg config.Config, filter string) error {
+	ctx, cancel := withRequestTimeout(ctx, cfg)
+	defer cancel()
+	ids, err := llm.ListModels(ctx, llmOptions(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to list models: %w", err)
+	}
+
+	filter = strings.ToLower(filter)
+	for _, id := range ids {
+		if strings.Contains(strings.ToLower(id), filter) {
+			fmt.Println(id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/cstobie/ai-commit/internal/commit"
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/template"
+)
+
+// statsTemplate renders messages from the file list alone in no-LLM mode
+const statsTemplate = "stats"
+
+// offlineMessage renders a deterministic message from the changed files
+// without calling the API
+func offlineMessage(prepared *Prepared) (string, error) {
+	files, err := changedPaths(prepared)
+	if err != nil {
+		return "", err
+	}
+
+	data := withFiles(templateData(prepared.cfg, prepared.Diff), files)
+	data.FileCount = len(files)
+	data.Scope = commonDir(files)
+	data.SuggestedType = commit.SuggestType(files, prepared.cfg.ParsedTypeRules)
+	message, err := template.Execute(statsTemplate, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to render stats message: %w", err)
+	}
+	return strings.TrimSpace(message), nil
+}
+
+// changedPaths lists the staged files, or the files named in the diff when
+// it did not come from a repository
+func changedPaths(prepared *Prepared) ([]string, error) {
+	if prepared.RepoRoot == "" {
+		return git.DiffPaths(prepared.Diff), nil
+	}
+
+	fileChanges, err := git.GetStagedDiffFiles(prepared.RepoRoot, diffOptions(prepared.cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staged files: %w", err)
+	}
+	files := make([]string, 0, len(fileChanges))
+	for _, fc := range fileChanges {
+		files = append(files, fc.Path)
+	}
+	return files, nil
+}
+
+// commonDir returns the deepest directory containing all files, or an empty
+// string if they only share the repository root
+func commonDir(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	dir := path.Dir(files[0])
+	for _, file := range files[1:] {
+		for dir != "." && !strings.HasPrefix(file, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/commit"
+)
+
+func TestCommonDir(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"README.md"}, ""},
+		{[]string{"internal/app/app.go"}, "internal/app"},
+		{[]string{"internal/app/app.go", "internal/app/offline.go"}, "internal/app"},
+		{[]string{"internal/app/app.go", "internal/git/git.go"}, "internal"},
+		{[]string{"internal/app/app.go", "internalx/a.go"}, ""},
+		{[]string{"internal/app/app.go", "go.mod"}, ""},
+	}
+	for _, tt := range tests {
+		if got := commonDir(tt.files); got != tt.want {
+			t.Errorf("commonDir(%q) = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
+
+func TestOfflineMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want string
+	}{
+		{
+			name: "single file",
+			diff: "diff --git a/internal/app/app.go b/internal/app/app.go\n--- a/internal/app/app.go\n+++ b/internal/app/app.go\n@@ -1 +1 @@\n-a\n+b\n",
+			want: "chore: update 1 file in internal/app",
+		},
+		{
+			name: "docs only",
+			diff: "diff --git a/docs/a.md b/docs/a.md\n--- a/docs/a.md\n+++ b/docs/a.md\n@@ -1 +1 @@\n-a\n+b\n" +
+				"diff --git a/docs/b.md b/docs/b.md\n--- a/docs/b.md\n+++ b/docs/b.md\n@@ -1 +1 @@\n-a\n+b\n",
+			want: "docs: update 2 files in docs\n\n- docs/a.md\n- docs/b.md",
+		},
+		{
+			name: "rename and binary file",
+			diff: "diff --git a/web/old.png b/web/new.png\nsimilarity index 100%\nrename from web/old.png\nrename to web/new.png\n" +
+				"diff --git a/web/logo.png b/web/logo.png\nindex 1111111..2222222 100644\nBinary files a/web/logo.png and b/web/logo.png differ\n",
+			want: "chore: update 2 files in web\n\n- web/new.png\n- web/logo.png",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.NoLLM = true
+			cfg.ParsedTypeRules = commit.DefaultTypeRules
+			prepared, err := NewGenerator(cfg).PrepareDiff(tt.diff)
+			if err != nil {
+				t.Fatalf("PrepareDiff error = %v", err)
+			}
+			for range 2 {
+				got, err := offlineMessage(prepared)
+				if err != nil {
+					t.Fatalf("offlineMessage error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("offlineMessage() = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
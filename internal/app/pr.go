@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/template"
+	"github.com/cstobie/ai-commit/internal/ui"
+)
+
+// prTemplate is the template used for pull request descriptions
+const prTemplate = "pr"
+
+// defaultPRBases are tried in order when no base is given
+var defaultPRBases = []string{"origin/main", "origin/HEAD", "origin/master", "main", "master"}
+
+// RunPR prints a pull request title and description for the commits on HEAD
+// since it diverged from base, or from the first of defaultPRBases that
+// exists if base is empty. With an output file set, it is written there
+// instead.
+func RunPR(ctx context.Context, cfg config.Config, verbose bool, base string) error {
+	if err := git.EnsureGitAvailable(); err != nil {
+		return err
+	}
+	repoRoot, err := git.GetRepoRoot(".")
+	if err != nil {
+		return fmt.Errorf("This command must be run inside a git repository. %w", err)
+	}
+	if base == "" {
+		if base, err = defaultPRBase(repoRoot); err != nil {
+			return err
+		}
+	} else if _, err := git.ResolveCommit(repoRoot, base); err != nil {
+		return err
+	}
+	slog.Debug("Describing branch", "base", base)
+
+	if err := applyTemplateDefaults(&cfg, prTemplate); err != nil {
+		return err
+	}
+	// Descriptions are longer than commit messages
+	cfg.MaxOutputTokens = cfg.ExplainMaxOutputTokens
+	if err := clampInputTokens(&cfg); err != nil {
+		return err
+	}
+
+	diff, err := git.GetRangeDiff(repoRoot, base, diffOptions(cfg))
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(diff) == "" {
+		fmt.Fprintf(os.Stderr, "No changes on HEAD since %s.\n", base)
+		return nil
+	}
+	messages, err := git.GetRangeCommitMessages(repoRoot, base)
+	if err != nil {
+		return err
+	}
+	proceed, err := confirmSecrets(ctx, cfg, diff, ui.IsTerminal(os.Stdin))
+	if err != nil {
+		return err
+	}
+	if !proceed {
+		fmt.Fprintln(os.Stderr, "PR description aborted.")
+		return nil
+	}
+
+	data := templateData(cfg, diff)
+	data.SquashedCommits = messages
+	prompt, err := template.Execute(prTemplate, data)
+	if err != nil {
+		return fmt.Errorf("failed to prepare prompt: %w", err)
+	}
+	slog.Debug("Prepared PR prompt", "characters", len(prompt), "commits", len(messages))
+
+	spinner := ui.NewSpinner("Describing the branch...", cfg.OutputFile == "" && !verbose)
+	spinner.Start(ctx)
+	description, usage, err := generateMessage(ctx, cfg, prompt)
+	spinner.Stop()
+	if err != nil {
+		return fmt.Errorf("failed to generate PR description: %w", err)
+	}
+
+	if cfg.OutputFile != "" {
+		if err := writeOutputFile(cfg.OutputFile, description); err != nil {
+			return err
+		}
+	}
+	if cfg.OutputFile == "" || verbose {
+		fmt.Println(description)
+	}
+	printUsage(os.Stderr, cfg.ShowUsage, cfg.LLMModel, usage)
+	return nil
+}
+
+// defaultPRBase returns the first of defaultPRBases that names a commit
+func defaultPRBase(repoRoot string) (string, error) {
+	for _, base := range defaultPRBases {
+		if _, err := git.ResolveCommit(repoRoot, base); err == nil {
+			return base, nil
+		}
+	}
+	return "", fmt.Errorf("no base branch found (tried %s); pass one with --base", strings.Join(defaultPRBases, ", "))
+}
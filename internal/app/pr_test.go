@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestRunPR(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{"main.go": "package main\n"})
+	testutil.RunGit(t, repo, "checkout", "--quiet", "-b", "feature")
+	testutil.WriteFile(t, repo, "login.go", "package main\n\nfunc login() {}\n")
+	testutil.RunGit(t, repo, "add", "login.go")
+	testutil.RunGit(t, repo, "commit", "--quiet", "-m", "feat: add login")
+	testutil.WriteFile(t, repo, "logout.go", "package main\n\nfunc logout() {}\n")
+	testutil.RunGit(t, repo, "add", "logout.go")
+	testutil.RunGit(t, repo, "commit", "--quiet", "-m", "feat: add logout")
+	t.Chdir(repo)
+
+	const description = "Add login and logout\n\n## Summary\n- Add login\n- Add logout"
+	tests := []struct {
+		name       string
+		base       string
+		outputFile bool
+		wantErr    bool
+		wantOutput string
+	}{
+		{"default base", "", false, false, description + "\n"},
+		{"explicit base", "main", false, false, description + "\n"},
+		{"output file", "main", true, false, ""},
+		{"no changes", "HEAD", false, false, ""},
+		{"unknown base", "no-such-branch", false, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newChatServer(t, description)
+			cfg := serverConfig(server)
+			if tt.outputFile {
+				cfg.OutputFile = filepath.Join(t.TempDir(), "pr.md")
+			}
+			var err error
+			output := captureStdout(t, func() {
+				err = RunPR(context.Background(), cfg, false, tt.base)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunPR error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if output != tt.wantOutput {
+				t.Errorf("output = %q, want %q", output, tt.wantOutput)
+			}
+			if tt.outputFile {
+				written, err := os.ReadFile(cfg.OutputFile)
+				if err != nil {
+					t.Fatalf("reading output file: %v", err)
+				}
+				if strings.TrimSpace(string(written)) != description {
+					t.Errorf("output file = %q, want %q", written, description)
+				}
+			}
+			prompts := server.requests()
+			if tt.wantErr || tt.base == "HEAD" {
+				if len(prompts) != 0 {
+					t.Errorf("model was asked %d times, want none", len(prompts))
+				}
+				return
+			}
+			if len(prompts) != 1 {
+				t.Fatalf("model was asked %d times, want once", len(prompts))
+			}
+			for _, want := range []string{"## Summary", "+func login() {}", "+func logout() {}", "feat: add login", "feat: add logout"} {
+				if !strings.Contains(prompts[0], want) {
+					t.Errorf("prompt does not include %q:\n%s", want, prompts[0])
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultPRBase(t *testing.T) {
+	tests := []struct {
+		name    string
+		branch  string
+		want    string
+		wantErr bool
+	}{
+		{"main", "main", "main", false},
+		{"master", "master", "master", false},
+		{"neither", "trunk", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testutil.NewRepo(t, nil)
+			testutil.RunGit(t, repo, "branch", "--move", tt.branch)
+			got, err := defaultPRBase(repo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("defaultPRBase error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("defaultPRBase = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/cstobie/ai-commit/internal/ui"
+	"golang.org/x/term"
+)
+
+// stdinRequest asks the stdin reader for a line, or a single byte if key is set
+type stdinRequest struct {
+	key   bool
+	reply chan stdinResult
+}
+
+type stdinResult struct {
+	text string
+	err  error
+}
+
+var (
+	// stdinRequests is served by a single background reader, so buffered input
+	// is never dropped between prompts. It only reads on request, so nothing
+	// competes with an editor for the terminal between prompts.
+	stdinRequests     chan stdinRequest
+	stdinRequestsOnce sync.Once
+)
+
+// startStdinReader starts the background stdin reader on first use
+func startStdinReader() {
+	stdinRequests = make(chan stdinRequest)
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for req := range stdinRequests {
+			if req.key {
+				b, err := reader.ReadByte()
+				req.reply <- stdinResult{text: string(b), err: err}
+				continue
+			}
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				err = nil
+			}
+			req.reply <- stdinResult{text: line, err: err}
+		}
+	}()
+}
+
+// readStdin reads a line, or a single byte if key is set. It returns the
+// context error if ctx is cancelled first, e.g. by Ctrl-C, and io.EOF at end
+// of input.
+func readStdin(ctx context.Context, key bool) (string, error) {
+	stdinRequestsOnce.Do(startStdinReader)
+	reply := make(chan stdinResult, 1)
+	select {
+	case stdinRequests <- stdinRequest{key: key, reply: reply}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	select {
+	case result := <-reply:
+		return result.text, result.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// readResponse reads a line from stdin with surrounding whitespace removed
+func readResponse(ctx context.Context) (string, error) {
+	line, err := readStdin(ctx, false)
+	return strings.TrimSpace(line), err
+}
+
+// confirm asks question on out and reads a single y or n keystroke, like
+// commitMenu. Enter gives the default, shown in upper case in the hint; the
+// end of input counts as a refusal.
+func confirm(ctx context.Context, out io.Writer, question string, defaultYes bool) (bool, error) {
+	hint := "[y/N]"
+	if defaultYes {
+		hint = "[Y/n]"
+	}
+	for {
+		fmt.Fprintf(out, "%s %s: ", question, hint)
+		key, err := readKey(ctx, out)
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(out)
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		switch key {
+		case '\r', '\n':
+			return defaultYes, nil
+		case 'y', 'Y':
+			return true, nil
+		case 'n', 'N', 0x03, 0x04: // Ctrl-C and Ctrl-D arrive as bytes in raw mode
+			return false, nil
+		default:
+			fmt.Fprintf(out, "Unknown choice %q, answer y or n.\n", key)
+		}
+	}
+}
+
+// Choices offered by the commit menu
+const (
+	choiceCommit     = 'y'
+	choiceEdit       = 'e'
+	choiceRegenerate = 'r'
+	choiceAbort      = 'n'
+)
+
+// commitMenu asks what to do with a generated message and returns one of
+// the choice constants. Enter means commit, or abort with abortByDefault; the
+// default is shown in upper case. A single keystroke is read when the
+// terminal supports raw mode, a line otherwise. Without a terminal on stdin
+// nothing can be confirmed, so it aborts. The menu and notes go to out.
+func commitMenu(ctx context.Context, out io.Writer, abortByDefault bool) (rune, error) {
+	if !ui.IsTerminal(os.Stdin) {
+		fmt.Fprintln(out, "stdin is not a terminal, not committing. Use -n to only print the message.")
+		return choiceAbort, nil
+	}
+
+	menu, enter := commitMenuPrompt(abortByDefault)
+	for {
+		fmt.Fprint(out, menu)
+		key, err := readKey(ctx, out)
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(out)
+			return choiceAbort, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if choice, ok := menuChoice(key, enter); ok {
+			return choice, nil
+		}
+		fmt.Fprintf(out, "Unknown choice %q.\n", key)
+	}
+}
+
+// commitMenuPrompt returns the commit menu, with the default in upper case,
+// and the choice made by Enter
+func commitMenuPrompt(abortByDefault bool) (string, rune) {
+	if abortByDefault {
+		return "[y] commit  [e] edit  [r] regenerate  [N] abort (Enter = n): ", choiceAbort
+	}
+	return "[Y] commit  [e] edit  [r] regenerate  [n] abort (Enter = y): ", choiceCommit
+}
+
+// menuChoice returns the choice for a key pressed at the commit menu, with
+// Enter making the enter choice, and false for an unknown key
+func menuChoice(key, enter rune) (rune, bool) {
+	switch key {
+	case '\r', '\n':
+		return enter, true
+	case 'y', 'Y':
+		return choiceCommit, true
+	case 'e', 'E':
+		return choiceEdit, true
+	case 'r', 'R':
+		return choiceRegenerate, true
+	case 'n', 'N', 0x03, 0x04: // Ctrl-C and Ctrl-D arrive as bytes in raw mode
+		return choiceAbort, true
+	}
+	return 0, false
+}
+
+// readKey reads a single keystroke from the terminal on stdin and echoes it
+// to out, falling back to the first character of a line if raw mode is
+// unavailable
+func readKey(ctx context.Context, out io.Writer) (rune, error) {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		line, err := readResponse(ctx)
+		if err != nil || line == "" {
+			return '\n', err
+		}
+		return rune(line[0]), nil
+	}
+
+	key, err := readStdin(ctx, true)
+	term.Restore(fd, state)
+	if err != nil {
+		return 0, err
+	}
+	if key == "\r" || key == "\n" {
+		fmt.Fprintln(out)
+	} else {
+		fmt.Fprintln(out, strings.TrimSpace(key))
+	}
+	return rune(key[0]), nil
+}
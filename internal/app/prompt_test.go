@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestConfirm feeds answers through a pipe, so stdin is not a terminal and
+// confirm falls back to reading lines. The stdin reader starts on first use
+// and keeps the pipe, so all cases share it.
+func TestConfirm(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = stdin })
+
+	tests := []struct {
+		name       string
+		input      string
+		defaultYes bool
+		want       bool
+		wantOut    string
+	}{
+		{name: "enter takes default yes", input: "\n", defaultYes: true, want: true, wantOut: "Go? [Y/n]: "},
+		{name: "enter takes default no", input: "\n", defaultYes: false, want: false, wantOut: "Go? [y/N]: "},
+		{name: "y", input: "y\n", want: true},
+		{name: "upper case N", input: "N\n", defaultYes: true, want: false},
+		{name: "stray key asks again", input: "x\ny\n", want: true, wantOut: "Unknown choice 'x'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := io.WriteString(w, tt.input); err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			got, err := confirm(context.Background(), &out, "Go?", tt.defaultYes)
+			if err != nil {
+				t.Fatalf("confirm error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("confirm after %q = %v, want %v", tt.input, got, tt.want)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("confirm printed %q, want it to contain %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+
+	// The end of input refuses, even with a default of yes
+	w.Close()
+	var out strings.Builder
+	if got, err := confirm(context.Background(), &out, "Go?", true); err != nil || got {
+		t.Errorf("confirm at end of input = %v, %v; want false, nil", got, err)
+	}
+}
+
+func TestCommitMenuDefault(t *testing.T) {
+	tests := []struct {
+		name           string
+		abortByDefault bool
+		key            rune
+		want           rune
+		wantMenu       string
+	}{
+		{"enter commits by default", false, '\n', choiceCommit, "[Y] commit"},
+		{"enter aborts with abort default", true, '\n', choiceAbort, "[N] abort (Enter = n)"},
+		{"carriage return aborts with abort default", true, '\r', choiceAbort, "[N] abort"},
+		{"y commits with abort default", true, 'y', choiceCommit, "[y] commit"},
+		{"n aborts with commit default", false, 'N', choiceAbort, "[n] abort (Enter = y)"},
+		{"ctrl-c aborts", false, 0x03, choiceAbort, ""},
+		{"edit", false, 'e', choiceEdit, ""},
+		{"regenerate", true, 'R', choiceRegenerate, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			menu, enter := commitMenuPrompt(tt.abortByDefault)
+			if !strings.Contains(menu, tt.wantMenu) {
+				t.Errorf("menu = %q, want it to contain %q", menu, tt.wantMenu)
+			}
+			got, ok := menuChoice(tt.key, enter)
+			if !ok || got != tt.want {
+				t.Errorf("menuChoice(%q) = %q, %v, want %q", tt.key, got, ok, tt.want)
+			}
+		})
+	}
+	if _, ok := menuChoice('x', choiceCommit); ok {
+		t.Error("menuChoice accepted an unknown key")
+	}
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/git"
+)
+
+// commitAndPush commits message and, with --push, pushes the new commit
+func commitAndPush(ctx context.Context, cfg config.Config, repoRoot, message string, verbose, ask bool) error {
+	if err := performCommit(repoRoot, message, commitOptionsFor(cfg), verbose); err != nil {
+		return err
+	}
+	if !cfg.Push {
+		return nil
+	}
+	return pushCommit(ctx, cfg, repoRoot, ask)
+}
+
+// pushCommit pushes HEAD to the --push-to target or the upstream of the
+// current branch, asking first if ask is set. A failed push leaves the
+// commit in place.
+func pushCommit(ctx context.Context, cfg config.Config, repoRoot string, ask bool) error {
+	target := cfg.PushRemote
+	if cfg.PushBranch != "" {
+		target += "/" + cfg.PushBranch
+	}
+	if target == "" {
+		upstream, err := git.GetUpstream(repoRoot)
+		if err != nil {
+			return err
+		}
+		if upstream == "" {
+			return fmt.Errorf("committed, but not pushed: the current branch has no upstream; " +
+				"set one with 'git push -u' or use --push-to <remote>[:<branch>]")
+		}
+		target = upstream
+	}
+
+	out := messageOutput(cfg)
+	if ask {
+		ok, err := confirm(ctx, out, fmt.Sprintf("Push to %s?", target), true)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(out, "Not pushed.")
+			return nil
+		}
+	}
+	if err := git.Push(repoRoot, cfg.PushRemote, cfg.PushBranch); err != nil {
+		return fmt.Errorf("committed, but failed to push to %s: %w", target, err)
+	}
+	fmt.Fprintf(out, "Pushed to %s.\n", target)
+	return nil
+}
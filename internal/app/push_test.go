@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestCommitAndPush(t *testing.T) {
+	tests := []struct {
+		name       string
+		push       bool
+		upstream   bool
+		wantPushed bool
+		wantErr    string
+	}{
+		{name: "push to upstream", push: true, upstream: true, wantPushed: true},
+		{name: "push disabled", push: false, upstream: true},
+		{name: "no upstream keeps the commit", push: true, upstream: false, wantErr: "no upstream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testutil.NewRepo(t, nil)
+			remote := t.TempDir()
+			testutil.RunGit(t, remote, "init", "--quiet", "--bare")
+			testutil.RunGit(t, repo, "remote", "add", "origin", remote)
+			if tt.upstream {
+				testutil.RunGit(t, repo, "push", "--quiet", "-u", "origin", "main")
+			}
+			testutil.WriteFile(t, repo, "a.txt", "a\n")
+			testutil.RunGit(t, repo, "add", "a.txt")
+
+			cfg := testConfig()
+			cfg.Push = tt.push
+			var err error
+			captureStdout(t, func() {
+				err = commitAndPush(context.Background(), cfg, repo, "feat: add a", false, false)
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("commitAndPush error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("commitAndPush error = %v", err)
+			}
+			if got := testutil.RunGit(t, repo, "log", "-1", "--format=%s"); got != "feat: add a" {
+				t.Errorf("local HEAD = %q, want the new commit", got)
+			}
+			pushed := strings.Contains(testutil.RunGit(t, remote, "log", "--all", "--format=%s"), "feat: add a")
+			if pushed != tt.wantPushed {
+				t.Errorf("commit pushed = %v, want %v", pushed, tt.wantPushed)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/git"
+)
+
+// RunReword generates a new message for an existing commit from its diff and,
+// once confirmed, rewrites the commit and the commits after it
+func RunReword(ctx context.Context, cfg config.Config, verbose bool, interactive bool, rev string) error {
+	if err := git.EnsureGitAvailable(); err != nil {
+		return err
+	}
+	repoRoot, err := git.GetRepoRoot(".")
+	if err != nil {
+		return fmt.Errorf("This command must be run inside a git repository. %w", err)
+	}
+
+	// Rewriting history in the middle of another operation would tangle the
+	// two, as committing would for generate
+	state, err := git.GetRepoState(repoRoot)
+	if err != nil {
+		return err
+	}
+	if state.Operation != "" {
+		return fmt.Errorf("a %s is in progress; finish it with 'git %s --continue' or '--abort' before rewording", state.Operation, state.Operation)
+	}
+
+	// Rewriting history under uncommitted changes is asking for trouble
+	clean, err := git.IsWorkingTreeClean(repoRoot)
+	if err != nil {
+		return err
+	}
+	if !clean {
+		return git.ErrDirtyWorkingTree
+	}
+
+	commit, err := git.ResolveCommit(repoRoot, rev)
+	if err != nil {
+		return err
+	}
+	oldMessage, err := git.GetCommitMessage(repoRoot, commit)
+	if err != nil {
+		return err
+	}
+	diff, err := git.GetCommitDiff(repoRoot, commit, diffOptions(cfg))
+	if err != nil {
+		return err
+	}
+
+	generator := NewGenerator(cfg)
+	prepared, err := generator.PrepareDiff(diff)
+	if err != nil {
+		return fmt.Errorf("commit %.7s: %w", commit, err)
+	}
+	proceed, err := confirmSecrets(ctx, prepared.cfg, prepared.Diff, interactive)
+	if err != nil {
+		return err
+	}
+	if !proceed {
+		fmt.Println("Reword aborted.")
+		return nil
+	}
+
+	result, usage, ok, err := generateCheckedMessage(ctx, generator, GenerateOptions{Prepared: prepared}, verbose, interactive)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Reword aborted.")
+		return nil
+	}
+	message, err := decorateMessage(prepared.cfg, repoRoot, result.Message)
+	if err != nil {
+		return err
+	}
+
+	printMessage(cfg, fmt.Sprintf("Current message of %.7s", commit), oldMessage)
+	printMessage(cfg, "Generated commit message", message)
+	printUsage(os.Stdout, cfg.ShowUsage, result.Model, usage)
+
+	if !interactive {
+		return nil
+	}
+	ok, err = confirm(ctx, os.Stdout, fmt.Sprintf("Reword %.7s with this message?", commit), true)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Reword aborted.")
+		return nil
+	}
+
+	newHead, err := git.RewordCommit(repoRoot, commit, normalizeMessage(message))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Commit reworded; HEAD is now %.7s.\n", newHead)
+	return nil
+}
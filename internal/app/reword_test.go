@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestRunRewordRefusesDuringOperation(t *testing.T) {
+	tests := []struct {
+		marker string
+		want   string
+	}{
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+		{"rebase-merge", "rebase"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			repo := testutil.NewRepo(t, map[string]string{"a.txt": "a\n"})
+			head := testutil.RunGit(t, repo, "rev-parse", "HEAD")
+			marker := filepath.Join(repo, ".git", tt.marker)
+			if err := os.WriteFile(marker, []byte(head+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Chdir(repo)
+
+			err := RunReword(context.Background(), testConfig(), false, false, "HEAD")
+			if err == nil || !strings.Contains(err.Error(), "a "+tt.want+" is in progress") {
+				t.Errorf("RunReword() error = %v, want a %s in progress", err, tt.want)
+			}
+			if got := testutil.RunGit(t, repo, "rev-parse", "HEAD"); got != head {
+				t.Errorf("HEAD moved from %s to %s", head, got)
+			}
+		})
+	}
+}
+
+func TestRewordNormalizesMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{"CRLF", "feat: add a\r\n\r\nFirst line.\r\nSecond line.\r\n"},
+		{"CR", "feat: add a\r\rFirst line.\rSecond line.\r"},
+		{"no blank line", "feat: add a\nFirst line.\nSecond line.  \n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testutil.NewRepo(t, map[string]string{"a.txt": "a\n"})
+			head := testutil.RunGit(t, repo, "rev-parse", "HEAD")
+			if _, err := git.RewordCommit(repo, head, normalizeMessage(tt.message)); err != nil {
+				t.Fatalf("RewordCommit error = %v", err)
+			}
+			raw := testutil.RunGit(t, repo, "cat-file", "-p", "HEAD")
+			if _, message, _ := strings.Cut(raw, "\n\n"); message != "feat: add a\n\nFirst line.\nSecond line." {
+				t.Errorf("commit message = %q, want LF line endings and a blank line after the subject", message)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cstobie/ai-commit/internal/commit"
+)
+
+// headerRegex splits a "type(scope)!: subject" header into its parts
+var headerRegex = regexp.MustCompile(`^([A-Za-z]+)(\([^()]+\))?(!)?: (\S.*)$`)
+
+// commitSpec is the header format a template asks for. The dialects differ
+// only in their header rules:
+//
+//   - Conventional Commits allows any type in any case, an optional scope
+//     and a "!" marking breaking changes, and puts no limit on the header
+//     length.
+//   - Angular allows build, ci, docs, feat, fix, perf, refactor and test, no
+//     "!", and headers up to 100 characters. The subject starts lowercase and
+//     does not end with a period.
+//   - Karma allows feat, fix, docs, style, refactor, perf, test and chore, no
+//     "!", and headers up to 70 characters, with the same subject rules.
+type commitSpec struct {
+	name         string
+	types        []string // Allowed types; any word if empty
+	breakingMark bool     // Whether "type!:" is allowed
+	maxHeader    int      // Maximum header length in characters; 0 for no limit
+	strictText   bool     // Subject must start lowercase and not end with a period
+
+	// Further limits from the repository's commitlint config
+	scopes      []string // Allowed scopes; any if empty
+	maxSubject  int      // Maximum subject length in characters; 0 for no limit
+	noPeriod    bool     // Subject must not end with a period
+	maxBodyLine int      // Maximum body line length in characters; 0 for no limit
+}
+
+var (
+	conventionalSpec = commitSpec{name: "Conventional Commits", breakingMark: true}
+	angularSpec      = commitSpec{
+		name:       "Angular",
+		types:      []string{"build", "ci", "docs", "feat", "fix", "perf", "refactor", "test"},
+		maxHeader:  100,
+		strictText: true,
+	}
+	karmaSpec = commitSpec{
+		name:       "Karma",
+		types:      []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "chore"},
+		maxHeader:  70,
+		strictText: true,
+	}
+)
+
+// commitSpecs maps template names to the format their messages must follow.
+// Templates without an entry, like simple, are not checked.
+var commitSpecs = map[string]commitSpec{
+	"conventional":      conventionalSpec,
+	subjectOnlyTemplate: conventionalSpec,
+	"angular":           angularSpec,
+	"karma":             karmaSpec,
+}
+
+// withLintRules returns the spec tightened by commitlint rules, which replace
+// the spec's types and header limit where they are set
+func (s commitSpec) withLintRules(rules commit.LintRules) commitSpec {
+	s.name += " and commitlint"
+	if len(rules.Types) > 0 {
+		s.types = rules.Types
+	}
+	if rules.HeaderMaxLength > 0 {
+		s.maxHeader = rules.HeaderMaxLength
+	}
+	s.scopes = rules.Scopes
+	s.maxSubject = rules.SubjectMaxLength
+	s.noPeriod = rules.SubjectNoPeriod
+	s.maxBodyLine = rules.BodyMaxLineLength
+	return s
+}
+
+// check reports how message breaks the spec, or nil
+func (s commitSpec) check(message string) error {
+	header, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	m := headerRegex.FindStringSubmatch(header)
+	if m == nil {
+		return fmt.Errorf("header %q is not in %s format \"type(scope): subject\"", header, s.name)
+	}
+	commitType, scope, breaking, subject := m[1], strings.Trim(m[2], "()"), m[3], m[4]
+
+	if len(s.types) > 0 && !slices.Contains(s.types, commitType) {
+		return fmt.Errorf("type %q is not one of the %s types: %s", commitType, s.name, strings.Join(s.types, ", "))
+	}
+	if len(s.scopes) > 0 && scope != "" && !slices.Contains(s.scopes, scope) {
+		return fmt.Errorf("scope %q is not one of the allowed scopes: %s", scope, strings.Join(s.scopes, ", "))
+	}
+	if breaking != "" && !s.breakingMark {
+		return fmt.Errorf("%s headers do not use \"!\" for breaking changes", s.name)
+	}
+	if length := utf8.RuneCountInString(header); s.maxHeader > 0 && length > s.maxHeader {
+		return fmt.Errorf("header is %d characters, more than the %d allowed by %s", length, s.maxHeader, s.name)
+	}
+	if s.strictText {
+		if first, _ := utf8.DecodeRuneInString(subject); unicode.IsUpper(first) {
+			return fmt.Errorf("%s subjects start with a lowercase letter", s.name)
+		}
+		if strings.HasSuffix(subject, ".") {
+			return fmt.Errorf("%s subjects do not end with a period", s.name)
+		}
+	}
+	if length := utf8.RuneCountInString(subject); s.maxSubject > 0 && length > s.maxSubject {
+		return fmt.Errorf("subject is %d characters, more than the %d allowed by %s", length, s.maxSubject, s.name)
+	}
+	if s.noPeriod && strings.HasSuffix(subject, ".") {
+		return fmt.Errorf("%s subjects do not end with a period", s.name)
+	}
+	if s.maxBodyLine > 0 {
+		_, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+		for _, line := range strings.Split(body, "\n") {
+			if length := utf8.RuneCountInString(line); length > s.maxBodyLine {
+				return fmt.Errorf("a body line is %d characters, more than the %d allowed by %s", length, s.maxBodyLine, s.name)
+			}
+		}
+	}
+	return nil
+}
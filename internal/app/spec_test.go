@@ -0,0 +1,53 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/commit"
+)
+
+func TestCommitSpecCheck(t *testing.T) {
+	strictLint := angularSpec.withLintRules(commit.LintRules{Types: []string{"feat", "fix"}, HeaderMaxLength: 40})
+
+	tests := []struct {
+		name    string
+		spec    commitSpec
+		message string
+		wantErr bool
+	}{
+		{"angular message", angularSpec, "build(deps): bump the test runner\n\nKeeps CI fast.", false},
+		{"angular message under stricter rules", strictLint, "build(deps): bump the test runner", true},
+		{"angular message under karma", karmaSpec, "build(deps): bump the test runner", true},
+		{"conventional any type", conventionalSpec, "wip: Try Something.", false},
+		{"conventional breaking mark", conventionalSpec, "feat(api)!: drop v1", false},
+		{"angular breaking mark", angularSpec, "feat(api)!: drop v1", true},
+		{"angular uppercase subject", angularSpec, "fix: Handle empty input", true},
+		{"angular trailing period", angularSpec, "fix: handle empty input.", true},
+		{"angular long header", angularSpec, "fix: " + strings.Repeat("a", 96), true},
+		{"karma long header", karmaSpec, "fix: " + strings.Repeat("a", 66), true},
+		{"karma chore", karmaSpec, "chore: tidy up", false},
+		{"not a header", angularSpec, "Update files", true},
+		{"stricter header length", strictLint, "fix: " + strings.Repeat("a", 36), true},
+		{"stricter rules pass", strictLint, "fix: handle empty input", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.check(tt.message)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s check(%q) error = %v, wantErr %v", tt.spec.name, tt.message, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommitSpecsByTemplate(t *testing.T) {
+	for name, want := range map[string]string{"conventional": "Conventional Commits", "angular": "Angular", "karma": "Karma"} {
+		if spec, ok := commitSpecs[name]; !ok || spec.name != want {
+			t.Errorf("commitSpecs[%q] = %q, want %q", name, spec.name, want)
+		}
+	}
+	if _, ok := commitSpecs["simple"]; ok {
+		t.Error("the simple template has a commit spec")
+	}
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/llm"
+	"github.com/cstobie/ai-commit/internal/ui"
+)
+
+// splitCommit is one proposed commit in a split plan
+type splitCommit struct {
+	group   git.FileGroup
+	message string
+}
+
+// RunSuggestSplits groups the staged changes into clusters and proposes a
+// commit message for each. With apply, the index is reset and each cluster
+// is staged and committed in turn.
+func RunSuggestSplits(ctx context.Context, cfg config.Config, verbose bool, apply bool) error {
+	generator := NewGenerator(cfg)
+	cfg = generator.modeConfig()
+	repoRoot, err := findRepo(&cfg, ".")
+	if err != nil {
+		return err
+	}
+	if err := applyTemplateDefaults(&cfg, cfg.TemplateName); err != nil {
+		return err
+	}
+	_, _, err = stagedDiff(&cfg, repoRoot)
+	if errors.Is(err, ErrNoStagedChanges) {
+		fmt.Println("No staged changes found. Stage changes first with 'git add'.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fileChanges, err := git.GetStagedDiffFiles(repoRoot, diffOptions(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to get staged files: %w", err)
+	}
+	groups := git.GroupFileChanges(fileChanges)
+	slog.Debug("Grouped staged changes", "files", len(fileChanges), "groups", len(groups))
+
+	var allDiffs strings.Builder
+	for _, group := range groups {
+		allDiffs.WriteString(groupDiff(group))
+	}
+	proceed, err := confirmSecrets(ctx, cfg, allDiffs.String(), ui.IsTerminal(os.Stdin))
+	if err != nil {
+		return err
+	}
+	if !proceed {
+		fmt.Fprintln(messageOutput(cfg), "Suggest splits aborted.")
+		return nil
+	}
+
+	spinner := ui.NewSpinner("Generating commit messages...", !verbose)
+	spinner.Start(ctx)
+	plan, usage, err := planSplits(ctx, generator, cfg, repoRoot, groups, verbose)
+	spinner.Stop()
+	if err != nil {
+		return err
+	}
+
+	colors := ui.NewColors(os.Stdout, cfg.NoColor)
+	for i, commit := range plan {
+		fmt.Printf("Commit %d: %s (%s, %d files)\n", i+1, commit.group.Dir, commit.group.ChangeType, len(commit.group.Files))
+		for _, fc := range commit.group.Files {
+			fmt.Printf("  %s\n", colors.Path(fc.Path))
+		}
+		fmt.Println(colors.Fence())
+		fmt.Println(colors.Message(commit.message))
+		fmt.Println(colors.Fence())
+	}
+	printUsage(os.Stdout, cfg.ShowUsage, cfg.LLMModel, usage)
+
+	if !apply {
+		return nil
+	}
+	return applySplits(repoRoot, plan, verbose)
+}
+
+// planSplits generates a commit message for each group from its own diff.
+// Each prompt is prepared and each message checked and decorated as by
+// generate, with the diff stat and suggestions limited to the group's files.
+func planSplits(ctx context.Context, generator *Generator, cfg config.Config, repoRoot string,
+	groups []git.FileGroup, verbose bool) ([]splitCommit, *llm.Usage, error) {
+	var usage *llm.Usage
+	plan := make([]splitCommit, 0, len(groups))
+	for _, group := range groups {
+		groupCfg := cfg
+		groupCfg.Pathspecs = literalPathspecs(group.Paths())
+		prepared, err := preparePrompt(groupCfg, repoRoot, groupDiff(group), nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		result, messageUsage, _, err := generateCheckedMessage(ctx, generator, GenerateOptions{Prepared: prepared}, verbose, false)
+		usage = llm.AddUsage(usage, messageUsage)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate commit message for %s (%s): %w", group.Dir, group.ChangeType, err)
+		}
+		message, err := decorateMessage(cfg, repoRoot, result.Message)
+		if err != nil {
+			return nil, nil, err
+		}
+		plan = append(plan, splitCommit{group: group, message: message})
+	}
+	return plan, usage, nil
+}
+
+// literalPathspecs turns paths into pathspecs that match only those paths,
+// even if they contain glob characters
+func literalPathspecs(paths []string) []string {
+	specs := make([]string, len(paths))
+	for i, path := range paths {
+		specs[i] = ":(literal)" + path
+	}
+	return specs
+}
+
+// groupDiff joins the diffs of the files in a group, describing binary files
+// and files without textual changes by name
+func groupDiff(group git.FileGroup) string {
+	var sb strings.Builder
+	for _, fc := range group.Files {
+		if fc.IsBinary || fc.Diff == "" {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", fc.ChangeType, fc.Path))
+			continue
+		}
+		sb.WriteString(fc.Diff)
+		if !strings.HasSuffix(fc.Diff, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// applySplits unstages everything, then stages and commits each group in
+// turn. Files are committed as they were staged, so changes left unstaged,
+// e.g. with git add -p, stay unstaged. If a commit fails, the groups not yet
+// committed are staged again.
+func applySplits(repoRoot string, plan []splitCommit, verbose bool) error {
+	staged, err := git.WriteIndexTree(repoRoot)
+	if err != nil {
+		return err
+	}
+	slog.Debug("Saved the staged changes", "tree", staged)
+	if err := git.ResetIndex(repoRoot); err != nil {
+		return err
+	}
+	for i, commit := range plan {
+		if err := git.StageFromTree(repoRoot, staged, commit.group.Paths()); err != nil {
+			return restageSplits(repoRoot, staged, plan[i:], fmt.Errorf("commit %d: %w", i+1, err))
+		}
+		if err := performCommit(repoRoot, commit.message, commitOptions{}, verbose); err != nil {
+			return restageSplits(repoRoot, staged, plan[i:], fmt.Errorf("commit %d: %w", i+1, err))
+		}
+	}
+	return nil
+}
+
+// restageSplits stages the files of the remaining commits from the saved
+// tree after err stopped applySplits. If that fails too, the error tells the
+// user how to restore them.
+func restageSplits(repoRoot, tree string, remaining []splitCommit, err error) error {
+	var paths []string
+	for _, commit := range remaining {
+		paths = append(paths, commit.group.Paths()...)
+	}
+	if restageErr := git.StageFromTree(repoRoot, tree, paths); restageErr != nil {
+		return fmt.Errorf("%w\nthe remaining changes could not be staged again (%v); restore them with: git read-tree %s", err, restageErr, tree)
+	}
+	return fmt.Errorf("%w\nthe changes of the remaining %d commit(s) are staged again", err, len(remaining))
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestApplySplitsKeepsPartialStaging(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{
+		"api/handler.go": "package api\n\nfunc A() {}\n",
+		"docs/guide.md":  "# Guide\n",
+		"old.txt":        "remove me\n",
+	})
+
+	// Stage one change to each file, then make more changes left unstaged
+	testutil.WriteFile(t, repo, "api/handler.go", "package api\n\nfunc A() {}\n\nfunc B() {}\n")
+	testutil.WriteFile(t, repo, "docs/guide.md", "# Guide\n\nStaged intro.\n")
+	testutil.RunGit(t, repo, "add", "api/handler.go", "docs/guide.md")
+	testutil.RunGit(t, repo, "rm", "--quiet", "old.txt")
+	testutil.WriteFile(t, repo, "api/handler.go", "package api\n\nfunc A() {}\n\nfunc B() {}\n\nfunc Unstaged() {}\n")
+	testutil.WriteFile(t, repo, "docs/guide.md", "# Guide\n\nStaged intro.\n\nUnstaged section.\n")
+
+	fileChanges, err := git.GetStagedDiffFiles(repo, git.DiffOptions{ContextLines: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plan []splitCommit
+	for _, group := range git.GroupFileChanges(fileChanges) {
+		plan = append(plan, splitCommit{group: group, message: "chore: update " + group.Dir})
+	}
+	if len(plan) < 2 {
+		t.Fatalf("want several groups, got %d", len(plan))
+	}
+
+	if err := applySplits(repo, plan, true); err != nil {
+		t.Fatalf("applySplits error = %v", err)
+	}
+
+	if got := testutil.RunGit(t, repo, "rev-list", "--count", "HEAD"); got != "4" {
+		t.Errorf("repository has %s commits, want the initial one and 3 splits", got)
+	}
+	if got := testutil.RunGit(t, repo, "show", "HEAD:api/handler.go"); got != "package api\n\nfunc A() {}\n\nfunc B() {}" {
+		t.Errorf("committed api/handler.go = %q, want the staged version", got)
+	}
+	if got := testutil.RunGit(t, repo, "show", "HEAD:docs/guide.md"); got != "# Guide\n\nStaged intro." {
+		t.Errorf("committed docs/guide.md = %q, want the staged version", got)
+	}
+	if got := testutil.RunGit(t, repo, "ls-tree", "--name-only", "HEAD", "old.txt"); got != "" {
+		t.Errorf("old.txt is still in HEAD")
+	}
+	if got := testutil.RunGit(t, repo, "status", "--porcelain"); got != "M api/handler.go\n M docs/guide.md" {
+		t.Errorf("status = %q, want only the unstaged changes left", got)
+	}
+}
+
+func TestApplySplitsRestagesAfterFailedCommit(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{
+		"api/handler.go": "package api\n",
+		"docs/guide.md":  "# Guide\n",
+		"web/app.js":     "app()\n",
+	})
+	testutil.WriteFile(t, repo, "api/handler.go", "package api\n\nfunc A() {}\n")
+	testutil.WriteFile(t, repo, "docs/guide.md", "# Guide\n\nIntro.\n")
+	testutil.WriteFile(t, repo, "web/app.js", "app()\nrun()\n")
+	testutil.RunGit(t, repo, "add", "--all")
+	testutil.WriteFile(t, repo, ".git/hooks/commit-msg", "#!/bin/sh\n! grep -q reject \"$1\"\n")
+	if err := os.Chmod(filepath.Join(repo, ".git/hooks/commit-msg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	fileChanges, err := git.GetStagedDiffFiles(repo, git.DiffOptions{ContextLines: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plan []splitCommit
+	for _, group := range git.GroupFileChanges(fileChanges) {
+		plan = append(plan, splitCommit{group: group, message: "chore: update " + group.Dir})
+	}
+	if len(plan) != 3 {
+		t.Fatalf("want 3 groups, got %d", len(plan))
+	}
+	plan[1].message = "chore: reject this commit"
+
+	err = applySplits(repo, plan, true)
+	if err == nil || !strings.Contains(err.Error(), "commit 2") {
+		t.Fatalf("applySplits error = %v, want commit 2 to fail", err)
+	}
+	if got := testutil.RunGit(t, repo, "rev-list", "--count", "HEAD"); got != "2" {
+		t.Errorf("repository has %s commits, want the initial one and the first split", got)
+	}
+	var want []string
+	for _, commit := range plan[1:] {
+		want = append(want, commit.group.Paths()...)
+	}
+	slices.Sort(want)
+	if got := testutil.RunGit(t, repo, "diff", "--cached", "--name-only"); got != strings.Join(want, "\n") {
+		t.Errorf("staged files = %q, want the remaining %q", got, want)
+	}
+}
+
+func TestPlanSplitsChecksAndDecoratesMessages(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{"api/handler.go": "package api\n", "docs/guide.md": "# Guide\n"})
+	testutil.WriteFile(t, repo, "api/handler.go", "package api\n\nfunc A() {}\n")
+	testutil.WriteFile(t, repo, "docs/guide.md", "# Guide\n\nIntro.\n")
+	testutil.RunGit(t, repo, "add", "--all")
+	fileChanges, err := git.GetStagedDiffFiles(repo, git.DiffOptions{ContextLines: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := git.GroupFileChanges(fileChanges)
+
+	t.Run("decorated", func(t *testing.T) {
+		server := newChatServer(t, "feat: update the files")
+		cfg := serverConfig(server)
+		cfg.MessageFooter = "Refs: #12"
+		plan, _, err := planSplits(context.Background(), NewGenerator(cfg), cfg, repo, groups, true)
+		if err != nil {
+			t.Fatalf("planSplits error = %v", err)
+		}
+		for _, commit := range plan {
+			if commit.message != "feat: update the files\n\nRefs: #12" {
+				t.Errorf("%s message = %q, want the footer added", commit.group.Dir, commit.message)
+			}
+		}
+		for i, prompt := range server.requests() {
+			other := groups[1-i].Files[0].Path
+			if strings.Contains(prompt, other) {
+				t.Errorf("prompt for %s mentions %s:\n%s", groups[i].Dir, other, prompt)
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		server := newChatServer(t, "ok")
+		cfg := serverConfig(server)
+		cfg.MinMessageLength = 10
+		if _, _, err := planSplits(context.Background(), NewGenerator(cfg), cfg, repo, groups, true); err == nil {
+			t.Error("planSplits accepted a message that fails the checks")
+		}
+	})
+}
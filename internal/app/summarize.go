@@ -0,0 +1,25 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cstobie/ai-commit/internal/config"
+)
+
+// RunSummarize prints one message describing all commits on HEAD since it
+// diverged from base, e.g. for a squash merge. Nothing is committed.
+func RunSummarize(ctx context.Context, cfg config.Config, verbose bool, base string) error {
+	generator := NewGenerator(cfg)
+	prepared, err := generator.PrepareRange(".", base)
+	if errors.Is(err, ErrEmptyDiff) {
+		fmt.Fprintf(os.Stderr, "No changes on HEAD since %s.\n", base)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return printPreparedMessage(ctx, generator, prepared, verbose)
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestRunSummarize(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{"main.go": "package main\n"})
+	testutil.RunGit(t, repo, "checkout", "--quiet", "-b", "feature")
+	testutil.WriteFile(t, repo, "login.go", "package main\n\nfunc login() {}\n")
+	testutil.RunGit(t, repo, "add", "login.go")
+	testutil.RunGit(t, repo, "commit", "--quiet", "-m", "feat: add login")
+	testutil.WriteFile(t, repo, "logout.go", "package main\n\nfunc logout() {}\n")
+	testutil.RunGit(t, repo, "add", "logout.go")
+	testutil.RunGit(t, repo, "commit", "--quiet", "-m", "feat: add logout")
+	t.Chdir(repo)
+
+	tests := []struct {
+		name       string
+		base       string
+		wantErr    bool
+		wantOutput string
+	}{
+		{"branch range", "main", false, "feat: add session handling\n"},
+		{"no changes", "HEAD", false, ""},
+		{"unknown base", "no-such-branch", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newChatServer(t, "feat: add session handling")
+			var err error
+			output := captureStdout(t, func() {
+				err = RunSummarize(context.Background(), serverConfig(server), false, tt.base)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunSummarize error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if output != tt.wantOutput {
+				t.Errorf("output = %q, want %q", output, tt.wantOutput)
+			}
+			if tt.wantOutput == "" {
+				if prompts := server.requests(); len(prompts) != 0 {
+					t.Errorf("model was asked %d times, want none", len(prompts))
+				}
+				return
+			}
+			prompts := server.requests()
+			if len(prompts) != 1 {
+				t.Fatalf("model was asked %d times, want once", len(prompts))
+			}
+			for _, want := range []string{"+func login() {}", "+func logout() {}", "feat: add login", "feat: add logout"} {
+				if !strings.Contains(prompts[0], want) {
+					t.Errorf("prompt does not include %q:\n%s", want, prompts[0])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/llm"
+	"github.com/cstobie/ai-commit/internal/tokenizer"
+)
+
+// The smart diff and the prompt limit must agree on what a token is
+func TestTokenizerSharedAcrossPipeline(t *testing.T) {
+	diff := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n func main() {\n-\tfmt.Println(\"old\")\n+\tfmt.Println(\"new\")\n }\n"
+	for _, name := range []string{tokenizer.NameWords, tokenizer.NameChars} {
+		cfg := testConfig()
+		cfg.Tokenizer = name
+		tok := configTokenizer(cfg)
+
+		tokens := git.FileChange{Path: "main.go", Diff: diff}.EstimatedTokens(tok)
+		if tokens == 0 {
+			t.Fatalf("%s: estimated no tokens", name)
+		}
+		if _, truncated := llm.TruncateInput(diff, tokens, tok); truncated {
+			t.Errorf("%s: TruncateInput cut a diff the smart diff counts as %d tokens", name, tokens)
+		}
+		if _, truncated := llm.TruncateInput(diff, tokens-1, tok); !truncated {
+			t.Errorf("%s: TruncateInput kept a diff of %d tokens within %d", name, tokens, tokens-1)
+		}
+	}
+}
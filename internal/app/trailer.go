@@ -0,0 +1,55 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/cstobie/ai-commit/internal/commit"
+	"github.com/cstobie/ai-commit/internal/git"
+)
+
+// RunTrailer appends git trailers such as "Refs: #123" to the message of
+// HEAD without calling the API. Trailers already in the message are skipped.
+func RunTrailer(trailers []string) error {
+	for _, trailer := range trailers {
+		if err := commit.ValidateTrailer(trailer); err != nil {
+			return err
+		}
+	}
+
+	if err := git.EnsureGitAvailable(); err != nil {
+		return err
+	}
+	repoRoot, err := git.GetRepoRoot(".")
+	if err != nil {
+		return fmt.Errorf("This command must be run inside a git repository. %w", err)
+	}
+
+	// Staged changes would look like they belong in the amended commit
+	staged, err := git.GetStagedFilesList(repoRoot, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get staged files list: %w", err)
+	}
+	if staged != "" {
+		return git.ErrStagedChanges
+	}
+
+	head, err := git.ResolveCommit(repoRoot, "HEAD")
+	if err != nil {
+		return err
+	}
+	message, err := git.GetCommitMessage(repoRoot, head)
+	if err != nil {
+		return err
+	}
+
+	amended := commit.AppendTrailers(message, trailers)
+	if amended == message {
+		fmt.Println("Trailers already present; nothing to do.")
+		return nil
+	}
+	if err := git.AmendMessage(repoRoot, amended); err != nil {
+		return err
+	}
+	fmt.Printf("Amended %.7s:\n---\n%s\n---\n", head, amended)
+	return nil
+}
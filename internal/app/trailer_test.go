@@ -0,0 +1,38 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestRunTrailer(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{"a.txt": "a\n"})
+	testutil.RunGit(t, repo, "commit", "--quiet", "--amend", "-m", "feat: add a\n\nAdds a.")
+	t.Chdir(repo)
+
+	if err := RunTrailer([]string{"Refs: #123"}); err != nil {
+		t.Fatalf("RunTrailer() error = %v", err)
+	}
+	if err := RunTrailer([]string{"Refs: #123", "Reviewed-by: Bob <bob@example.com>"}); err != nil {
+		t.Fatalf("RunTrailer() error = %v", err)
+	}
+	want := "feat: add a\n\nAdds a.\n\nRefs: #123\nReviewed-by: Bob <bob@example.com>"
+	if got := testutil.RunGit(t, repo, "log", "-1", "--format=%B"); got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+	if got := testutil.RunGit(t, repo, "rev-list", "--count", "HEAD"); got != "1" {
+		t.Errorf("repository has %s commits, want HEAD amended in place", got)
+	}
+
+	if err := RunTrailer([]string{"not a trailer"}); err == nil {
+		t.Error("RunTrailer() accepted an invalid trailer")
+	}
+	testutil.WriteFile(t, repo, "a.txt", "b\n")
+	testutil.RunGit(t, repo, "add", "a.txt")
+	if err := RunTrailer([]string{"Refs: #9"}); !errors.Is(err, git.ErrStagedChanges) {
+		t.Errorf("RunTrailer() with staged changes error = %v, want %v", err, git.ErrStagedChanges)
+	}
+}
@@ -0,0 +1,41 @@
+package commit
+
+import (
+	"regexp"
+	"strings"
+)
+
+// publicDeclRegex matches declarations that are part of a public API: Go
+// exported functions, methods and types, JavaScript and TypeScript exports,
+// Java and C# public members, and Rust pub items
+var publicDeclRegex = regexp.MustCompile(`^(func (\([^)]*\) )?[A-Z]|type [A-Z]|export |public |pub (fn|struct|enum|trait|mod) )`)
+
+// PossibleBreaking reports whether diff removes or changes a public
+// declaration, which likely makes the change breaking. A removed declaration
+// counts unless the same line is added back elsewhere in the diff, so moved
+// code is ignored while a changed signature is not. It is a hint, not a
+// guarantee.
+func PossibleBreaking(diff string) bool {
+	var removed []string
+	added := make(map[string]bool)
+	for _, line := range strings.Split(diff, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			// File headers
+		case strings.HasPrefix(line, "-"):
+			if decl := strings.TrimSpace(line[1:]); publicDeclRegex.MatchString(decl) {
+				removed = append(removed, decl)
+			}
+		case strings.HasPrefix(line, "+"):
+			added[strings.TrimSpace(line[1:])] = true
+		}
+	}
+
+	for _, decl := range removed {
+		if !added[decl] {
+			return true
+		}
+	}
+	return false
+}
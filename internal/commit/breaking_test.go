@@ -0,0 +1,32 @@
+package commit
+
+import "testing"
+
+func TestPossibleBreaking(t *testing.T) {
+	const header = "diff --git a/api.go b/api.go\n--- a/api.go\n+++ b/api.go\n@@ -1,3 +1,3 @@\n"
+	tests := []struct {
+		name string
+		diff string
+		want bool
+	}{
+		{"removed exported function", header + "-func Parse(s string) error {\n-\treturn nil\n-}\n", true},
+		{"changed signature", header + "-func Parse(s string) error {\n+func Parse(s string, strict bool) error {\n", true},
+		{"removed method", header + "-func (c *Client) Close() error {\n", true},
+		{"removed exported type", header + "-type Options struct {\n", true},
+		{"removed unexported function", header + "-func parse(s string) error {\n", false},
+		{"moved function", header + "-func Parse(s string) error {\n+\n+func Parse(s string) error {\n", false},
+		{"added function", header + "+func Parse(s string) error {\n", false},
+		{"removed JavaScript export", "--- a/index.js\n+++ b/index.js\n-export function parse(s) {\n", true},
+		{"removed Java public method", "--- a/A.java\n+++ b/A.java\n-    public void run() {\n", true},
+		{"removed Rust pub fn", "--- a/lib.rs\n+++ b/lib.rs\n-pub fn parse() {}\n", true},
+		{"file header is not a removal", "--- a/func Parse.go\n+++ b/func Parse.go\n", false},
+		{"CRLF moved function", header + "-func Parse() {\r\n+func Parse() {\r\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PossibleBreaking(tt.diff); got != tt.want {
+				t.Errorf("PossibleBreaking() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
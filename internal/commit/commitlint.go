@@ -0,0 +1,187 @@
+package commit
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CommitlintFiles are the commitlint configs read from the repository root,
+// in order. JavaScript configs cannot be read without Node and are ignored.
+var CommitlintFiles = []string{".commitlintrc", ".commitlintrc.json", ".commitlintrc.yaml", ".commitlintrc.yml"}
+
+// conventionalPreset is the part of @commitlint/config-conventional that
+// LintRules supports, applied when a config extends it
+var conventionalPreset = LintRules{
+	Types:             []string{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"},
+	HeaderMaxLength:   100,
+	SubjectNoPeriod:   true,
+	BodyMaxLineLength: 100,
+}
+
+// LintRules are the commitlint rules that messages are checked against and
+// the model is told about. Only rules at the error level are used; zero
+// values mean a rule is not set.
+type LintRules struct {
+	Source            string   // Path of the config the rules came from
+	Types             []string // type-enum
+	Scopes            []string // scope-enum
+	HeaderMaxLength   int      // header-max-length
+	SubjectMaxLength  int      // subject-max-length
+	SubjectNoPeriod   bool     // subject-full-stop: never "."
+	BodyMaxLineLength int      // body-max-line-length
+}
+
+// commitlintConfig is the shape of a JSON or YAML commitlint config
+type commitlintConfig struct {
+	Extends any              `yaml:"extends"`
+	Rules   map[string][]any `yaml:"rules"`
+}
+
+// LoadCommitlint reads the first commitlint config found in repoRoot. It
+// returns nil if there is none.
+func LoadCommitlint(repoRoot string) (*LintRules, error) {
+	for _, name := range CommitlintFiles {
+		path := filepath.Join(repoRoot, name)
+		content, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read commitlint config: %w", err)
+		}
+		rules, err := ParseCommitlint(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid commitlint config %s: %w", name, err)
+		}
+		rules.Source = path
+		return rules, nil
+	}
+	return nil, nil
+}
+
+// ParseCommitlint parses a commitlint config in JSON or YAML, which YAML
+// parsers read alike. Rules the config does not set keep the values of
+// @commitlint/config-conventional when it extends that, and are unset
+// otherwise; rules LintRules does not support are ignored.
+func ParseCommitlint(content []byte) (*LintRules, error) {
+	var config commitlintConfig
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, err
+	}
+
+	rules := LintRules{}
+	if extendsConventional(config.Extends) {
+		rules = conventionalPreset
+	}
+	for name, value := range config.Rules {
+		if len(value) == 0 {
+			continue
+		}
+		level, ok := value[0].(int)
+		if !ok {
+			return nil, fmt.Errorf("rule %s: level must be 0, 1 or 2", name)
+		}
+		var when string
+		var arg any
+		if len(value) > 1 {
+			when, _ = value[1].(string)
+		}
+		if len(value) > 2 {
+			arg = value[2]
+		}
+		// Disabled rules and warnings turn off what a preset set
+		enabled := level == 2
+
+		switch name {
+		case "type-enum":
+			rules.Types = nil
+			if enabled && when != "never" {
+				rules.Types = stringList(arg)
+			}
+		case "scope-enum":
+			rules.Scopes = nil
+			if enabled && when != "never" {
+				rules.Scopes = stringList(arg)
+			}
+		case "header-max-length":
+			rules.HeaderMaxLength = 0
+			if n, ok := arg.(int); ok && enabled && when != "never" {
+				rules.HeaderMaxLength = n
+			}
+		case "subject-max-length":
+			rules.SubjectMaxLength = 0
+			if n, ok := arg.(int); ok && enabled && when != "never" {
+				rules.SubjectMaxLength = n
+			}
+		case "subject-full-stop":
+			stop, _ := arg.(string)
+			rules.SubjectNoPeriod = enabled && when == "never" && (stop == "." || stop == "")
+		case "body-max-line-length":
+			rules.BodyMaxLineLength = 0
+			if n, ok := arg.(int); ok && enabled && when != "never" {
+				rules.BodyMaxLineLength = n
+			}
+		}
+	}
+	return &rules, nil
+}
+
+// extendsConventional reports whether an extends value, a string or a list
+// of strings, names @commitlint/config-conventional
+func extendsConventional(extends any) bool {
+	names := stringList(extends)
+	if name, ok := extends.(string); ok {
+		names = []string{name}
+	}
+	for _, name := range names {
+		if name == "@commitlint/config-conventional" || name == "conventional" {
+			return true
+		}
+	}
+	return false
+}
+
+// stringList returns the strings in a YAML list, or nil if v is not a list
+func stringList(v any) []string {
+	items, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	var list []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// Describe returns the rules as instructions for the model, one per rule
+func (r LintRules) Describe() []string {
+	var lines []string
+	if len(r.Types) > 0 {
+		lines = append(lines, "The type must be one of: "+strings.Join(r.Types, ", "))
+	}
+	if len(r.Scopes) > 0 {
+		lines = append(lines, "The scope, if any, must be one of: "+strings.Join(r.Scopes, ", "))
+	}
+	if r.HeaderMaxLength > 0 {
+		lines = append(lines, fmt.Sprintf("The first line must be at most %d characters", r.HeaderMaxLength))
+	}
+	if r.SubjectMaxLength > 0 {
+		lines = append(lines, fmt.Sprintf("The subject after the colon must be at most %d characters", r.SubjectMaxLength))
+	}
+	if r.SubjectNoPeriod {
+		lines = append(lines, "The subject must not end with a period")
+	}
+	if r.BodyMaxLineLength > 0 {
+		lines = append(lines, fmt.Sprintf("Body lines must be at most %d characters", r.BodyMaxLineLength))
+	}
+	return lines
+}
@@ -0,0 +1,87 @@
+package commit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseCommitlint(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    LintRules
+		wantErr bool
+	}{
+		{
+			name: "sample .commitlintrc.json",
+			content: `{
+  "rules": {
+    "type-enum": [2, "always", ["feat", "fix", "docs"]],
+    "scope-enum": [2, "always", ["api", "web"]],
+    "header-max-length": [2, "always", 72],
+    "subject-max-length": [2, "always", 50],
+    "subject-full-stop": [2, "never", "."],
+    "body-max-line-length": [2, "always", 80],
+    "subject-case": [2, "always", "lower-case"]
+  }
+}`,
+			want: LintRules{Types: []string{"feat", "fix", "docs"}, Scopes: []string{"api", "web"}, HeaderMaxLength: 72,
+				SubjectMaxLength: 50, SubjectNoPeriod: true, BodyMaxLineLength: 80},
+		},
+		{
+			name:    "extends conventional",
+			content: `{"extends": ["@commitlint/config-conventional"]}`,
+			want:    conventionalPreset,
+		},
+		{
+			name:    "extends conventional with overrides in YAML",
+			content: "extends: '@commitlint/config-conventional'\nrules:\n  header-max-length: [2, always, 60]\n  body-max-line-length: [0]\n",
+			want:    LintRules{Types: conventionalPreset.Types, HeaderMaxLength: 60, SubjectNoPeriod: true},
+		},
+		{
+			name:    "warnings are not enforced",
+			content: `{"rules": {"type-enum": [1, "always", ["feat"]], "header-max-length": [1, "always", 50]}}`,
+			want:    LintRules{},
+		},
+		{name: "empty", content: `{}`, want: LintRules{}},
+		{name: "level is not a number", content: `{"rules": {"type-enum": ["error", "always", ["feat"]]}}`, wantErr: true},
+		{name: "not JSON or YAML", content: `{"rules": [`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCommitlint([]byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCommitlint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ParseCommitlint() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCommitlint(t *testing.T) {
+	repo := t.TempDir()
+	rules, err := LoadCommitlint(repo)
+	if err != nil || rules != nil {
+		t.Fatalf("LoadCommitlint() without a config = %+v, %v, want nil", rules, err)
+	}
+
+	path := filepath.Join(repo, ".commitlintrc.json")
+	if err := os.WriteFile(path, []byte(`{"rules": {"type-enum": [2, "always", ["feat", "fix"]]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err = LoadCommitlint(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.Source != path || !slices.Equal(rules.Types, []string{"feat", "fix"}) {
+		t.Errorf("LoadCommitlint() = %+v, want the types from %s", rules, path)
+	}
+	if lines := rules.Describe(); !slices.Equal(lines, []string{"The type must be one of: feat, fix"}) {
+		t.Errorf("Describe() = %q", lines)
+	}
+}
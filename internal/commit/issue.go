@@ -0,0 +1,35 @@
+package commit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// issueKeyRegex matches a Jira issue key: a project key of a letter followed
+// by letters, digits or underscores, a dash and the issue number
+var issueKeyRegex = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-[1-9][0-9]*$`)
+
+// branchIssueRegex finds an issue key in a branch name, e.g. the PROJ-123 of
+// feature/PROJ-123-login or proj-123_login
+var branchIssueRegex = regexp.MustCompile(`(?i)(?:^|[^A-Z0-9])([A-Z][A-Z0-9_]+-[1-9][0-9]*)(?:$|[^0-9])`)
+
+// ParseIssueKey validates a Jira issue key, accepting it in any case, and
+// returns it in upper case
+func ParseIssueKey(key string) (string, error) {
+	upper := strings.ToUpper(strings.TrimSpace(key))
+	if !issueKeyRegex.MatchString(upper) {
+		return "", fmt.Errorf("invalid issue key '%s': expected a Jira key like PROJ-123", key)
+	}
+	return upper, nil
+}
+
+// IssueFromBranch returns the first issue key in branch, in upper case, or an
+// empty string if it has none
+func IssueFromBranch(branch string) string {
+	m := branchIssueRegex.FindStringSubmatch(branch)
+	if m == nil {
+		return ""
+	}
+	return strings.ToUpper(m[1])
+}
@@ -0,0 +1,53 @@
+package commit
+
+import "testing"
+
+func TestParseIssueKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{"PROJ-123", "PROJ-123", false},
+		{"proj-7", "PROJ-7", false},
+		{" AB_2-10 ", "AB_2-10", false},
+		{"PROJ-0", "", true},
+		{"PROJ123", "", true},
+		{"1PROJ-1", "", true},
+		{"P-1", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := ParseIssueKey(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIssueKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseIssueKey(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIssueFromBranch(t *testing.T) {
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"feature/PROJ-123-login", "PROJ-123"},
+		{"proj-123_login", "PROJ-123"},
+		{"PROJ-9", "PROJ-9"},
+		{"bugfix/ab-12/ef-34", "AB-12"},
+		{"main", ""},
+		{"fix-login", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			if got := IssueFromBranch(tt.branch); got != tt.want {
+				t.Errorf("IssueFromBranch(%q) = %q, want %q", tt.branch, got, tt.want)
+			}
+		})
+	}
+}
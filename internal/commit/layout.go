@@ -0,0 +1,32 @@
+package commit
+
+import "strings"
+
+// NormalizeLayout fixes the layout git expects of a message: no leading
+// blank lines, the subject on the first line, exactly one blank line before
+// the body, and no trailing whitespace on any line or after the last one.
+// Without the blank line git would treat the body as part of the subject.
+func NormalizeLayout(message string) string {
+	lines := strings.Split(message, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	subject, body := lines[0], lines[1:]
+	for len(body) > 0 && body[0] == "" {
+		body = body[1:]
+	}
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+	}
+	if len(body) == 0 {
+		return subject
+	}
+	return subject + "\n\n" + strings.Join(body, "\n")
+}
@@ -0,0 +1,28 @@
+package commit
+
+import "testing"
+
+func TestNormalizeLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"already correct", "feat: add login\n\nAdds a form.\n\nAnd a route.", "feat: add login\n\nAdds a form.\n\nAnd a route."},
+		{"no blank line", "feat: add login\nAdds a form.", "feat: add login\n\nAdds a form."},
+		{"multiple blank lines", "feat: add login\n\n\n\nAdds a form.", "feat: add login\n\nAdds a form."},
+		{"leading blank lines", "\n\n  \nfeat: add login\n\nAdds a form.", "feat: add login\n\nAdds a form."},
+		{"trailing whitespace", "feat: add login  \n\nAdds a form.\t\n\n\n", "feat: add login\n\nAdds a form."},
+		{"CRLF", "feat: add login\r\n\r\nAdds a form.\r\n", "feat: add login\n\nAdds a form."},
+		{"blank lines inside the body kept", "feat: add login\n\nFirst.\n\n\nSecond.", "feat: add login\n\nFirst.\n\n\nSecond."},
+		{"subject only", "feat: add login\n\n", "feat: add login"},
+		{"empty", "\n \n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeLayout(tt.message); got != tt.want {
+				t.Errorf("NormalizeLayout(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
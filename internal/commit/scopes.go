@@ -0,0 +1,54 @@
+package commit
+
+import (
+	"path"
+	"strings"
+)
+
+// ParseScopes splits a list of scopes separated by commas or whitespace
+func ParseScopes(spec string) []string {
+	return strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
+
+// SuggestScope maps each path to the allowed scope named by its deepest
+// directory, e.g. "api" for apps/api/server.go or packages/api-client/x.ts,
+// and returns the scope most paths map to. It returns an empty string if no
+// path maps to a scope or two scopes tie.
+func SuggestScope(paths []string, allowed []string) string {
+	counts := make(map[string]int)
+	for _, p := range paths {
+		if scope := scopeOf(p, allowed); scope != "" {
+			counts[scope]++
+		}
+	}
+
+	best, bestCount, tie := "", 0, false
+	for _, scope := range allowed {
+		switch n := counts[scope]; {
+		case n > bestCount:
+			best, bestCount, tie = scope, n, false
+		case n == bestCount && n > 0:
+			tie = true
+		}
+	}
+	if tie {
+		return ""
+	}
+	return best
+}
+
+// scopeOf returns the allowed scope matching the deepest directory of p
+func scopeOf(p string, allowed []string) string {
+	for dir := path.Dir(p); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		name := strings.ToLower(path.Base(dir))
+		for _, scope := range allowed {
+			s := strings.ToLower(scope)
+			if name == s || strings.HasPrefix(name, s+"-") || strings.HasPrefix(name, s+"_") {
+				return scope
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package commit
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"", nil},
+		{"web,api, db", []string{"web", "api", "db"}},
+		{"web api\tdb\n", []string{"web", "api", "db"}},
+	}
+	for _, tt := range tests {
+		if got := ParseScopes(tt.spec); !slices.Equal(got, tt.want) {
+			t.Errorf("ParseScopes(%q) = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestScope(t *testing.T) {
+	allowed := []string{"web", "api", "db"}
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{"one scope", []string{"apps/api/server.go", "apps/api/routes.go"}, "api"},
+		{"prefixed directory", []string{"packages/api-client/index.ts"}, "api"},
+		{"deepest directory wins", []string{"web/src/db/cache.ts"}, "db"},
+		{"case-insensitive", []string{"services/DB/schema.sql"}, "db"},
+		{"majority", []string{"web/a.ts", "web/b.ts", "api/c.go"}, "web"},
+		{"tie", []string{"web/a.ts", "api/c.go"}, ""},
+		{"no matching directory", []string{"README.md", "scripts/build.sh"}, ""},
+		{"unmatched paths ignored", []string{"README.md", "db/migrate.sql"}, "db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SuggestScope(tt.paths, allowed); got != tt.want {
+				t.Errorf("SuggestScope(%q) = %q, want %q", tt.paths, got, tt.want)
+			}
+		})
+	}
+	if got := SuggestScope([]string{"api/a.go"}, nil); got != "" {
+		t.Errorf("SuggestScope without allowed scopes = %q, want none", got)
+	}
+}
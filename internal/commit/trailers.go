@@ -0,0 +1,73 @@
+package commit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// trailerLineRegex matches a git trailer line such as "Signed-off-by: A <a@b>"
+var trailerLineRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*: \S`)
+
+// ValidateTrailer checks that trailer is a single "Key: value" line
+func ValidateTrailer(trailer string) error {
+	if strings.Contains(trailer, "\n") || !trailerLineRegex.MatchString(trailer) {
+		return fmt.Errorf("invalid trailer '%s': expected \"Key: value\", e.g. \"Refs: #123\"", trailer)
+	}
+	return nil
+}
+
+// AppendTrailers adds trailers after the trailer block at the end of message,
+// starting a new block if there is none. Trailers already in the block are
+// skipped.
+func AppendTrailers(message string, trailers []string) string {
+	paragraphs := strings.Split(strings.TrimRight(message, "\n"), "\n\n")
+	last := len(paragraphs) - 1
+	if last == 0 || !isTrailerBlock(paragraphs[last]) {
+		paragraphs = append(paragraphs, "")
+		last++
+	}
+
+	block := paragraphs[last]
+	for _, trailer := range trailers {
+		if block != "" && strings.Contains("\n"+block+"\n", "\n"+trailer+"\n") {
+			continue
+		}
+		if block != "" {
+			block += "\n"
+		}
+		block += trailer
+	}
+	if block == "" {
+		return message
+	}
+	paragraphs[last] = block
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// AppendFooter adds footer as the last paragraph of message, keeping any
+// trailer block at the very end where git expects it. A footer made of
+// trailers joins that block.
+func AppendFooter(message, footer string) string {
+	if isTrailerBlock(footer) {
+		return AppendTrailers(message, strings.Split(footer, "\n"))
+	}
+
+	paragraphs := strings.Split(strings.TrimRight(message, "\n"), "\n\n")
+	last := len(paragraphs) - 1
+	if last == 0 || !isTrailerBlock(paragraphs[last]) {
+		return strings.Join(append(paragraphs, footer), "\n\n")
+	}
+	paragraphs = append(paragraphs[:last], footer, paragraphs[last])
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// isTrailerBlock reports whether every line of paragraph is a git trailer
+func isTrailerBlock(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !trailerLineRegex.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package commit
+
+import "testing"
+
+func TestValidateTrailer(t *testing.T) {
+	tests := []struct {
+		trailer string
+		wantErr bool
+	}{
+		{"Refs: #123", false},
+		{"Co-authored-by: Jane Doe <jane@example.com>", false},
+		{"Refs:#123", true},
+		{"Refs #123", true},
+		{": value", true},
+		{"Refs: #1\nFixes: #2", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateTrailer(tt.trailer); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateTrailer(%q) error = %v, wantErr %v", tt.trailer, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAppendTrailers(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		trailers []string
+		want     string
+	}{
+		{
+			name:     "subject only",
+			message:  "feat: add login",
+			trailers: []string{"Refs: #123"},
+			want:     "feat: add login\n\nRefs: #123",
+		},
+		{
+			name:     "subject that looks like a trailer",
+			message:  "fix: handle nil",
+			trailers: []string{"Refs: #1"},
+			want:     "fix: handle nil\n\nRefs: #1",
+		},
+		{
+			name:     "body without trailers",
+			message:  "feat: add login\n\nAdds a form.\n",
+			trailers: []string{"Refs: #123", "Reviewed-by: Bob <bob@example.com>"},
+			want:     "feat: add login\n\nAdds a form.\n\nRefs: #123\nReviewed-by: Bob <bob@example.com>",
+		},
+		{
+			name:     "joins existing block",
+			message:  "feat: add login\n\nAdds a form.\n\nSigned-off-by: A <a@b>",
+			trailers: []string{"Refs: #123"},
+			want:     "feat: add login\n\nAdds a form.\n\nSigned-off-by: A <a@b>\nRefs: #123",
+		},
+		{
+			name:     "skips duplicate",
+			message:  "feat: add login\n\nRefs: #123",
+			trailers: []string{"Refs: #123"},
+			want:     "feat: add login\n\nRefs: #123",
+		},
+		{
+			name:     "no trailers",
+			message:  "feat: add login",
+			trailers: nil,
+			want:     "feat: add login",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AppendTrailers(tt.message, tt.trailers); got != tt.want {
+				t.Errorf("AppendTrailers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendFooter(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		footer  string
+		want    string
+	}{
+		{
+			name:    "plain footer",
+			message: "feat: add login",
+			footer:  "Generated by ai-commit",
+			want:    "feat: add login\n\nGenerated by ai-commit",
+		},
+		{
+			name:    "plain footer goes before trailers",
+			message: "feat: add login\n\nRefs: #123",
+			footer:  "Generated by ai-commit",
+			want:    "feat: add login\n\nGenerated by ai-commit\n\nRefs: #123",
+		},
+		{
+			name:    "trailer footer joins block",
+			message: "feat: add login\n\nRefs: #123",
+			footer:  "Model: test/model",
+			want:    "feat: add login\n\nRefs: #123\nModel: test/model",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AppendFooter(tt.message, tt.footer); got != tt.want {
+				t.Errorf("AppendFooter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
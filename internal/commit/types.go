@@ -0,0 +1,107 @@
+// Package commit derives commit message hints from the changed files and the diff
+package commit
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// TypeRule maps files matching any of its patterns to a conventional commit
+// type. A pattern ending in "/" matches a directory anywhere in the path; a
+// pattern containing "/" otherwise matches the whole path; any other pattern
+// matches the file name. Patterns use path.Match syntax.
+type TypeRule struct {
+	Type     string
+	Patterns []string
+}
+
+// DefaultTypeRules are used when no rules are configured. Tests come first so
+// a test fixture under docs/ still counts as a test.
+var DefaultTypeRules = []TypeRule{
+	{Type: "test", Patterns: []string{"*_test.go", "*_test.py", "test_*.py", "*.test.js", "*.test.ts", "*.test.tsx",
+		"*.spec.js", "*.spec.ts", "test/", "tests/", "__tests__/", "testdata/"}},
+	{Type: "docs", Patterns: []string{"*.md", "*.rst", "*.adoc", "docs/", "doc/", "LICENSE"}},
+}
+
+// ParseTypeRules parses a "type=pattern,pattern;type=pattern" list of rules.
+// Rules are tried in order and the first match wins.
+func ParseTypeRules(spec string) ([]TypeRule, error) {
+	var rules []TypeRule
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		commitType, patterns, ok := strings.Cut(entry, "=")
+		commitType = strings.TrimSpace(commitType)
+		if !ok || commitType == "" {
+			return nil, fmt.Errorf("invalid type rule '%s': expected type=pattern,pattern", entry)
+		}
+		rule := TypeRule{Type: commitType}
+		for _, pattern := range strings.Split(patterns, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			if _, err := path.Match(strings.TrimSuffix(pattern, "/"), ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s' for type %s: %w", pattern, commitType, err)
+			}
+			rule.Patterns = append(rule.Patterns, pattern)
+		}
+		if len(rule.Patterns) == 0 {
+			return nil, fmt.Errorf("invalid type rule '%s': no patterns", entry)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// SuggestType returns the type of the first rule matching each path when
+// all paths get the same type, and an empty string for mixed changes, for
+// files no rule matches, or for no paths at all
+func SuggestType(paths []string, rules []TypeRule) string {
+	suggested := ""
+	for _, p := range paths {
+		commitType := typeOf(p, rules)
+		if commitType == "" || (suggested != "" && commitType != suggested) {
+			return ""
+		}
+		suggested = commitType
+	}
+	return suggested
+}
+
+// typeOf returns the type of the first rule with a pattern matching p
+func typeOf(p string, rules []TypeRule) string {
+	for _, rule := range rules {
+		for _, pattern := range rule.Patterns {
+			if matches(pattern, p) {
+				return rule.Type
+			}
+		}
+	}
+	return ""
+}
+
+// matches reports whether the repo-relative path p matches pattern
+func matches(pattern, p string) bool {
+	if dir, ok := strings.CutSuffix(pattern, "/"); ok {
+		for parent := path.Dir(p); parent != "."; parent = path.Dir(parent) {
+			if matched, _ := path.Match(dir, path.Base(parent)); matched && !strings.Contains(dir, "/") {
+				return true
+			}
+			if matched, _ := path.Match(dir, parent); matched {
+				return true
+			}
+		}
+		return false
+	}
+	if strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, p)
+		return matched
+	}
+	matched, _ := path.Match(pattern, path.Base(p))
+	return matched
+}
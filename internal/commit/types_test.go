@@ -0,0 +1,63 @@
+package commit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSuggestType(t *testing.T) {
+	custom := []TypeRule{{Type: "ci", Patterns: []string{".github/workflows/*.yml", "Jenkinsfile"}}}
+	tests := []struct {
+		name  string
+		paths []string
+		rules []TypeRule
+		want  string
+	}{
+		{"docs only", []string{"README.md", "docs/setup.txt", "LICENSE"}, DefaultTypeRules, "docs"},
+		{"tests only", []string{"internal/app/app_test.go", "tests/e2e.sh", "web/login.spec.ts"}, DefaultTypeRules, "test"},
+		{"test fixture under docs", []string{"docs/testdata/sample.md"}, DefaultTypeRules, "test"},
+		{"mixed docs and tests", []string{"README.md", "app_test.go"}, DefaultTypeRules, ""},
+		{"docs with code", []string{"README.md", "main.go"}, DefaultTypeRules, ""},
+		{"code only", []string{"main.go"}, DefaultTypeRules, ""},
+		{"no paths", nil, DefaultTypeRules, ""},
+		{"custom rules", []string{".github/workflows/ci.yml", "Jenkinsfile"}, custom, "ci"},
+		{"custom rules ignore defaults", []string{"README.md"}, custom, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SuggestType(tt.paths, tt.rules); got != tt.want {
+				t.Errorf("SuggestType(%q) = %q, want %q", tt.paths, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTypeRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []TypeRule
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"two rules", "docs=*.md, docs/; ci = .github/workflows/*.yml;", []TypeRule{
+			{Type: "docs", Patterns: []string{"*.md", "docs/"}},
+			{Type: "ci", Patterns: []string{".github/workflows/*.yml"}},
+		}, false},
+		{"missing type", "=*.md", nil, true},
+		{"missing patterns", "docs=", nil, true},
+		{"no equals sign", "docs", nil, true},
+		{"bad pattern", "docs=[", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTypeRules(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTypeRules(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTypeRules(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,13 +1,33 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
 	"strings"
+	"text/template"
 
+	"github.com/cstobie/ai-commit/internal/commit"
+	"github.com/cstobie/ai-commit/internal/credentials"
+	"github.com/cstobie/ai-commit/internal/git"
+	"github.com/cstobie/ai-commit/internal/llm"
+	"github.com/cstobie/ai-commit/internal/logging"
+	"github.com/cstobie/ai-commit/internal/secrets"
+	tmpl "github.com/cstobie/ai-commit/internal/template"
+	"github.com/cstobie/ai-commit/internal/tokenizer"
+	"github.com/cstobie/ai-commit/internal/ui"
+	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
 
+// DefaultEnvFile is the dotenv file loaded from the current directory when no
+// explicit path is given
+const DefaultEnvFile = ".env"
+
 type Config struct {
 	OpenRouterAPIKey string  `mapstructure:"OPENROUTER_API_KEY"`
 	LLMModel         string  `mapstructure:"LLM_MODEL"`
@@ -16,10 +36,256 @@ type Config struct {
 	TemplateName     string  `mapstructure:"TEMPLATE_NAME"`
 	BasePrompt       string  `mapstructure:"BASE_PROMPT"` // Internal use for template
 	TimeoutSeconds   int     `mapstructure:"TIMEOUT_SECONDS"`
-	Temperature      float64 `mapstructure:"TEMPERATURE"` // Optional temperature setting
+	Temperature      float64 `mapstructure:"TEMPERATURE"`        // Optional temperature setting
+	ShowUsage        string  `mapstructure:"SHOW_USAGE"`         // Usage footer: off, compact or full
+	ModelLimits      string  `mapstructure:"MODEL_LIMITS"`       // Context window overrides: model=tokens,...
+	DiffContext      int     `mapstructure:"DIFF_CONTEXT"`       // Lines of context in the diff (--unified=N)
+	IgnoreWhitespace bool    `mapstructure:"IGNORE_WHITESPACE"`  // Hide whitespace-only changes from the diff
+	IgnoreBinary     bool    `mapstructure:"IGNORE_BINARY"`      // Leave binary files out of the diff entirely
+	HTTPProxy        string  `mapstructure:"HTTP_PROXY"`         // Proxy URL for API calls, or "none" to disable proxies
+	APIBaseURL       string  `mapstructure:"API_BASE_URL"`       // Chat completions API base, e.g. a gateway
+	Structured       bool    `mapstructure:"STRUCTURED"`         // Request JSON output and format it locally
+	RequirePattern   string  `mapstructure:"REQUIRE_PATTERN"`    // Regex generated messages must match
+	MaxRetries       int     `mapstructure:"MAX_RETRIES"`        // Regeneration attempts for rejected messages
+	RetryEmpty       int     `mapstructure:"RETRY_EMPTY"`        // Regeneration attempts for empty responses
+	MaxContinuations int     `mapstructure:"MAX_CONTINUATIONS"`  // Requests for the rest of a cut-off message
+	MinMessageLength int     `mapstructure:"MIN_MESSAGE_LENGTH"` // Shorter messages are rejected as placeholders
+	Detailed         bool    `mapstructure:"DETAILED"`           // Add a body with one bullet per file
+	LogLevel         string  `mapstructure:"LOG_LEVEL"`          // debug, info, warn or error
+	Language         string  `mapstructure:"LANGUAGE"`           // Natural language of the message; empty means English
+	LocalizeType     bool    `mapstructure:"LOCALIZE_TYPE"`      // Translate the conventional commit type prefix too
+	// Warn when the staged diff exceeds MaxInputTokens by this factor; 0 disables
+	DiffWarnMultiplier float64 `mapstructure:"DIFF_WARN_MULTIPLIER"`
+	// Temperature added each time the user regenerates interactively
+	RegenerateTemperatureStep float64 `mapstructure:"REGENERATE_TEMPERATURE_STEP"`
+	// Limit the diff and commit to these paths; set from --pathspec, not the environment
+	Pathspecs []string `mapstructure:"-"`
+	// Output token limit for the explain command
+	ExplainMaxOutputTokens int `mapstructure:"EXPLAIN_MAX_OUTPUT_TOKENS"`
+	// Sampling of over-budget files in the smart diff
+	SmartDiffHeadLines int `mapstructure:"SMART_DIFF_HEAD_LINES"`
+	SmartDiffMaxChunks int `mapstructure:"SMART_DIFF_MAX_CHUNKS"`
+	SmartDiffTailHunks int `mapstructure:"SMART_DIFF_TAIL_HUNKS"`
+	SmartDiffTailLines int `mapstructure:"SMART_DIFF_TAIL_LINES"`
+	// Generate a one-line subject without a body
+	SubjectOnly bool `mapstructure:"SUBJECT_ONLY"`
+	// Longer messages are shortened according to MessageOverflow; 0 disables
+	MaxMessageChars int `mapstructure:"MAX_MESSAGE_CHARS"`
+	// How to shorten long messages: truncate or reprompt
+	MessageOverflow string `mapstructure:"MESSAGE_OVERFLOW"`
+	// API provider, one of llm.ProviderNames: openrouter or azure
+	Provider string `mapstructure:"PROVIDER"`
+	// Azure OpenAI deployment settings, used when Provider is azure
+	AzureEndpoint   string `mapstructure:"AZURE_ENDPOINT"`
+	AzureDeployment string `mapstructure:"AZURE_DEPLOYMENT"`
+	AzureAPIVersion string `mapstructure:"AZURE_API_VERSION"`
+	AzureAPIKey     string `mapstructure:"AZURE_API_KEY"`
+	// API key of a provider without a field of its own, read from the
+	// setting its registry entry names
+	otherAPIKey string
+	// Check the diff for likely secrets before sending it
+	SecretScan bool `mapstructure:"SECRET_SCAN"`
+	// Extra secret regexes, separated by whitespace (use \s inside a pattern)
+	SecretPatterns string `mapstructure:"SECRET_PATTERNS"`
+	// Regexes for matches or file paths that are never reported, separated by whitespace
+	SecretAllowlist string `mapstructure:"SECRET_ALLOWLIST"`
+	// Extra regexes for declarations listed as API additions, separated by whitespace
+	DeclPatterns string `mapstructure:"DECL_PATTERNS"`
+	// Render a message from the file list without calling the API
+	NoLLM bool `mapstructure:"NO_LLM"`
+	// Format messages as Jira smart commits for the issue named by Issue or the branch
+	SmartCommit bool `mapstructure:"SMART_COMMIT"`
+	// Jira issue key for smart commits; set from --issue, not the environment
+	Issue string `mapstructure:"-"`
+	// Text added before and after every message; may use {{.Branch}} and {{.Model}}
+	MessageHeader string `mapstructure:"MESSAGE_HEADER"`
+	MessageFooter string `mapstructure:"MESSAGE_FOOTER"`
+	// How generated messages are printed: a preset (fenced, plain) or a
+	// template using {{.Heading}}, {{.Message}} and {{.Fence}}
+	OutputTemplate string `mapstructure:"OUTPUT_TEMPLATE"`
+	// Token budgets for the subject and body, shown to the model; when either
+	// is set their sum replaces MaxOutputTokens
+	SubjectTokens int `mapstructure:"SUBJECT_TOKENS"`
+	BodyTokens    int `mapstructure:"BODY_TOKENS"`
+	// Offer to write the message in the editor when generation fails interactively
+	FallbackEditor bool `mapstructure:"FALLBACK_EDITOR"`
+	// File with example diffs and messages shown to the model before the real diff
+	ExamplesFile string `mapstructure:"EXAMPLES_FILE"`
+	// Examples loaded from ExamplesFile
+	Examples []Example `mapstructure:"-"`
+	// Rules from the repository's commitlint config, if it has one
+	Commitlint *commit.LintRules `mapstructure:"-"`
+	// Shorten diff lines longer than this many characters; 0 disables
+	MaxLineChars int `mapstructure:"MAX_LINE_CHARS"`
+	// Above this many staged files, send only the file list and stats; 0 disables
+	MaxFiles int `mapstructure:"MAX_FILES"`
+	// API requests per minute across invocations; 0 disables throttling
+	MaxRPM int `mapstructure:"MAX_RPM"`
+	// Number of recent commit messages shown to the model as style examples; 0 disables
+	HistoryCount int `mapstructure:"HISTORY_COUNT"`
+	// Rules suggesting a commit type from the changed files:
+	// type=pattern,pattern;type=pattern. Empty uses commit.DefaultTypeRules
+	TypeRules string `mapstructure:"TYPE_RULES"`
+	// Templates chosen by the size of the staged change, as
+	// template=files<N,lines<N;template. Empty always uses TemplateName
+	TemplateRules string `mapstructure:"TEMPLATE_RULES"`
+	// Rules parsed from TemplateRules
+	ParsedTemplateRules []TemplateRule `mapstructure:"-"`
+	// Rules parsed from TypeRules
+	ParsedTypeRules []commit.TypeRule `mapstructure:"-"`
+	// Scopes messages may use, separated by commas or spaces; empty allows any
+	AllowedScopes string `mapstructure:"ALLOWED_SCOPES"`
+	// Scopes parsed from AllowedScopes
+	ParsedScopes []string `mapstructure:"-"`
+	// Commit guidelines shown to the model; empty looks for .gitmessage, then
+	// CONTRIBUTING.md in the repository root
+	GuidelinesFile string `mapstructure:"GUIDELINES_FILE"`
+	// Guidelines are trimmed to this many characters; 0 disables them
+	GuidelinesChars int `mapstructure:"GUIDELINES_CHARS"`
+	// Extra headers for API requests: name=value;name2=value2
+	ExtraHeaders string `mapstructure:"EXTRA_HEADERS"`
+	// Headers parsed from ExtraHeaders
+	Headers map[string]string `mapstructure:"-"`
+	// Message role carrying the prompt instructions: user or system
+	PromptRole string `mapstructure:"PROMPT_ROLE"`
+	// How tokens are estimated for MaxInputTokens and the smart diff: words (the default) or chars
+	Tokenizer string `mapstructure:"TOKENIZER"`
+	// Temperature 0 and a fixed seed for reproducible output
+	Deterministic bool `mapstructure:"DETERMINISTIC"`
+	// What Enter does at the commit menu: commit or abort
+	InteractiveDefault string `mapstructure:"INTERACTIVE_DEFAULT"`
+	// Where API keys come from: env, or keychain for the OS keychain, where
+	// keys set in the environment still win
+	SecretSource string `mapstructure:"SECRET_SOURCE"`
+	// Plain output without ANSI colors; also set from --no-color
+	NoColor bool `mapstructure:"NO_COLOR"`
+	// Print only the message on stdout; set from --quiet
+	Quiet bool `mapstructure:"-"`
+	// Sign the commit: GPGSignDefaultKey or a key ID; empty follows commit.gpgsign.
+	// Set from --gpg-sign
+	GPGSign string `mapstructure:"-"`
+	// Author ("Name <email>") and date overrides for the commit; set from --author and --date
+	Author string `mapstructure:"-"`
+	Date   string `mapstructure:"-"`
+	// Dump the prompt to stderr before calling the API; set from --debug-prompt
+	DebugPrompt bool `mapstructure:"-"`
+	// Write the dumped prompt to this file instead of stderr; set from --prompt-out
+	PromptOut string `mapstructure:"-"`
+	// Write the message to this file instead of printing it and committing;
+	// set from --output-file
+	OutputFile string `mapstructure:"-"`
+	// The author's intent, shown to the model; set from --hint
+	Hint string `mapstructure:"-"`
+	// Read the diff from this file ("-" for stdin) instead of git; set from --diff-file
+	DiffFile string `mapstructure:"-"`
+	// Commit without asking, never reading stdin; set from --yes
+	Yes bool `mapstructure:"-"`
+	// Push after committing, to the upstream or to PushRemote and PushBranch
+	// when set; set from --push and --push-to
+	Push       bool   `mapstructure:"-"`
+	PushRemote string `mapstructure:"-"`
+	PushBranch string `mapstructure:"-"`
+
+	// MAX_INPUT_TOKENS is not set, so the input budget grows to the model's
+	// context window when it is known
+	AutoInputTokens bool `mapstructure:"-"`
+	// Model defaults in effect, e.g. "TEMPERATURE=0.3", for verbose output
+	ModelDefaults []string `mapstructure:"-"`
+	// Settings the model and template defaults may change; see UseModel and
+	// ApplyTemplateDefaults
+	defaultable modelDefaultable
+}
+
+// String returns a printable form of the config with the API key redacted,
+// so the config can be logged safely
+func (c Config) String() string {
+	// A distinct type drops the String method and avoids infinite recursion
+	type plainConfig Config
+	redacted := plainConfig(c)
+	redacted.OpenRouterAPIKey = secrets.RedactKey(c.OpenRouterAPIKey)
+	redacted.AzureAPIKey = secrets.RedactKey(c.AzureAPIKey)
+	redacted.otherAPIKey = secrets.RedactKey(c.otherAPIKey)
+	return fmt.Sprintf("Config%+v", redacted)
+}
+
+// GoString redacts the API key for %#v formatting as well
+func (c Config) GoString() string {
+	return c.String()
 }
 
-func LoadConfig() (Config, error) {
+// DefaultMaxInputTokens is the input token budget when MAX_INPUT_TOKENS is
+// not set and the model's context window is unknown
+const DefaultMaxInputTokens = 4000
+
+// SubjectOnlyMaxOutputTokens is the output token limit in subject-only mode
+// unless MAX_OUTPUT_TOKENS is set explicitly
+const SubjectOnlyMaxOutputTokens = 40
+
+// GPGSignDefaultKey requests signing with git's default signing key
+const GPGSignDefaultKey = "default"
+
+// APIKeyName returns the setting holding the API key for provider, without
+// the AICOMMIT_ prefix, as registered in llm.ProviderNames. An empty provider
+// means OpenRouter.
+func APIKeyName(provider string) string {
+	if provider == "" {
+		provider = llm.ProviderOpenRouter
+	}
+	spec, _ := llm.LookupProvider(provider)
+	return spec.APIKeyName
+}
+
+// APIKey returns the API key for the selected provider
+func (c Config) APIKey() string {
+	return *c.apiKeyField()
+}
+
+// apiKeyField points at the field holding the API key for the selected
+// provider. Providers without a field of their own share otherAPIKey.
+func (c *Config) apiKeyField() *string {
+	switch APIKeyName(c.Provider) {
+	case "OPENROUTER_API_KEY":
+		return &c.OpenRouterAPIKey
+	case "AZURE_API_KEY":
+		return &c.AzureAPIKey
+	default:
+		return &c.otherAPIKey
+	}
+}
+
+// Supported values for PromptRole
+const (
+	PromptRoleUser   = "user"
+	PromptRoleSystem = "system"
+)
+
+// Supported values for InteractiveDefault
+const (
+	InteractiveCommit = "commit"
+	InteractiveAbort  = "abort"
+)
+
+// Ways to shorten messages longer than MaxMessageChars
+const (
+	OverflowTruncate = "truncate"
+	OverflowReprompt = "reprompt"
+)
+
+// Usage footer modes for ShowUsage
+const (
+	UsageOff     = "off"
+	UsageCompact = "compact"
+	UsageFull    = "full"
+)
+
+// LoadConfig reads configuration from the environment. Variables from envFile
+// are loaded first without overriding the real process environment. An empty
+// envFile means DefaultEnvFile, which is silently skipped when missing.
+func LoadConfig(envFile string) (Config, error) {
+	if err := loadEnvFile(envFile); err != nil {
+		return Config{}, err
+	}
+
 	viper.SetEnvPrefix("AICOMMIT") // Environment variables prefix: AICOMMIT_
 	viper.AutomaticEnv()
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
@@ -32,31 +298,346 @@ func LoadConfig() (Config, error) {
 	viper.BindEnv("TEMPLATE_NAME")
 	viper.BindEnv("TIMEOUT_SECONDS")
 	viper.BindEnv("TEMPERATURE")
+	viper.BindEnv("SHOW_USAGE")
+	viper.BindEnv("MODEL_LIMITS")
+	viper.BindEnv("DIFF_CONTEXT")
+	viper.BindEnv("IGNORE_WHITESPACE")
+	viper.BindEnv("IGNORE_BINARY")
+	viper.BindEnv("DIFF_WARN_MULTIPLIER")
+	viper.BindEnv("REGENERATE_TEMPERATURE_STEP")
+	viper.BindEnv("SMART_DIFF_HEAD_LINES")
+	viper.BindEnv("SMART_DIFF_MAX_CHUNKS")
+	viper.BindEnv("SMART_DIFF_TAIL_HUNKS")
+	viper.BindEnv("SMART_DIFF_TAIL_LINES")
+	viper.BindEnv("API_BASE_URL")
+	viper.BindEnv("HTTP_PROXY")
+	viper.BindEnv("STRUCTURED")
+	viper.BindEnv("REQUIRE_PATTERN")
+	viper.BindEnv("MAX_RETRIES")
+	viper.BindEnv("RETRY_EMPTY")
+	viper.BindEnv("MAX_CONTINUATIONS")
+	viper.BindEnv("EXPLAIN_MAX_OUTPUT_TOKENS")
+	viper.BindEnv("MIN_MESSAGE_LENGTH")
+	viper.BindEnv("DETAILED")
+	viper.BindEnv("LANGUAGE")
+	viper.BindEnv("LOG_LEVEL")
+	viper.BindEnv("LOCALIZE_TYPE")
+	viper.BindEnv("SUBJECT_ONLY")
+	viper.BindEnv("MAX_MESSAGE_CHARS")
+	viper.BindEnv("MESSAGE_OVERFLOW")
+	viper.BindEnv("PROVIDER")
+	viper.BindEnv("SECRET_SCAN")
+	viper.BindEnv("NO_LLM")
+	viper.BindEnv("MESSAGE_HEADER")
+	viper.BindEnv("SMART_COMMIT")
+	viper.BindEnv("MESSAGE_FOOTER")
+	viper.BindEnv("OUTPUT_TEMPLATE")
+	viper.BindEnv("SUBJECT_TOKENS")
+	viper.BindEnv("BODY_TOKENS")
+	viper.BindEnv("HISTORY_COUNT")
+	viper.BindEnv("MAX_RPM")
+	viper.BindEnv("PROMPT_ROLE")
+	viper.BindEnv("EXTRA_HEADERS")
+	viper.BindEnv("GUIDELINES_FILE")
+	viper.BindEnv("TYPE_RULES")
+	viper.BindEnv("TEMPLATE_RULES")
+	viper.BindEnv("ALLOWED_SCOPES")
+	viper.BindEnv("DETERMINISTIC")
+	viper.BindEnv("TOKENIZER")
+	viper.BindEnv("GUIDELINES_CHARS")
+	viper.BindEnv("INTERACTIVE_DEFAULT")
+	viper.BindEnv("MAX_LINE_CHARS")
+	viper.BindEnv("MAX_FILES")
+	viper.BindEnv("SECRET_SOURCE")
+	viper.BindEnv("EXAMPLES_FILE")
+	viper.BindEnv("FALLBACK_EDITOR")
+	viper.BindEnv("SECRET_PATTERNS")
+	viper.BindEnv("SECRET_ALLOWLIST")
+	viper.BindEnv("DECL_PATTERNS")
+	viper.BindEnv("AZURE_ENDPOINT")
+	viper.BindEnv("AZURE_DEPLOYMENT")
+	viper.BindEnv("AZURE_API_VERSION")
+	viper.BindEnv("AZURE_API_KEY")
 
 	// Default values
 	viper.SetDefault("LLM_MODEL", "openai/gpt-4o-mini") // Updated Default Model
-	viper.SetDefault("MAX_INPUT_TOKENS", 4000)
+	viper.SetDefault("MAX_INPUT_TOKENS", DefaultMaxInputTokens)
 	viper.SetDefault("MAX_OUTPUT_TOKENS", 200)
 	viper.SetDefault("TEMPLATE_NAME", "conventional")
 	viper.SetDefault("TIMEOUT_SECONDS", 60) // Default request timeout
 	viper.SetDefault("TEMPERATURE", 0.7)    // Default temperature
+	viper.SetDefault("SHOW_USAGE", UsageOff)
+	viper.SetDefault("DIFF_CONTEXT", 3)
+	viper.SetDefault("IGNORE_WHITESPACE", true)
+	viper.SetDefault("DIFF_WARN_MULTIPLIER", 2.0)
+	viper.SetDefault("REGENERATE_TEMPERATURE_STEP", 0.1)
+	viper.SetDefault("SMART_DIFF_HEAD_LINES", 5)
+	viper.SetDefault("SMART_DIFF_MAX_CHUNKS", 3)
+	viper.SetDefault("SMART_DIFF_TAIL_HUNKS", 2)
+	viper.SetDefault("SMART_DIFF_TAIL_LINES", 4)
+	viper.SetDefault("MAX_RETRIES", 2)
+	viper.SetDefault("RETRY_EMPTY", 2)
+	viper.SetDefault("MAX_CONTINUATIONS", 2)
+	viper.SetDefault("EXPLAIN_MAX_OUTPUT_TOKENS", 800)
+	viper.SetDefault("MIN_MESSAGE_LENGTH", 10)
+	viper.SetDefault("LOG_LEVEL", "warn")
+	viper.SetDefault("MAX_MESSAGE_CHARS", 2000)
+	viper.SetDefault("MESSAGE_OVERFLOW", OverflowTruncate)
+	viper.SetDefault("PROVIDER", llm.ProviderOpenRouter)
+	viper.SetDefault("SECRET_SCAN", true)
+	viper.SetDefault("AZURE_API_VERSION", "2024-06-01")
+	viper.SetDefault("MAX_LINE_CHARS", 1000)
+	viper.SetDefault("MAX_FILES", 300)
+	viper.SetDefault("SECRET_SOURCE", credentials.SourceEnv)
+	viper.SetDefault("PROMPT_ROLE", PromptRoleUser)
+	viper.SetDefault("GUIDELINES_CHARS", 2000)
+	viper.SetDefault("INTERACTIVE_DEFAULT", InteractiveCommit)
+	viper.SetDefault("TOKENIZER", tokenizer.NameWords)
+	viper.SetDefault("OUTPUT_TEMPLATE", ui.OutputFenced)
 
 	var cfg Config
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return Config{}, fmt.Errorf("unable to decode config: %w", err)
 	}
 
+	// The model's recommended settings fill what is still at the built-in
+	// defaults. The template's own settings are applied once the template
+	// is final; see ApplyTemplateDefaults.
+	_, modelSet := os.LookupEnv("AICOMMIT_LLM_MODEL")
+	_, maxOutputSet := os.LookupEnv("AICOMMIT_MAX_OUTPUT_TOKENS")
+	_, temperatureSet := os.LookupEnv("AICOMMIT_TEMPERATURE")
+	_, maxInputSet := os.LookupEnv("AICOMMIT_MAX_INPUT_TOKENS")
+	cfg.AutoInputTokens = !maxInputSet
+	budgetSet := maxOutputSet || cfg.SubjectTokens != 0 || cfg.BodyTokens != 0
+	cfg.defaultable = modelDefaultable{
+		model:                   !modelSet,
+		templateMaxOutputTokens: !budgetSet,
+		maxOutputTokens:         !budgetSet && !cfg.SubjectOnly,
+		temperature:             !temperatureSet && !cfg.Deterministic,
+		baseMaxOutputTokens:     cfg.MaxOutputTokens,
+		baseTemperature:         cfg.Temperature,
+	}
+	cfg.applyModelDefaults()
+
+	if cfg.Deterministic {
+		cfg.Temperature = 0
+	}
+
+	if cfg.SubjectTokens < 0 || cfg.BodyTokens < 0 {
+		return Config{}, fmt.Errorf("subject and body tokens must not be negative")
+	}
+	if cfg.SubjectTokens > 0 || cfg.BodyTokens > 0 {
+		cfg.MaxOutputTokens = cfg.SubjectTokens + cfg.BodyTokens
+	}
+	// A single line needs far fewer tokens than a full message
+	if _, set := os.LookupEnv("AICOMMIT_MAX_OUTPUT_TOKENS"); cfg.SubjectOnly && !set {
+		cfg.MaxOutputTokens = SubjectOnlyMaxOutputTokens
+		if cfg.SubjectTokens > 0 {
+			cfg.MaxOutputTokens = cfg.SubjectTokens
+		}
+	}
+
 	// Check if API key is loaded from environment
 	_ = viper.GetString("OPENROUTER_API_KEY")
 
 	// Validation (Example)
-	if cfg.OpenRouterAPIKey == "" {
-		log.Println("Warning: AICOMMIT_OPENROUTER_API_KEY environment variable not set.")
+	cfg.Provider = strings.ToLower(cfg.Provider)
+	if _, ok := llm.LookupProvider(cfg.Provider); !ok {
+		return Config{}, fmt.Errorf("invalid PROVIDER '%s': must be one of %s", cfg.Provider, strings.Join(llm.ProviderNames(), ", "))
+	}
+
+	// Fill in the key for the selected provider from the secret source
+	secretProvider, err := credentials.NewProvider(cfg.SecretSource)
+	if err != nil {
+		return Config{}, err
+	}
+	apiKey := cfg.apiKeyField()
+	if *apiKey == "" && !cfg.NoLLM {
+		if *apiKey, err = secretProvider.Secret(APIKeyName(cfg.Provider)); err != nil {
+			return Config{}, err
+		}
+	}
+	if *apiKey == "" && !cfg.NoLLM {
+		slog.Warn("API key environment variable not set", "variable", "AICOMMIT_"+APIKeyName(cfg.Provider))
 		// Allow proceeding but API calls will fail later if key is truly needed
 	}
-	if cfg.MaxInputTokens <= 0 || cfg.MaxOutputTokens <= 0 {
+
+	if cfg.Provider == llm.ProviderAzure {
+		// API_BASE_URL replaces the endpoint, e.g. for a gateway in front of it
+		if (cfg.AzureEndpoint == "" && cfg.APIBaseURL == "") || cfg.AzureDeployment == "" {
+			return Config{}, fmt.Errorf("AZURE_ENDPOINT (or API_BASE_URL) and AZURE_DEPLOYMENT must be set when PROVIDER is azure")
+		}
+		if cfg.AzureEndpoint != "" {
+			u, err := url.Parse(cfg.AzureEndpoint)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return Config{}, fmt.Errorf("invalid AZURE_ENDPOINT '%s': must be an absolute http(s) URL", cfg.AzureEndpoint)
+			}
+		}
+	}
+	if cfg.MaxInputTokens <= 0 || cfg.MaxOutputTokens <= 0 || cfg.ExplainMaxOutputTokens <= 0 {
 		return Config{}, fmt.Errorf("token limits must be positive")
 	}
+	if cfg.DiffContext < 0 {
+		return Config{}, fmt.Errorf("diff context lines must not be negative")
+	}
+	if cfg.RegenerateTemperatureStep < 0 {
+		return Config{}, fmt.Errorf("regenerate temperature step must not be negative")
+	}
+	if cfg.DiffWarnMultiplier < 0 {
+		return Config{}, fmt.Errorf("diff warn multiplier must not be negative")
+	}
+	if cfg.SmartDiffHeadLines < 0 || cfg.SmartDiffMaxChunks < 0 || cfg.SmartDiffTailHunks < 0 || cfg.SmartDiffTailLines < 0 {
+		return Config{}, fmt.Errorf("smart diff sampling settings must not be negative")
+	}
+	if cfg.APIBaseURL != "" {
+		u, err := url.Parse(cfg.APIBaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid API_BASE_URL '%s': must be an absolute http(s) URL", cfg.APIBaseURL)
+		}
+	}
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		return Config{}, err
+	}
+	if cfg.HTTPProxy != "" && !strings.EqualFold(cfg.HTTPProxy, "none") {
+		u, err := url.Parse(cfg.HTTPProxy)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid HTTP_PROXY '%s': must be a proxy URL or \"none\"", cfg.HTTPProxy)
+		}
+	}
+	if cfg.RequirePattern != "" {
+		if _, err := regexp.Compile(cfg.RequirePattern); err != nil {
+			return Config{}, fmt.Errorf("invalid REQUIRE_PATTERN: %w", err)
+		}
+	}
+	if _, err := secrets.NewScanner(strings.Fields(cfg.SecretPatterns), strings.Fields(cfg.SecretAllowlist)); err != nil {
+		return Config{}, err
+	}
+	if _, err := git.NewDeclMatcher(strings.Fields(cfg.DeclPatterns)); err != nil {
+		return Config{}, err
+	}
+	for name, text := range map[string]string{"MESSAGE_HEADER": cfg.MessageHeader, "MESSAGE_FOOTER": cfg.MessageFooter} {
+		if _, err := template.New(name).Funcs(tmpl.Funcs).Parse(text); err != nil {
+			return Config{}, fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	if _, err := ui.ParseOutput(cfg.OutputTemplate); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxRetries < 0 {
+		return Config{}, fmt.Errorf("max retries must not be negative")
+	}
+	if cfg.RetryEmpty < 0 {
+		return Config{}, fmt.Errorf("empty response retries must not be negative")
+	}
+	if cfg.MaxContinuations < 0 {
+		return Config{}, fmt.Errorf("max continuations must not be negative")
+	}
+	if cfg.ExamplesFile != "" {
+		examples, err := LoadExamples(cfg.ExamplesFile)
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.Examples = examples
+	}
+	cfg.ParsedScopes = commit.ParseScopes(cfg.AllowedScopes)
+	cfg.ParsedTypeRules = commit.DefaultTypeRules
+	if cfg.TypeRules != "" {
+		rules, err := commit.ParseTypeRules(cfg.TypeRules)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid TYPE_RULES: %w", err)
+		}
+		cfg.ParsedTypeRules = rules
+	}
+	templateRules, err := ParseTemplateRules(cfg.TemplateRules)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid TEMPLATE_RULES: %w", err)
+	}
+	cfg.ParsedTemplateRules = templateRules
+	headers, err := ParseExtraHeaders(cfg.ExtraHeaders)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid EXTRA_HEADERS: %w", err)
+	}
+	cfg.Headers = headers
+	if cfg.MaxLineChars < 0 {
+		return Config{}, fmt.Errorf("max line chars must not be negative")
+	}
+	if cfg.MaxFiles < 0 {
+		return Config{}, fmt.Errorf("max files must not be negative")
+	}
+	if cfg.MaxRPM < 0 {
+		return Config{}, fmt.Errorf("max RPM must not be negative")
+	}
+	if cfg.GuidelinesChars < 0 {
+		return Config{}, fmt.Errorf("guidelines chars must not be negative")
+	}
+	if cfg.HistoryCount < 0 {
+		return Config{}, fmt.Errorf("history count must not be negative")
+	}
+	if cfg.MaxMessageChars < 0 {
+		return Config{}, fmt.Errorf("max message chars must not be negative")
+	}
+	cfg.Tokenizer = strings.ToLower(cfg.Tokenizer)
+	if _, err := tokenizer.New(cfg.Tokenizer); err != nil {
+		return Config{}, err
+	}
+	cfg.InteractiveDefault = strings.ToLower(cfg.InteractiveDefault)
+	if cfg.InteractiveDefault != InteractiveCommit && cfg.InteractiveDefault != InteractiveAbort {
+		return Config{}, fmt.Errorf("invalid INTERACTIVE_DEFAULT '%s': must be commit or abort", cfg.InteractiveDefault)
+	}
+	cfg.PromptRole = strings.ToLower(cfg.PromptRole)
+	if cfg.PromptRole != PromptRoleUser && cfg.PromptRole != PromptRoleSystem {
+		return Config{}, fmt.Errorf("invalid PROMPT_ROLE '%s': must be user or system", cfg.PromptRole)
+	}
+	cfg.MessageOverflow = strings.ToLower(cfg.MessageOverflow)
+	if cfg.MessageOverflow != OverflowTruncate && cfg.MessageOverflow != OverflowReprompt {
+		return Config{}, fmt.Errorf("invalid MESSAGE_OVERFLOW '%s': must be truncate or reprompt", cfg.MessageOverflow)
+	}
+	cfg.ShowUsage = strings.ToLower(cfg.ShowUsage)
+	switch cfg.ShowUsage {
+	case UsageOff, UsageCompact, UsageFull:
+	default:
+		return Config{}, fmt.Errorf("invalid SHOW_USAGE '%s': must be off, compact or full", cfg.ShowUsage)
+	}
 
 	return cfg, nil
-}
\ No newline at end of file
+}
+
+// ApplyTemplateDefaults applies the settings from the config block of the
+// template in use to those not set in the environment or by flags. They
+// replace the built-in and model defaults; a model they set brings its own
+// defaults for the other settings.
+func (c *Config) ApplyTemplateDefaults(defaults tmpl.Defaults) {
+	d := &c.defaultable
+	if defaults.Model != "" && d.model {
+		c.LLMModel = defaults.Model
+	}
+	if defaults.MaxOutputTokens > 0 && d.templateMaxOutputTokens {
+		c.MaxOutputTokens = defaults.MaxOutputTokens
+		d.maxOutputTokens = false
+	}
+	if defaults.Temperature != nil && d.temperature {
+		c.Temperature = *defaults.Temperature
+		d.temperature = false
+	}
+	c.applyModelDefaults()
+}
+
+// loadEnvFile loads variables from a dotenv file into the process environment.
+// Existing environment variables always take precedence over file values.
+// Values are never logged so secrets stay out of verbose output.
+func loadEnvFile(envFile string) error {
+	explicit := envFile != ""
+	if !explicit {
+		envFile = DefaultEnvFile
+	}
+
+	if err := godotenv.Load(envFile); err != nil {
+		// A missing default .env is fine, a missing explicit file is not
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("unable to load env file '%s': %w", envFile, err)
+	}
+
+	return nil
+}
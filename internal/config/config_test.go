@@ -0,0 +1,66 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/llm"
+)
+
+func TestConfigStringRedactsKeys(t *testing.T) {
+	const openRouterKey = "sk-or-v1-secretsecret9876"
+	const azureKey = "azure-secretsecret5432"
+	const otherKey = "other-secretsecret1098"
+	cfg := Config{OpenRouterAPIKey: openRouterKey, AzureAPIKey: azureKey, otherAPIKey: otherKey, LLMModel: "test/model"}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	logger.Debug("Using configuration", "config", cfg)
+	outputs := map[string]string{
+		"String": cfg.String(),
+		"%v":     fmt.Sprintf("%v", cfg),
+		"%+v":    fmt.Sprintf("%+v", cfg),
+		"%#v":    fmt.Sprintf("%#v", cfg),
+		"slog":   logs.String(),
+	}
+	for name, output := range outputs {
+		if strings.Contains(output, openRouterKey) || strings.Contains(output, azureKey) || strings.Contains(output, otherKey) {
+			t.Errorf("%s output contains a full API key: %s", name, output)
+		}
+		if !strings.Contains(output, "9876") || !strings.Contains(output, "test/model") {
+			t.Errorf("%s output lost the redacted key or other settings: %s", name, output)
+		}
+	}
+}
+
+func TestAPIKeyName(t *testing.T) {
+	for _, name := range llm.ProviderNames() {
+		if APIKeyName(name) == "" {
+			t.Errorf("provider %s has no API key setting", name)
+		}
+	}
+	if got := APIKeyName(""); got != "OPENROUTER_API_KEY" {
+		t.Errorf("APIKeyName(\"\") = %q, want the OpenRouter key", got)
+	}
+}
+
+func TestAPIKeyFollowsProvider(t *testing.T) {
+	tests := []struct {
+		provider string
+		want     string
+	}{
+		{"", "openrouter-key"},
+		{llm.ProviderOpenRouter, "openrouter-key"},
+		{llm.ProviderAzure, "azure-key"},
+		{"unregistered", "other-key"},
+	}
+	for _, tt := range tests {
+		cfg := Config{Provider: tt.provider, OpenRouterAPIKey: "openrouter-key", AzureAPIKey: "azure-key", otherAPIKey: "other-key"}
+		if got := cfg.APIKey(); got != tt.want {
+			t.Errorf("APIKey() with provider %q = %q, want %q", tt.provider, got, tt.want)
+		}
+	}
+}
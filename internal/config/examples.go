@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Lines starting the parts of each example in an examples file
+const (
+	examplesDiffMarker    = "=== diff"
+	examplesMessageMarker = "=== message"
+)
+
+// Example is a diff and the commit message written for it, shown to the model
+// before the real diff
+type Example struct {
+	Diff    string
+	Message string
+}
+
+// LoadExamples reads examples from a file made of sections like:
+//
+//	=== diff
+//	<diff>
+//	=== message
+//	<commit message>
+func LoadExamples(path string) ([]Example, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read examples file: %w", err)
+	}
+	examples, err := ParseExamples(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid examples file '%s': %w", path, err)
+	}
+	return examples, nil
+}
+
+// ParseExamples parses the contents of an examples file
+func ParseExamples(content string) ([]Example, error) {
+	var examples []Example
+	var current *strings.Builder
+	var diff, message strings.Builder
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		example := Example{Diff: strings.Trim(diff.String(), "\n"), Message: strings.TrimSpace(message.String())}
+		if example.Diff == "" || example.Message == "" {
+			return fmt.Errorf("example %d needs both a non-empty diff and message", len(examples)+1)
+		}
+		examples = append(examples, example)
+		diff.Reset()
+		message.Reset()
+		return nil
+	}
+
+	for i, line := range strings.Split(content, "\n") {
+		switch strings.TrimRight(line, " \r") {
+		case examplesDiffMarker:
+			if current == &diff {
+				return nil, fmt.Errorf("line %d: expected %q before the next diff", i+1, examplesMessageMarker)
+			}
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			current = &diff
+		case examplesMessageMarker:
+			if current != &diff {
+				return nil, fmt.Errorf("line %d: %q must follow a diff", i+1, examplesMessageMarker)
+			}
+			current = &message
+		default:
+			if current == nil {
+				if strings.TrimSpace(line) != "" {
+					return nil, fmt.Errorf("line %d: expected %q", i+1, examplesDiffMarker)
+				}
+				continue
+			}
+			current.WriteString(line + "\n")
+		}
+	}
+	if current == &diff {
+		return nil, fmt.Errorf("example %d has no %q section", len(examples)+1, examplesMessageMarker)
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return examples, nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExamples(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Example
+		wantErr bool
+	}{
+		{
+			name: "two examples",
+			content: "\n=== diff\n-old\n+new\n=== message\nfix: use the new value\n\n" +
+				"=== diff\r\n+added\n=== message \nfeat: add a line\n\nWith a body.\n",
+			want: []Example{
+				{Diff: "-old\n+new", Message: "fix: use the new value"},
+				{Diff: "+added", Message: "feat: add a line\n\nWith a body."},
+			},
+		},
+		{name: "empty file", content: "\n"},
+		{name: "text before the first diff", content: "notes\n=== diff\n+a\n=== message\nfeat: a\n", wantErr: true},
+		{name: "diff without message", content: "=== diff\n+a\n", wantErr: true},
+		{name: "two diffs in a row", content: "=== diff\n+a\n=== diff\n+b\n=== message\nfeat: b\n", wantErr: true},
+		{name: "message without diff", content: "=== message\nfeat: a\n", wantErr: true},
+		{name: "empty message", content: "=== diff\n+a\n=== message\n\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExamples(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExamples() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExamples() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// headerNameRegex matches valid HTTP header names (RFC 9110 tokens)
+var headerNameRegex = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// reservedHeaders carry authentication or the request encoding and are
+// always set by ai-commit itself
+var reservedHeaders = []string{"Authorization", "Content-Type", "Api-Key"}
+
+// ParseExtraHeaders parses a "name=value;name2=value2" list of headers sent
+// with every API request. Reserved headers are rejected.
+func ParseExtraHeaders(spec string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, entry := range strings.Split(spec, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid header '%s': expected name=value", strings.TrimSpace(entry))
+		}
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if !headerNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid header name '%s'", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("invalid value for header '%s': must be a single line", name)
+		}
+		for _, reserved := range reservedHeaders {
+			if strings.EqualFold(name, reserved) {
+				return nil, fmt.Errorf("header '%s' is set by ai-commit and cannot be overridden", name)
+			}
+		}
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+	return headers, nil
+}
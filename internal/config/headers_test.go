@@ -0,0 +1,35 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExtraHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"empty", "", map[string]string{}, false},
+		{"two headers", "x-team-id=platform; X-Cost-Center = 42 ;", map[string]string{"X-Team-Id": "platform", "X-Cost-Center": "42"}, false},
+		{"value with equals sign", "X-Token=a=b", map[string]string{"X-Token": "a=b"}, false},
+		{"missing value", "X-Team-Id", nil, true},
+		{"invalid name", "X Team=1", nil, true},
+		{"authorization", "Authorization=Bearer other", nil, true},
+		{"content type in lowercase", "content-type=text/plain", nil, true},
+		{"azure api key", "api-key=other", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExtraHeaders(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExtraHeaders(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExtraHeaders(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import "fmt"
+
+// ModelDefaults are recommended settings for a model. Zero values keep the
+// generic defaults.
+type ModelDefaults struct {
+	MaxOutputTokens int
+	Temperature     *float64
+}
+
+// temperature returns a pointer to t for ModelDefaults literals
+func temperature(t float64) *float64 {
+	return &t
+}
+
+// KnownModelDefaults maps model IDs to their recommended settings. They fill
+// MAX_OUTPUT_TOKENS and TEMPERATURE when neither the environment nor the
+// template sets them.
+var KnownModelDefaults = map[string]ModelDefaults{
+	// Reasoning models spend output tokens on thinking before the answer,
+	// and OpenAI's only accept temperature 1
+	"openai/o1-mini":       {MaxOutputTokens: 4000, Temperature: temperature(1)},
+	"openai/o3-mini":       {MaxOutputTokens: 4000, Temperature: temperature(1)},
+	"deepseek/deepseek-r1": {MaxOutputTokens: 4000, Temperature: temperature(0.6)},
+	// Small open models drift from the requested format at higher temperatures
+	"meta-llama/llama-3-8b-instruct": {Temperature: temperature(0.3)},
+	"mistralai/mistral-7b-instruct":  {Temperature: temperature(0.3)},
+}
+
+// modelDefaultable records which settings model and template defaults may
+// fill because they were not set explicitly, and the values they had before
+type modelDefaultable struct {
+	model                   bool
+	templateMaxOutputTokens bool // Subject-only mode has its own budget, which only the template replaces
+	maxOutputTokens         bool
+	temperature             bool
+	baseMaxOutputTokens     int
+	baseTemperature         float64
+}
+
+// UseModel switches to model and applies its recommended defaults to the
+// settings that were not set explicitly, undoing those of the previous model.
+// Template defaults no longer change the model.
+func (c *Config) UseModel(model string) {
+	c.LLMModel = model
+	c.defaultable.model = false
+	c.applyModelDefaults()
+}
+
+// SetTemperature sets the temperature explicitly, so model and template
+// defaults no longer change it
+func (c *Config) SetTemperature(temperature float64) {
+	c.Temperature = temperature
+	c.defaultable.temperature = false
+}
+
+// applyModelDefaults fills the settings in c.defaultable from the defaults
+// of c.LLMModel and records them in c.ModelDefaults
+func (c *Config) applyModelDefaults() {
+	d := c.defaultable
+	c.ModelDefaults = nil
+	if d.maxOutputTokens {
+		c.MaxOutputTokens = d.baseMaxOutputTokens
+	}
+	if d.temperature {
+		c.Temperature = d.baseTemperature
+	}
+
+	defaults, ok := KnownModelDefaults[c.LLMModel]
+	if !ok {
+		return
+	}
+	if d.maxOutputTokens && defaults.MaxOutputTokens > 0 {
+		c.MaxOutputTokens = defaults.MaxOutputTokens
+		c.ModelDefaults = append(c.ModelDefaults, fmt.Sprintf("MAX_OUTPUT_TOKENS=%d", defaults.MaxOutputTokens))
+	}
+	if d.temperature && defaults.Temperature != nil {
+		c.Temperature = *defaults.Temperature
+		c.ModelDefaults = append(c.ModelDefaults, fmt.Sprintf("TEMPERATURE=%g", *defaults.Temperature))
+	}
+}
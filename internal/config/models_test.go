@@ -0,0 +1,77 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestApplyModelDefaults(t *testing.T) {
+	defaultable := modelDefaultable{maxOutputTokens: true, temperature: true, baseMaxOutputTokens: 200, baseTemperature: 0.7}
+
+	tests := []struct {
+		name            string
+		model           string
+		setup           func(c *Config)
+		wantMaxOutput   int
+		wantTemperature float64
+		wantApplied     []string
+	}{
+		{
+			name:            "known model gets its defaults",
+			model:           "openai/o3-mini",
+			setup:           func(c *Config) {},
+			wantMaxOutput:   4000,
+			wantTemperature: 1,
+			wantApplied:     []string{"MAX_OUTPUT_TOKENS=4000", "TEMPERATURE=1"},
+		},
+		{
+			name:            "known model with only a temperature",
+			model:           "mistralai/mistral-7b-instruct",
+			setup:           func(c *Config) {},
+			wantMaxOutput:   200,
+			wantTemperature: 0.3,
+			wantApplied:     []string{"TEMPERATURE=0.3"},
+		},
+		{
+			name:            "unknown model keeps generic defaults",
+			model:           "openai/gpt-4o-mini",
+			setup:           func(c *Config) {},
+			wantMaxOutput:   200,
+			wantTemperature: 0.7,
+		},
+		{
+			name:  "explicit settings win",
+			model: "openai/o3-mini",
+			setup: func(c *Config) {
+				c.defaultable.maxOutputTokens = false
+				c.MaxOutputTokens = 300
+				c.SetTemperature(0.2)
+			},
+			wantMaxOutput:   300,
+			wantTemperature: 0.2,
+		},
+		{
+			name:  "switching models undoes the previous defaults",
+			model: "openai/o3-mini",
+			setup: func(c *Config) {
+				c.UseModel("openai/gpt-4o-mini")
+			},
+			wantMaxOutput:   200,
+			wantTemperature: 0.7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{LLMModel: tt.model, MaxOutputTokens: 200, Temperature: 0.7, defaultable: defaultable}
+			tt.setup(&cfg)
+			cfg.applyModelDefaults()
+			if cfg.MaxOutputTokens != tt.wantMaxOutput || cfg.Temperature != tt.wantTemperature {
+				t.Errorf("settings = %d tokens, temperature %g, want %d, %g",
+					cfg.MaxOutputTokens, cfg.Temperature, tt.wantMaxOutput, tt.wantTemperature)
+			}
+			if !slices.Equal(cfg.ModelDefaults, tt.wantApplied) {
+				t.Errorf("ModelDefaults = %q, want %q", cfg.ModelDefaults, tt.wantApplied)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"testing"
+
+	tmpl "github.com/cstobie/ai-commit/internal/template"
+)
+
+func TestApplyTemplateDefaults(t *testing.T) {
+	temperature := 0.3
+	defaults := tmpl.Defaults{Model: "openai/o3-mini", MaxOutputTokens: 400, Temperature: &temperature}
+	base := Config{LLMModel: "openai/gpt-4o-mini", MaxOutputTokens: 200, Temperature: 0.7}
+	defaultable := modelDefaultable{
+		model:                   true,
+		templateMaxOutputTokens: true,
+		maxOutputTokens:         true,
+		temperature:             true,
+		baseMaxOutputTokens:     200,
+		baseTemperature:         0.7,
+	}
+
+	tests := []struct {
+		name            string
+		setup           func(c *Config)
+		wantModel       string
+		wantMaxOutput   int
+		wantTemperature float64
+	}{
+		{
+			name:            "template replaces built-in and model defaults",
+			setup:           func(c *Config) {},
+			wantModel:       "openai/o3-mini",
+			wantMaxOutput:   400,
+			wantTemperature: 0.3,
+		},
+		{
+			name: "environment wins",
+			setup: func(c *Config) {
+				c.defaultable.model = false
+				c.defaultable.templateMaxOutputTokens = false
+				c.defaultable.maxOutputTokens = false
+				c.defaultable.temperature = false
+			},
+			wantModel:       "openai/gpt-4o-mini",
+			wantMaxOutput:   200,
+			wantTemperature: 0.7,
+		},
+		{
+			name: "flags win",
+			setup: func(c *Config) {
+				c.UseModel("openai/o1-mini")
+				c.SetTemperature(1.2)
+			},
+			wantModel:       "openai/o1-mini",
+			wantMaxOutput:   400,
+			wantTemperature: 1.2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.defaultable = defaultable
+			tt.setup(&cfg)
+			cfg.ApplyTemplateDefaults(defaults)
+			if cfg.LLMModel != tt.wantModel || cfg.MaxOutputTokens != tt.wantMaxOutput || cfg.Temperature != tt.wantTemperature {
+				t.Errorf("got model %s, max output tokens %d, temperature %g; want %s, %d, %g",
+					cfg.LLMModel, cfg.MaxOutputTokens, cfg.Temperature, tt.wantModel, tt.wantMaxOutput, tt.wantTemperature)
+			}
+		})
+	}
+}
+
+func TestApplyTemplateDefaultsModelBringsItsDefaults(t *testing.T) {
+	cfg := Config{LLMModel: "openai/gpt-4o-mini", MaxOutputTokens: 200, Temperature: 0.7}
+	cfg.defaultable = modelDefaultable{
+		model:                   true,
+		templateMaxOutputTokens: true,
+		maxOutputTokens:         true,
+		temperature:             true,
+		baseMaxOutputTokens:     200,
+		baseTemperature:         0.7,
+	}
+	cfg.ApplyTemplateDefaults(tmpl.Defaults{Model: "openai/o3-mini"})
+	if cfg.MaxOutputTokens != 4000 || cfg.Temperature != 1 {
+		t.Errorf("got max output tokens %d, temperature %g; want the o3-mini defaults 4000, 1",
+			cfg.MaxOutputTokens, cfg.Temperature)
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tmpl "github.com/cstobie/ai-commit/internal/template"
+)
+
+// TemplateRule selects a template for staged changes below its limits
+type TemplateRule struct {
+	Template string
+	MaxFiles int // Changes to fewer files match; 0 for no limit
+	MaxLines int // Fewer added and removed lines match; 0 for no limit
+}
+
+// Matches reports whether a change to files files with lines changed lines
+// is within the rule's limits
+func (r TemplateRule) Matches(files, lines int) bool {
+	return (r.MaxFiles == 0 || files < r.MaxFiles) && (r.MaxLines == 0 || lines < r.MaxLines)
+}
+
+// ParseTemplateRules parses a "template=files<N,lines<N;template" list of
+// rules. Both limits are optional, and a template without any matches every
+// change. Rules are tried in order and the first match wins.
+func ParseTemplateRules(spec string) ([]TemplateRule, error) {
+	var rules []TemplateRule
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, conditions, _ := strings.Cut(entry, "=")
+		rule := TemplateRule{Template: strings.TrimSpace(name)}
+		if !tmpl.Exists(rule.Template) {
+			return nil, fmt.Errorf("invalid template rule '%s': template '%s' does not exist", entry, rule.Template)
+		}
+		for _, condition := range strings.Split(conditions, ",") {
+			condition = strings.TrimSpace(condition)
+			if condition == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(condition, "<")
+			limit, err := strconv.Atoi(strings.TrimSpace(value))
+			if !ok || err != nil || limit <= 0 {
+				return nil, fmt.Errorf("invalid condition '%s' for template %s: expected files<N or lines<N", condition, rule.Template)
+			}
+			switch strings.TrimSpace(key) {
+			case "files":
+				rule.MaxFiles = limit
+			case "lines":
+				rule.MaxLines = limit
+			default:
+				return nil, fmt.Errorf("invalid condition '%s' for template %s: expected files<N or lines<N", condition, rule.Template)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
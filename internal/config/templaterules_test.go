@@ -0,0 +1,62 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTemplateRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []TemplateRule
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"file limit and fallback", "simple=files<3;conventional", []TemplateRule{
+			{Template: "simple", MaxFiles: 3},
+			{Template: "conventional"},
+		}, false},
+		{"both limits", " simple = files<2, lines<20 ", []TemplateRule{{Template: "simple", MaxFiles: 2, MaxLines: 20}}, false},
+		{"unknown template", "missing=files<3", nil, true},
+		{"unknown condition", "simple=hunks<3", nil, true},
+		{"zero limit", "simple=files<0", nil, true},
+		{"not a limit", "simple=files>3", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTemplateRules(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTemplateRules(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTemplateRules(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateRuleMatches(t *testing.T) {
+	rule := TemplateRule{Template: "simple", MaxFiles: 3, MaxLines: 20}
+	tests := []struct {
+		name  string
+		files int
+		lines int
+		want  bool
+	}{
+		{"below both limits", 2, 19, true},
+		{"at the file limit", 3, 1, false},
+		{"at the line limit", 1, 20, false},
+		{"one file", 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rule.Matches(tt.files, tt.lines); got != tt.want {
+				t.Errorf("Matches(%d, %d) = %v, want %v", tt.files, tt.lines, got, tt.want)
+			}
+		})
+	}
+	if !(TemplateRule{Template: "conventional"}).Matches(1000, 100000) {
+		t.Error("a rule without limits does not match every change")
+	}
+}
@@ -0,0 +1,94 @@
+// Package credentials reads API keys from the environment or the OS keychain
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Sources of API keys
+const (
+	SourceEnv      = "env"      // AICOMMIT_ environment variables and .env files
+	SourceKeychain = "keychain" // macOS Keychain, Secret Service on Linux, Windows Credential Manager
+)
+
+// keychainService is the service name keys are stored under in the keychain
+const keychainService = "ai-commit"
+
+// SecretProvider looks up secrets by the name of their environment variable
+// without the AICOMMIT_ prefix, e.g. OPENROUTER_API_KEY
+type SecretProvider interface {
+	// Secret returns the named secret, or an empty string if it is not stored
+	Secret(name string) (string, error)
+}
+
+// Env reads secrets from AICOMMIT_ environment variables
+type Env struct{}
+
+// Secret returns the value of AICOMMIT_<name>
+func (Env) Secret(name string) (string, error) {
+	return os.Getenv("AICOMMIT_" + name), nil
+}
+
+// KeyringBackend is the part of the OS keychain API used by Keychain
+type KeyringBackend interface {
+	Get(service, user string) (string, error)
+	Set(service, user, password string) error
+}
+
+// osKeyring is the system keychain
+type osKeyring struct{}
+
+func (osKeyring) Get(service, user string) (string, error) { return keyring.Get(service, user) }
+func (osKeyring) Set(service, user, password string) error {
+	return keyring.Set(service, user, password)
+}
+
+// Keychain reads and stores secrets in the OS keychain, under the
+// "ai-commit" service with the secret name as the account
+type Keychain struct {
+	Backend KeyringBackend // The system keychain if nil
+}
+
+// backend returns the configured backend or the system keychain
+func (k Keychain) backend() KeyringBackend {
+	if k.Backend == nil {
+		return osKeyring{}
+	}
+	return k.Backend
+}
+
+// Secret returns the named secret from the keychain
+func (k Keychain) Secret(name string) (string, error) {
+	secret, err := k.backend().Get(keychainService, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s from the keychain: %w", name, err)
+	}
+	return secret, nil
+}
+
+// Store saves the named secret in the keychain, replacing any previous value
+func (k Keychain) Store(name, secret string) error {
+	if err := k.backend().Set(keychainService, name, secret); err != nil {
+		return fmt.Errorf("unable to store %s in the keychain: %w", name, err)
+	}
+	return nil
+}
+
+// NewProvider returns the provider for a SECRET_SOURCE value
+func NewProvider(source string) (SecretProvider, error) {
+	switch source {
+	case SourceEnv, "":
+		return Env{}, nil
+	case SourceKeychain:
+		return Keychain{}, nil
+	default:
+		return nil, fmt.Errorf("invalid SECRET_SOURCE '%s': must be env or keychain", source)
+	}
+}
@@ -0,0 +1,88 @@
+package credentials
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// fakeKeyring is an in-memory keychain
+type fakeKeyring struct {
+	secrets map[string]string // "service/user" to secret
+	err     error             // Returned by every call when set
+}
+
+func (f *fakeKeyring) Get(service, user string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	secret, ok := f.secrets[service+"/"+user]
+	if !ok {
+		return "", keyring.ErrNotFound
+	}
+	return secret, nil
+}
+
+func (f *fakeKeyring) Set(service, user, password string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.secrets[service+"/"+user] = password
+	return nil
+}
+
+func TestKeychain(t *testing.T) {
+	backend := &fakeKeyring{secrets: map[string]string{}}
+	keychain := Keychain{Backend: backend}
+
+	if secret, err := keychain.Secret("OPENROUTER_API_KEY"); err != nil || secret != "" {
+		t.Fatalf("Secret() before storing = %q, %v, want an empty secret", secret, err)
+	}
+	if err := keychain.Store("OPENROUTER_API_KEY", "sk-or-v1-stored"); err != nil {
+		t.Fatal(err)
+	}
+	if got := backend.secrets["ai-commit/OPENROUTER_API_KEY"]; got != "sk-or-v1-stored" {
+		t.Errorf("stored secret = %q under the ai-commit service", got)
+	}
+	if secret, err := keychain.Secret("OPENROUTER_API_KEY"); err != nil || secret != "sk-or-v1-stored" {
+		t.Errorf("Secret() = %q, %v, want the stored key", secret, err)
+	}
+	if secret, _ := keychain.Secret("AZURE_API_KEY"); secret != "" {
+		t.Errorf("Secret() for another name = %q, want none", secret)
+	}
+
+	backend.err = errors.New("keychain locked")
+	if _, err := keychain.Secret("OPENROUTER_API_KEY"); err == nil {
+		t.Error("Secret() hid a keychain error")
+	}
+	if err := keychain.Store("OPENROUTER_API_KEY", "x"); err == nil {
+		t.Error("Store() hid a keychain error")
+	}
+}
+
+func TestEnv(t *testing.T) {
+	t.Setenv("AICOMMIT_OPENROUTER_API_KEY", "sk-or-v1-env")
+	if secret, err := (Env{}).Secret("OPENROUTER_API_KEY"); err != nil || secret != "sk-or-v1-env" {
+		t.Errorf("Secret() = %q, %v, want the environment value", secret, err)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    SecretProvider
+		wantErr bool
+	}{
+		{"", Env{}, false},
+		{SourceEnv, Env{}, false},
+		{SourceKeychain, Keychain{}, false},
+		{"vault", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := NewProvider(tt.source)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NewProvider(%q) = %#v, %v, want %#v", tt.source, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// declKeywords start the declarations the smart diff keeps as important chunks
+const declKeywords = `func|def|class|void|export|function`
+
+// Patterns for the lines the smart diff keeps from truncated files
+var (
+	funcPattern   = regexp.MustCompile(`(?m)^[+-](` + declKeywords + `)`)
+	importPattern = regexp.MustCompile(`(?m)^[+-](import|from|require|use|using)`)
+)
+
+// DefaultDeclPatterns match function, type and class declarations, as for the
+// smart diff plus type-only keywords. They are applied to a line without its
+// +/- marker and indentation.
+var DefaultDeclPatterns = []string{
+	`^(async\s+)?(` + declKeywords + `)\b`,
+	`^(type|interface|struct|enum|trait|fn)\b`,
+	`^(pub|public)(\([^)]*\))?\s`,
+}
+
+// maxDeclarations bounds how many added declarations are listed, so a new
+// file full of helpers does not crowd out the diff
+const maxDeclarations = 30
+
+// DeclMatcher finds declarations added by a diff
+type DeclMatcher struct {
+	patterns []*regexp.Regexp
+}
+
+// NewDeclMatcher returns a DeclMatcher using DefaultDeclPatterns plus the
+// extra patterns
+func NewDeclMatcher(extra []string) (*DeclMatcher, error) {
+	m := &DeclMatcher{}
+	for _, expr := range DefaultDeclPatterns {
+		m.patterns = append(m.patterns, regexp.MustCompile(expr))
+	}
+	for _, expr := range extra {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid declaration pattern %q: %w", expr, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+	return m, nil
+}
+
+// Added lists the declarations added by diff, each followed by its file, e.g.
+// "func Push(repoRoot, remote, branch string) error (internal/git/push.go)".
+// A declaration removed elsewhere in the diff was moved rather than added and
+// is left out. Past maxDeclarations the list ends with a count of the rest.
+func (m *DeclMatcher) Added(diff string) []string {
+	type decl struct{ text, path string }
+	var added []decl
+	removed := make(map[string]bool)
+	var path string
+	for _, line := range strings.Split(diff, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			path = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+		case strings.HasPrefix(line, "--- "):
+			// Old file header
+		case strings.HasPrefix(line, "-"):
+			removed[strings.TrimSpace(line[1:])] = true
+		case strings.HasPrefix(line, "+"):
+			if text := strings.TrimSpace(line[1:]); m.matches(text) {
+				added = append(added, decl{text, path})
+			}
+		}
+	}
+
+	var list []string
+	for _, d := range added {
+		if !removed[d.text] {
+			list = append(list, fmt.Sprintf("%s (%s)", strings.TrimSuffix(d.text, " {"), d.path))
+		}
+	}
+	if len(list) > maxDeclarations {
+		list = append(list[:maxDeclarations], fmt.Sprintf("... and %d more", len(list)-maxDeclarations))
+	}
+	return list
+}
+
+// matches reports whether line starts a declaration
+func (m *DeclMatcher) matches(line string) bool {
+	for _, re := range m.patterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
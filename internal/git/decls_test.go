@@ -0,0 +1,78 @@
+package git
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDeclMatcherAdded(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra []string
+		diff  string
+		want  []string
+	}{
+		{
+			name: "two functions",
+			diff: "diff --git a/auth.go b/auth.go\n--- a/auth.go\n+++ b/auth.go\n@@ -1,2 +1,8 @@\n" +
+				"+func Login(user string) error {\n+\treturn nil\n+}\n+\n+func (s *Session) Close() error {\n+\treturn nil\n+}\n",
+			want: []string{"func Login(user string) error (auth.go)", "func (s *Session) Close() error (auth.go)"},
+		},
+		{
+			name: "types across files",
+			diff: "--- a/api.ts\n+++ b/api.ts\n+export interface User {\n+  name: string\n+}\n" +
+				"--- a/lib.rs\n+++ b/lib.rs\n+pub struct Config {\n+    name: String,\n",
+			want: []string{"export interface User (api.ts)", "pub struct Config (lib.rs)"},
+		},
+		{
+			name: "moved declaration left out",
+			diff: "--- a/a.go\n+++ b/a.go\n-func Parse() {\n--- a/b.go\n+++ b/b.go\n+func Parse() {\n+func Format() {\n",
+			want: []string{"func Format() (b.go)"},
+		},
+		{
+			name:  "extra pattern",
+			extra: []string{`^defmodule\s`},
+			diff:  "--- a/lib/app.ex\n+++ b/lib/app.ex\n+defmodule App do\n+  x = 1\n",
+			want:  []string{"defmodule App do (lib/app.ex)"},
+		},
+		{
+			name: "no declarations",
+			diff: "--- a/a.go\n+++ b/a.go\n+\tx := 1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewDeclMatcher(tt.extra)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Added(tt.diff); !slices.Equal(got, tt.want) {
+				t.Errorf("Added() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeclMatcherAddedLimit(t *testing.T) {
+	var diff strings.Builder
+	diff.WriteString("--- /dev/null\n+++ b/helpers.go\n")
+	for i := 0; i < maxDeclarations+5; i++ {
+		fmt.Fprintf(&diff, "+func helper%d() {\n", i)
+	}
+	m, err := NewDeclMatcher(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := m.Added(diff.String())
+	if len(got) != maxDeclarations+1 || got[len(got)-1] != "... and 5 more" {
+		t.Errorf("Added() returned %d entries ending %q, want %d and a count of the rest", len(got), got[len(got)-1], maxDeclarations+1)
+	}
+}
+
+func TestNewDeclMatcherInvalidPattern(t *testing.T) {
+	if _, err := NewDeclMatcher([]string{"("}); err == nil {
+		t.Error("NewDeclMatcher accepted an invalid pattern")
+	}
+}
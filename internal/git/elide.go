@@ -0,0 +1,64 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ElideLongLines shortens the changed and context lines of a diff that are
+// longer than maxChars, such as minified code. Text lines keep their first
+// maxChars characters; lines that look binary or encoded are replaced
+// entirely. A maxChars of zero or less returns the diff unchanged.
+func ElideLongLines(diff string, maxChars int) string {
+	if maxChars <= 0 {
+		return diff
+	}
+
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		if len(line) <= maxChars || strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ") {
+			continue
+		}
+		marker, content := line[:1], line[1:]
+		if marker != "+" && marker != "-" && marker != " " {
+			continue
+		}
+		length := utf8.RuneCountInString(content)
+		if length <= maxChars {
+			continue
+		}
+
+		if looksBinary(content) {
+			lines[i] = fmt.Sprintf("%s<binary-like line elided: %d chars>", marker, length)
+			continue
+		}
+		kept := []rune(content)[:maxChars]
+		lines[i] = fmt.Sprintf("%s%s <long line elided: %d chars>", marker, string(kept), length)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// minEncodedRun is the length of a run without whitespace taken as encoded
+// data rather than code
+const minEncodedRun = 200
+
+// looksBinary reports whether a line holds control characters, invalid UTF-8
+// or a long run without whitespace, like base64 data
+func looksBinary(line string) bool {
+	if !utf8.ValidString(line) {
+		return true
+	}
+	for _, r := range line {
+		if unicode.IsControl(r) && r != '\t' {
+			return true
+		}
+	}
+	for _, word := range strings.Fields(line) {
+		if len(word) >= minEncodedRun {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestElideLongLines(t *testing.T) {
+	header := "diff --git a/app.min.js b/app.min.js\n--- a/app.min.js\n+++ b/app.min.js\n@@ -1,2 +1,2 @@\n"
+	minified := strings.Repeat("var a = 1; ", 910)[:10000]
+	encoded := strings.Repeat("QUJD", 2500)
+
+	tests := []struct {
+		name     string
+		diff     string
+		maxChars int
+		want     string
+	}{
+		{"disabled", header + "+" + minified + "\n", 0, header + "+" + minified + "\n"},
+		{"short lines kept", header + "-old\n+new\n", 20, header + "-old\n+new\n"},
+		{"10k-character code line", header + " keep\n+" + minified + "\n", 20,
+			header + " keep\n+" + minified[:20] + " <long line elided: 10000 chars>\n"},
+		{"10k-character encoded line", header + "-" + encoded + "\n", 20,
+			header + "-<binary-like line elided: 10000 chars>\n"},
+		{"control characters", header + "+abc\x01defghijklmnopqrstuvwxyz\n", 10,
+			header + "+<binary-like line elided: 27 chars>\n"},
+		{"multibyte characters counted once", header + "+ééééé\n", 5, header + "+ééééé\n"},
+		{"file headers untouched", "--- a/" + strings.Repeat("dir/", 10) + "f\n", 10, "--- a/" + strings.Repeat("dir/", 10) + "f\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ElideLongLines(tt.diff, tt.maxChars); got != tt.want {
+				t.Errorf("ElideLongLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,40 +1,176 @@
 package git
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os/exec"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/cstobie/ai-commit/internal/tokenizer"
 )
 
 // FileChange represents a single file change in git
 type FileChange struct {
-	Path      string // Full path to the file
+	Path       string // Full path to the file
+	OldPath    string // Previous path for renamed files, empty otherwise
 	ChangeType string // Added, Modified, Deleted, Renamed
 	IsBinary   bool   // Whether the file is binary
 	Diff       string // The diff content for this file
+
+	// Submodule pointer changes carry the old and new commit instead of content
+	IsSubmodule  bool
+	SubmoduleOld string // Empty if the submodule was added
+	SubmoduleNew string // Empty if the submodule was removed
+}
+
+// subprojectCommitRegex matches the old and new commit lines of a submodule diff
+var subprojectCommitRegex = regexp.MustCompile(`(?m)^([+-])Subproject commit ([0-9a-f]+)`)
+
+// SubmoduleSummary describes a submodule pointer change, e.g.
+// "Submodule lib updated from abc1234→def5678"
+func (fc FileChange) SubmoduleSummary() string {
+	switch {
+	case fc.SubmoduleOld == "":
+		return fmt.Sprintf("Submodule %s added at %.7s", fc.Path, fc.SubmoduleNew)
+	case fc.SubmoduleNew == "":
+		return fmt.Sprintf("Submodule %s removed (was %.7s)", fc.Path, fc.SubmoduleOld)
+	default:
+		return fmt.Sprintf("Submodule %s updated from %.7s→%.7s", fc.Path, fc.SubmoduleOld, fc.SubmoduleNew)
+	}
+}
+
+// parseSubmoduleCommits returns the old and new commits of a submodule diff
+// block, and false if the block is not a submodule change
+func parseSubmoduleCommits(block string) (string, string, bool) {
+	matches := subprojectCommitRegex.FindAllStringSubmatch(block, -1)
+	if len(matches) == 0 || !strings.Contains(block, " 160000") {
+		return "", "", false
+	}
+	var oldCommit, newCommit string
+	for _, m := range matches {
+		if m[1] == "-" {
+			oldCommit = m[2]
+		} else {
+			newCommit = m[2]
+		}
+	}
+	return oldCommit, newCommit, true
+}
+
+// execCommand creates every git command run by this package. Tests can
+// replace it to return canned output without a real repository.
+var execCommand = exec.Command
+
+// ErrGitNotFound is returned when the git executable cannot be found
+var ErrGitNotFound = errors.New("git executable not found on PATH; please install git")
+
+// EnsureGitAvailable checks that the git executable is on PATH
+func EnsureGitAvailable() error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return ErrGitNotFound
+	}
+	return nil
+}
+
+// EstimatedTokens estimates the tokens in the file's diff with tok
+func (fc FileChange) EstimatedTokens(tok tokenizer.Tokenizer) int {
+	return tokenizer.Count(tok, fc.Diff)
+}
+
+// GetPrefix returns the path of dir relative to the repository root, with a
+// trailing slash, or an empty string at the root
+func GetPrefix(dir string) (string, error) {
+	cmd := execCommand("git", "-C", dir, "rev-parse", "--show-prefix")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error getting repository prefix: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
 }
 
 // GetRepoRoot finds the root directory of the git repository containing the specified directory
 func GetRepoRoot(dir string) (string, error) {
-	cmd := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel")
+	cmd := execCommand("git", "-C", dir, "rev-parse", "--show-toplevel")
 	output, err := cmd.CombinedOutput()
 
 	if err != nil {
-		if _, err := exec.LookPath("git"); err != nil {
-			return "", fmt.Errorf("git command not found: %w", err)
-		}
 		return "", fmt.Errorf("not a git repository or git error: %w", err)
 	}
 
 	return strings.TrimSpace(string(output)), nil
 }
 
+// GetCurrentBranch returns the short name of the checked out branch, or an
+// empty string when HEAD is detached
+func GetCurrentBranch(repoRoot string) (string, error) {
+	cmd := execCommand("git", "-C", repoRoot, "symbolic-ref", "--short", "-q", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		// symbolic-ref exits with 1 and no output when HEAD is detached
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		return "", fmt.Errorf("error getting current branch: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// DiffOptions controls how the staged diff is produced
+type DiffOptions struct {
+	ContextLines     int  // Lines of context around each change (--unified=N)
+	IgnoreWhitespace bool // Hide whitespace-only changes
+
+	// Sampling of over-budget files in the smart diff
+	HeadLines int // Lines kept from the start of the diff
+	MaxChunks int // Important chunks (functions, imports) kept
+	TailHunks int // Trailing hunks sampled so the end of the file is covered
+	TailLines int // Lines kept after each trailing hunk header
+
+	// Limit the diff to these repo-relative pathspecs; empty means everything
+	Pathspecs []string
+
+	// Shorten changed lines longer than this many characters; 0 disables
+	MaxLineChars int
+
+	// Above this many files the smart diff has no diff content; 0 disables
+	MaxFiles int
+
+	// Leave binary files out of the diff and the smart diff file list
+	IgnoreBinary bool
+
+	// Counts tokens for the smart diff budgets; tokenizer.Default if nil
+	Tokenizer tokenizer.Tokenizer
+}
+
+// withPathspecs appends the pathspec separator and pathspecs to git arguments
+func withPathspecs(args []string, pathspecs []string) []string {
+	if len(pathspecs) == 0 {
+		return args
+	}
+	return append(append(args, "--"), pathspecs...)
+}
+
+// stagedDiffArgs builds the git arguments for the staged diff
+func stagedDiffArgs(repoRoot string, opts DiffOptions) []string {
+	args := []string{"-C", repoRoot, "diff", "--staged", "--patch", fmt.Sprintf("--unified=%d", opts.ContextLines),
+		"--no-color", "--no-ext-diff", "--submodule=short"}
+	if opts.IgnoreWhitespace {
+		args = append(args, "--ignore-space-change", "--ignore-all-space", "--ignore-blank-lines")
+	}
+	return withPathspecs(args, opts.Pathspecs)
+}
+
 // GetStagedDiff returns the diff of all staged changes in the repository
-func GetStagedDiff(repoRoot string) (string, error) {
-	cmd := exec.Command("git", "-C", repoRoot, "diff", "--staged", "--patch", "--unified=0", 
-		"--no-color", "--no-ext-diff", "--ignore-space-change", "--ignore-all-space", "--ignore-blank-lines")
+func GetStagedDiff(repoRoot string, opts DiffOptions) (string, error) {
+	cmd := execCommand("git", stagedDiffArgs(repoRoot, opts)...)
 	output, err := cmd.CombinedOutput()
 
 	if err != nil {
@@ -42,59 +178,113 @@ func GetStagedDiff(repoRoot string) (string, error) {
 	}
 
 	// An empty output is valid - it means no staged changes
-	return string(output), nil
+	if opts.IgnoreBinary {
+		return withoutBinaryBlocks(string(output), opts.MaxLineChars)
+	}
+	return ElideLongLines(string(output), opts.MaxLineChars), nil
 }
 
-// GetStagedDiffFiles parses git diff and returns structured file changes
-func GetStagedDiffFiles(repoRoot string) ([]FileChange, error) {
-	// Get raw diff
-	diffOutput, err := GetStagedDiff(repoRoot)
+// withoutBinaryBlocks drops the blocks of binary files from diff
+func withoutBinaryBlocks(diff string, maxLineChars int) (string, error) {
+	blocks, err := parseDiffBlocks(strings.NewReader(diff), maxLineChars)
+	if err != nil {
+		return "", fmt.Errorf("error parsing staged diff: %w", err)
+	}
+	var sb strings.Builder
+	for _, block := range blocks {
+		if !binaryFileRegex.MatchString(block.text) {
+			sb.WriteString(block.text)
+		}
+	}
+	return sb.String(), nil
+}
+
+// GetStagedDiffStat returns the "git diff --staged --stat" summary of the
+// staged changes, limited to the pathspecs in opts
+func GetStagedDiffStat(repoRoot string, opts DiffOptions) (string, error) {
+	args := withPathspecs([]string{"-C", repoRoot, "diff", "--staged", "--stat", "--no-color", "--no-ext-diff"}, opts.Pathspecs)
+	output, err := execCommand("git", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error getting staged diff stat: %w", err)
+	}
+	stat := strings.TrimRight(string(output), "\n")
+	if opts.IgnoreBinary {
+		stat = withoutBinaryStat(stat)
+	}
+	return stat, nil
+}
+
+// statFilesRegex matches the file count in the last line of a diff stat
+var statFilesRegex = regexp.MustCompile(`^ (\d+) files? changed`)
+
+// withoutBinaryStat drops the lines of binary files from a diff stat and
+// lowers the file count in its summary line to match
+func withoutBinaryStat(stat string) string {
+	lines := strings.Split(stat, "\n")
+	kept := lines[:0:0]
+	for _, line := range lines {
+		if !strings.Contains(line, "| Bin ") {
+			kept = append(kept, line)
+		}
+	}
+	dropped := len(lines) - len(kept)
+	if dropped == 0 {
+		return stat
+	}
+	if len(kept) == 1 {
+		return "" // Only binary files changed
+	}
+	summary := kept[len(kept)-1]
+	if m := statFilesRegex.FindStringSubmatch(summary); m != nil {
+		count, _ := strconv.Atoi(m[1])
+		files := "files"
+		if count-dropped == 1 {
+			files = "file"
+		}
+		kept[len(kept)-1] = fmt.Sprintf(" %d %s changed", count-dropped, files) + summary[len(m[0]):]
+	}
+	return strings.Join(kept, "\n")
+}
+
+// binaryFileRegex detects binary files in a per-file diff block
+var binaryFileRegex = regexp.MustCompile(`(?m)^Binary files`)
+
+// GetStagedDiffFiles parses git diff and returns structured file changes.
+// The diff is streamed and split into per-file blocks as it is read, so the
+// combined diff of a large change is never held in memory on its own.
+func GetStagedDiffFiles(repoRoot string, opts DiffOptions) ([]FileChange, error) {
+	blocks, err := readStagedDiffBlocks(repoRoot, opts)
 	if err != nil {
 		return nil, err
 	}
-	if diffOutput == "" {
+	if len(blocks) == 0 {
 		return []FileChange{}, nil
 	}
 
-	// Get list of changed files
-	fileListCmd := exec.Command("git", "-C", repoRoot, "diff", "--staged", "--name-status")
+	// Get list of changed files, NUL-delimited so paths with spaces or tabs survive
+	fileListArgs := withPathspecs([]string{"-C", repoRoot, "diff", "--staged", "--name-status", "-z"}, opts.Pathspecs)
+	fileListCmd := execCommand("git", fileListArgs...)
 	fileListOutput, err := fileListCmd.CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("error getting staged file list: %w", err)
 	}
 	
-	// Parse file list
-	fileChanges := make([]FileChange, 0)
-	fileListLines := strings.Split(strings.TrimSpace(string(fileListOutput)), "\n")
-	
-	// Regex to match diff headers
-	diffHeaderRegex := regexp.MustCompile(`(?m)^diff --git a/(.+) b/(.+)$`)
-	binaryFileRegex := regexp.MustCompile(`(?m)^Binary files`)
+	entries, err := parseNameStatus(string(fileListOutput))
+	if err != nil {
+		return nil, err
+	}
 	
-	for _, line := range fileListLines {
-		if line == "" {
-			continue
-		}
-		
-		parts := strings.SplitN(line, "\t", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		
-		changeType := parts[0]
-		filePath := parts[1]
-		
-		// Handle rename case
-		if changeType[0] == 'R' {
-			renameParts := strings.SplitN(filePath, "\t", 2)
-			if len(renameParts) == 2 {
-				filePath = renameParts[1] // Use the new path
-			}
-		}
-		
+	// Index the blocks by path, so each file is looked up instead of
+	// rescanning the diff
+	blocksByPath := make(map[string]diffBlock)
+	for _, block := range blocks {
+		blocksByPath[block.newPath] = block
+	}
+	fileChanges := make([]FileChange, 0, len(entries))
+	for _, entry := range entries {
 		// Map git status to change type
 		var changeTypeStr string
-		switch changeType[0] {
+		switch entry.status[0] {
 		case 'A':
 			changeTypeStr = "Added"
 		case 'M':
@@ -107,45 +297,17 @@ func GetStagedDiffFiles(repoRoot string) ([]FileChange, error) {
 			changeTypeStr = "Modified" // Default case
 		}
 		
-		// Corresponding file blocks in the full diff
 		fileChange := FileChange{
-			Path:       filePath,
+			Path:       entry.path,
+			OldPath:    entry.oldPath,
 			ChangeType: changeTypeStr,
-			IsBinary:   false, // Will be set below if binary
-			Diff:       "",    // Will be set below
 		}
 		
-		// Find this file's diff in the full diff output
-		matches := diffHeaderRegex.FindAllStringSubmatchIndex(diffOutput, -1)
-		for i, match := range matches {
-			// Extract file path from the diff header
-			startB := match[4]
-			endB := match[5]
-			
-			filePathInDiff := diffOutput[startB:endB]
-			
-			// If this is our file
-			if filePathInDiff == filePath || strings.HasSuffix(filePathInDiff, "/"+filePath) {
-				// Find the start of this file's diff
-				diffStart := match[0]
-				
-				// Find the end (next file or end of diff)
-				diffEnd := len(diffOutput)
-				if i < len(matches)-1 {
-					diffEnd = matches[i+1][0]
-				}
-				
-				// Extract this file's diff
-				fileDiff := diffOutput[diffStart:diffEnd]
-				
-				// Check if binary
-				if binaryFileRegex.MatchString(fileDiff) {
-					fileChange.IsBinary = true
-				}
-				
-				fileChange.Diff = fileDiff
-				break
-			}
+		// Find this file's block by its exact repo-relative path
+		if block, ok := blocksByPath[entry.path]; ok && (entry.oldPath == "" || block.oldPath == entry.oldPath) {
+			fileChange.Diff = block.text
+			fileChange.IsBinary = binaryFileRegex.MatchString(block.text)
+			fileChange.SubmoduleOld, fileChange.SubmoduleNew, fileChange.IsSubmodule = parseSubmoduleCommits(block.text)
 		}
 		
 		fileChanges = append(fileChanges, fileChange)
@@ -154,9 +316,227 @@ func GetStagedDiffFiles(repoRoot string) ([]FileChange, error) {
 	return fileChanges, nil
 }
 
-// GetStagedFilesList returns a list of staged files with their status
-func GetStagedFilesList(repoRoot string) (string, error) {
-	cmd := exec.Command("git", "-C", repoRoot, "diff", "--staged", "--name-status")
+// withoutBinaryFiles drops binary files from fileChanges
+func withoutBinaryFiles(fileChanges []FileChange) []FileChange {
+	kept := fileChanges[:0:0]
+	for _, fc := range fileChanges {
+		if !fc.IsBinary {
+			kept = append(kept, fc)
+		}
+	}
+	if dropped := len(fileChanges) - len(kept); dropped > 0 {
+		slog.Debug("Left binary files out of the smart diff", "files", dropped)
+	}
+	return kept
+}
+
+// nameStatusEntry is one record from `git diff --name-status -z`
+type nameStatusEntry struct {
+	status  string // Status letter with optional score, e.g. M or R094
+	oldPath string // Source path for renames and copies, empty otherwise
+	path    string // Repo-relative path of the file after the change
+}
+
+// parseNameStatus parses NUL-delimited --name-status output. Renames and
+// copies carry two paths, every other status carries one.
+func parseNameStatus(output string) ([]nameStatusEntry, error) {
+	fields := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+	if len(fields) == 1 && fields[0] == "" {
+		return nil, nil
+	}
+
+	var entries []nameStatusEntry
+	for i := 0; i < len(fields); {
+		status := fields[i]
+		if status == "" {
+			return nil, fmt.Errorf("malformed name-status output: empty status")
+		}
+
+		if status[0] == 'R' || status[0] == 'C' {
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("malformed name-status output: missing paths for %s", status)
+			}
+			entries = append(entries, nameStatusEntry{status: status, oldPath: fields[i+1], path: fields[i+2]})
+			i += 3
+			continue
+		}
+
+		if i+1 >= len(fields) {
+			return nil, fmt.Errorf("malformed name-status output: missing path for %s", status)
+		}
+		entries = append(entries, nameStatusEntry{status: status, path: fields[i+1]})
+		i += 2
+	}
+
+	return entries, nil
+}
+
+// diffBlock is the section of a diff belonging to a single file
+type diffBlock struct {
+	oldPath string // Path on the a/ side
+	newPath string // Path on the b/ side
+	text    string // Full block, starting at the diff --git header
+}
+
+// readStagedDiffBlocks runs the staged diff and splits its output into
+// per-file blocks while reading it, eliding long lines on the way
+func readStagedDiffBlocks(repoRoot string, opts DiffOptions) ([]diffBlock, error) {
+	cmd := execCommand("git", stagedDiffArgs(repoRoot, opts)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("error getting staged diff: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error getting staged diff: %w", err)
+	}
+
+	blocks, readErr := parseDiffBlocks(stdout, opts.MaxLineChars)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("error getting staged diff: %w\n%s", err, stderr.String())
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("error reading staged diff: %w", readErr)
+	}
+	return blocks, nil
+}
+
+// parseDiffBlocks splits a patch into per-file blocks and resolves the old and
+// new path of each. Paths come from the rename and ---/+++ lines when present,
+// which are unambiguous, and from the diff --git header otherwise. A plain
+// unified diff of a single file, without that header, is one block. Lines
+// longer than maxLineChars are elided as by ElideLongLines.
+func parseDiffBlocks(diff io.Reader, maxLineChars int) ([]diffBlock, error) {
+	var blocks []diffBlock
+	reader := bufio.NewReader(diff)
+
+	var current *diffBlock
+	var text strings.Builder
+	inHeader := false
+	plain := false // The block has no diff --git header
+	flush := func() {
+		if current != nil {
+			current.text = text.String()
+			blocks = append(blocks, *current)
+		}
+		text.Reset()
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if line == "" {
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if maxLineChars > 0 && len(line) > maxLineChars {
+			line = ElideLongLines(line, maxLineChars)
+		}
+
+		// Diffs saved on Windows may end lines with CRLF
+		trimmed := strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(trimmed, "diff --git ") {
+			flush()
+			oldPath, newPath := parseDiffHeader(strings.TrimPrefix(trimmed, "diff --git "))
+			current = &diffBlock{oldPath: oldPath, newPath: newPath}
+			inHeader, plain = true, false
+		} else if current == nil && strings.HasPrefix(trimmed, "--- ") {
+			oldPath := stripDiffPrefix(unquotePath(strings.TrimRight(strings.TrimPrefix(trimmed, "--- "), "\t")), "a/")
+			current = &diffBlock{oldPath: oldPath, newPath: oldPath}
+			inHeader, plain = true, true
+		} else if strings.HasPrefix(trimmed, "@@") {
+			// Hunk content may itself look like header lines
+			inHeader = false
+		} else if current != nil && inHeader {
+			// Git pads ---/+++ paths containing spaces with a trailing tab
+			trimmed = strings.TrimRight(trimmed, "\t")
+			switch {
+			case strings.HasPrefix(trimmed, "rename from "):
+				current.oldPath = unquotePath(strings.TrimPrefix(trimmed, "rename from "))
+			case strings.HasPrefix(trimmed, "rename to "):
+				current.newPath = unquotePath(strings.TrimPrefix(trimmed, "rename to "))
+			case strings.HasPrefix(trimmed, "--- a/"), strings.HasPrefix(trimmed, "--- \"a/"):
+				current.oldPath = stripDiffPrefix(unquotePath(strings.TrimPrefix(trimmed, "--- ")), "a/")
+			case strings.HasPrefix(trimmed, "+++ b/"), strings.HasPrefix(trimmed, "+++ \"b/"):
+				current.newPath = stripDiffPrefix(unquotePath(strings.TrimPrefix(trimmed, "+++ ")), "b/")
+			case plain && strings.HasPrefix(trimmed, "+++ ") && trimmed != "+++ /dev/null":
+				// Without the header, paths need not carry the a/ and b/ prefixes
+				current.newPath = stripDiffPrefix(unquotePath(strings.TrimPrefix(trimmed, "+++ ")), "b/")
+			}
+		}
+		if current != nil {
+			text.WriteString(line)
+		}
+	}
+	flush()
+
+	return blocks, nil
+}
+
+// DiffPaths returns the path of each file in a patch, from the new side, or
+// the old side for deleted files. Renames and binary files are included even
+// without ---/+++ lines.
+func DiffPaths(diff string) []string {
+	blocks, _ := parseDiffBlocks(strings.NewReader(diff), 0) // Reading a string cannot fail
+	var paths []string
+	for _, block := range blocks {
+		paths = append(paths, block.newPath)
+	}
+	return paths
+}
+
+// parseDiffHeader extracts paths from the "a/X b/Y" part of a diff --git
+// header. When the paths contain spaces the header is ambiguous, so the
+// split is taken where both halves name the same file.
+func parseDiffHeader(header string) (string, string) {
+	if strings.HasPrefix(header, "\"") {
+		// Quoted paths: "a/x" "b/y"
+		if end := strings.Index(header[1:], "\" "); end >= 0 {
+			oldPath := unquotePath(header[:end+2])
+			newPath := unquotePath(header[end+3:])
+			return stripDiffPrefix(oldPath, "a/"), stripDiffPrefix(newPath, "b/")
+		}
+	}
+
+	// Unchanged path: "a/X b/X" splits exactly in the middle
+	if (len(header)-1)%2 == 0 {
+		mid := (len(header) - 1) / 2
+		oldPath, newPath := header[:mid], header[mid+1:]
+		if strings.HasPrefix(oldPath, "a/") && strings.HasPrefix(newPath, "b/") && oldPath[2:] == newPath[2:] {
+			return oldPath[2:], newPath[2:]
+		}
+	}
+
+	// Fall back to splitting on the last " b/"
+	if idx := strings.LastIndex(header, " b/"); idx >= 0 {
+		return stripDiffPrefix(header[:idx], "a/"), header[idx+3:]
+	}
+	return "", ""
+}
+
+// unquotePath decodes a C-style quoted path as emitted by git for unusual names
+func unquotePath(path string) string {
+	if len(path) >= 2 && strings.HasPrefix(path, "\"") && strings.HasSuffix(path, "\"") {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			return unquoted
+		}
+	}
+	return path
+}
+
+// stripDiffPrefix removes the a/ or b/ prefix git adds to diff paths
+func stripDiffPrefix(path, prefix string) string {
+	return strings.TrimPrefix(path, prefix)
+}
+
+// GetStagedFilesList returns a list of staged files with their status,
+// limited to the given pathspecs if any
+func GetStagedFilesList(repoRoot string, pathspecs []string) (string, error) {
+	cmd := execCommand("git", withPathspecs([]string{"-C", repoRoot, "diff", "--staged", "--name-status"}, pathspecs)...)
 	output, err := cmd.CombinedOutput()
 	
 	if err != nil {
@@ -167,17 +547,233 @@ func GetStagedFilesList(repoRoot string) (string, error) {
 	return string(output), nil
 }
 
+// GetStagedPaths returns the repo-relative paths of the staged files,
+// limited to the given pathspecs if any
+func GetStagedPaths(repoRoot string, pathspecs []string) ([]string, error) {
+	cmd := execCommand("git", withPathspecs([]string{"-C", repoRoot, "diff", "--staged", "--name-only", "-z"}, pathspecs)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting staged paths: %w", err)
+	}
+
+	var paths []string
+	for _, p := range strings.Split(string(output), "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// GetStagedChangeSize returns the number of staged files and of lines they
+// add and remove, limited to the given pathspecs if any. Binary files count
+// as files without lines.
+func GetStagedChangeSize(repoRoot string, pathspecs []string) (files, lines int, err error) {
+	cmd := execCommand("git", withPathspecs([]string{"-C", repoRoot, "diff", "--staged", "--numstat"}, pathspecs)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("error getting staged change size: %w", err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		files++
+		// Binary files show "-" for both counts
+		added, _ := strconv.Atoi(fields[0])
+		removed, _ := strconv.Atoi(fields[1])
+		lines += added + removed
+	}
+	return files, lines, nil
+}
+
+// GetRecentCommitMessages returns the messages of up to n of the latest
+// non-merge commits on HEAD, newest first. A repository without commits has no
+// messages.
+func GetRecentCommitMessages(repoRoot string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if err := execCommand("git", "-C", repoRoot, "rev-parse", "--verify", "--quiet", "HEAD").Run(); err != nil {
+		return nil, nil
+	}
+
+	output, err := execCommand("git", "-C", repoRoot, "log", "-z", "--no-merges", "--format=%B", "-n", strconv.Itoa(n)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting recent commit messages: %w", err)
+	}
+
+	var messages []string
+	for _, message := range strings.Split(string(output), "\x00") {
+		if message = strings.TrimSpace(message); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+// RootDir is the group name for files at the top of the repository
+const RootDir = "root"
+
+// topLevelDir returns the first folder of path, or RootDir for files in the
+// repository root
+func topLevelDir(path string) string {
+	if dir, _, found := strings.Cut(path, "/"); found {
+		return dir
+	}
+	return RootDir
+}
+
+// FileGroup is a cluster of staged changes with the same top-level directory
+// and change type
+type FileGroup struct {
+	Dir        string
+	ChangeType string
+	Files      []FileChange
+}
+
+// Paths returns every path touched by the group, including the old paths of
+// renamed files
+func (g FileGroup) Paths() []string {
+	var paths []string
+	for _, fc := range g.Files {
+		if fc.OldPath != "" {
+			paths = append(paths, fc.OldPath)
+		}
+		paths = append(paths, fc.Path)
+	}
+	return paths
+}
+
+// GroupFileChanges clusters file changes by top-level directory and change
+// type, in order of first appearance
+func GroupFileChanges(fileChanges []FileChange) []FileGroup {
+	var groups []FileGroup
+	index := make(map[[2]string]int)
+	for _, fc := range fileChanges {
+		key := [2]string{topLevelDir(fc.Path), fc.ChangeType}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, FileGroup{Dir: key[0], ChangeType: key[1]})
+		}
+		groups[i].Files = append(groups[i].Files, fc)
+	}
+	return groups
+}
+
+// ResetIndex unstages all changes, leaving the working tree untouched
+func ResetIndex(repoRoot string) error {
+	cmd := execCommand("git", "-C", repoRoot, "reset", "--quiet")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error resetting the index: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// WriteIndexTree saves the index as a tree object and returns its ID, so the
+// staged state can be restored later with StageFromTree
+func WriteIndexTree(repoRoot string) (string, error) {
+	cmd := execCommand("git", "-C", repoRoot, "write-tree")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error saving the index: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// StageFromTree stages paths as they are in tree, including deletions, and
+// leaves the working tree untouched. Paths are matched literally, not as
+// glob patterns.
+func StageFromTree(repoRoot, tree string, paths []string) error {
+	cmd := execCommand("git", withPathspecs([]string{"--literal-pathspecs", "-C", repoRoot, "reset", "--quiet", tree}, paths)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error staging files: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// Commit commits the staged changes, or only pathspecs if any, with the
+// message in messageFile and the extra commit flags. It returns git's output
+// either way, so callers can explain a failure.
+func Commit(repoRoot, messageFile string, flags, pathspecs []string) (string, error) {
+	args := append([]string{"-C", repoRoot, "commit", "-F", messageFile}, flags...)
+	output, err := execCommand("git", withPathspecs(args, pathspecs)...).CombinedOutput()
+	return string(output), err
+}
+
+// Var returns the value of a git logical variable, e.g. GIT_EDITOR, as git
+// resolves it from the environment and configuration
+func Var(repoRoot, name string) (string, error) {
+	output, err := execCommand("git", "-C", repoRoot, "var", name).Output()
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Per-file decisions recorded in a SmartDiffReport
+const (
+	DecisionWhole     = "whole"     // Entire diff included
+	DecisionTruncated = "truncated" // Only the start of the diff included
+	DecisionChunks    = "chunks"    // Start of the diff plus important chunks included
+	DecisionBinary    = "binary"    // Binary file, only mentioned by name
+	DecisionDeleted   = "deleted"   // Deleted file, only mentioned by name
+	DecisionEmpty     = "empty"     // No diff content available
+	DecisionOmitted   = "omitted"   // Over the MaxFiles cap, only mentioned by name
+)
+
+// FileReport describes how one file was treated by the smart diff
+type FileReport struct {
+	Path            string
+	EstimatedTokens int    // Estimated tokens of the file's full diff
+	BudgetTokens    int    // Tokens allotted to the file
+	Decision        string // One of the Decision constants
+}
+
+// SmartDiffReport describes the budget decisions made by PrepareSmartDiffWithReport
+type SmartDiffReport struct {
+	MaxTokens   int // Total token budget
+	OutputChars int // Length of the generated summary
+	Files       []FileReport
+}
+
+// String renders the report as a table for verbose logging
+func (r SmartDiffReport) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Smart diff report (budget %d tokens, output %d characters):\n", r.MaxTokens, r.OutputChars))
+	for _, f := range r.Files {
+		sb.WriteString(fmt.Sprintf("  %-9s est=%-6d budget=%-6d %s\n", f.Decision, f.EstimatedTokens, f.BudgetTokens, f.Path))
+	}
+	return sb.String()
+}
+
 // PrepareSmartDiff creates an intelligent diff summary for large commits
 // It ensures all files are included, with truncation applied based on file importance
-func PrepareSmartDiff(repoRoot string, maxTokens int) (string, error) {
+func PrepareSmartDiff(repoRoot string, maxTokens int, opts DiffOptions) (string, error) {
+	diff, _, err := PrepareSmartDiffWithReport(repoRoot, maxTokens, opts)
+	return diff, err
+}
+
+// PrepareSmartDiffWithReport is PrepareSmartDiff that also returns the
+// per-file budget decisions
+func PrepareSmartDiffWithReport(repoRoot string, maxTokens int, opts DiffOptions) (string, SmartDiffReport, error) {
+	report := SmartDiffReport{MaxTokens: maxTokens}
+
 	// Get all file changes
-	fileChanges, err := GetStagedDiffFiles(repoRoot)
+	fileChanges, err := GetStagedDiffFiles(repoRoot, opts)
 	if err != nil {
-		return "", err
+		return "", report, err
 	}
 	
+	if opts.IgnoreBinary {
+		fileChanges = withoutBinaryFiles(fileChanges)
+	}
 	if len(fileChanges) == 0 {
-		return "", nil
+		return "", report, nil
 	}
 	
 	// Create a summary of all files changed
@@ -223,18 +819,7 @@ func PrepareSmartDiff(repoRoot string, maxTokens int) (string, error) {
 	// Group files by directory to identify patterns
 	dirGroups := make(map[string][]string)
 	for _, fc := range fileChanges {
-		// Extract directory from path
-		pathParts := strings.Split(fc.Path, "/")
-		var dir string
-		if len(pathParts) > 1 {
-			// For multi-level paths, use the first folder
-			dir = pathParts[0]
-		} else {
-			// For files in root, use "root"
-			dir = "root"
-		}
-		
-		// Add to directory group
+		dir := topLevelDir(fc.Path)
 		dirGroups[dir] = append(dirGroups[dir], fc.Path)
 	}
 	
@@ -249,26 +834,62 @@ func PrepareSmartDiff(repoRoot string, maxTokens int) (string, error) {
 	// Add list of all files with change type
 	sb.WriteString("\nChanged files:\n")
 	for _, fc := range fileChanges {
+		if fc.OldPath != "" {
+			sb.WriteString(fmt.Sprintf("- %s: %s -> %s\n", fc.ChangeType, fc.OldPath, fc.Path))
+			continue
+		}
 		sb.WriteString(fmt.Sprintf("- %s: %s\n", fc.ChangeType, fc.Path))
 	}
 	
-	// Budget tokens per file
+	// Submodule bumps have no content diff, so describe them explicitly
+	var submodules []string
+	for _, fc := range fileChanges {
+		if fc.IsSubmodule {
+			submodules = append(submodules, "- "+fc.SubmoduleSummary()+"\n")
+		}
+	}
+	if len(submodules) > 0 {
+		sb.WriteString("\nSubmodules:\n")
+		sb.WriteString(strings.Join(submodules, ""))
+	}
+
+	// With too many files each would get a few useless tokens, so only the
+	// stats and file list are sent
+	if opts.MaxFiles > 0 && len(fileChanges) > opts.MaxFiles {
+		sb.WriteString(fmt.Sprintf("\nThis commit changes more than %d files, too many for detailed analysis, "+
+			"so no diff content is included. Describe the overall change from the statistics and file list.\n", opts.MaxFiles))
+		for _, fc := range fileChanges {
+			report.Files = append(report.Files, FileReport{Path: fc.Path, EstimatedTokens: fc.EstimatedTokens(opts.Tokenizer),
+				Decision: DecisionOmitted})
+		}
+		finalOutput := sb.String()
+		report.OutputChars = len(finalOutput)
+		slog.Debug("Smart diff has too many files for diff content", "files", len(fileChanges), "max_files", opts.MaxFiles)
+		return finalOutput, report, nil
+	}
+
+	// Budget tokens per file, proportionally to each file's size
 	// Reserve ~20% of tokens for the summary and metadata
 	fileDiffBudget := int(float64(maxTokens) * 0.8)
-	tokensPerFile := fileDiffBudget / len(fileChanges)
+	budgets := allocateFileBudgets(fileChanges, fileDiffBudget, opts.Tokenizer)
 	
 	// Log token budget info
-	log.Printf("Smart diff processing: Total tokens=%d, Files=%d, Tokens per file=%d", 
-		maxTokens, len(fileChanges), tokensPerFile)
+	slog.Debug("Smart diff processing", "total_tokens", maxTokens, "files", len(fileChanges),
+		"file_diff_budget", fileDiffBudget)
 	
 	// Add selected diff content for each file
 	sb.WriteString("\nSelected diff content:\n")
 	
 	// Process each file's diff
 	for i, fc := range fileChanges {
+		tokensPerFile := budgets[i]
+		fileReport := FileReport{Path: fc.Path, EstimatedTokens: fc.EstimatedTokens(opts.Tokenizer), BudgetTokens: tokensPerFile}
+
 		// Skip binary files
 		if fc.IsBinary {
 			sb.WriteString(fmt.Sprintf("\n### %s: %s (binary file)\n", fc.ChangeType, fc.Path))
+			fileReport.Decision = DecisionBinary
+			report.Files = append(report.Files, fileReport)
 			continue
 		}
 		
@@ -278,34 +899,32 @@ func PrepareSmartDiff(repoRoot string, maxTokens int) (string, error) {
 		// For deleted files, just note that they were deleted
 		if fc.ChangeType == "Deleted" {
 			sb.WriteString("File was deleted.\n")
+			fileReport.Decision = DecisionDeleted
+			report.Files = append(report.Files, fileReport)
 			continue
 		}
 		
 		// For other files, include a portion of the diff
 		if fc.Diff != "" {
-			// Simple tokenization (we'll estimate 1 token ≈ 4 characters)
-			diffChars := len(fc.Diff)
-			diffTokenEst := diffChars / 4
+			diffTokenEst := fc.EstimatedTokens(opts.Tokenizer)
 			
 			if i < 5 {
 				// Log details for first few files
-				log.Printf("File %d: %s - Est. tokens: %d (budget: %d)", 
-					i+1, fc.Path, diffTokenEst, tokensPerFile)
+				slog.Debug("Smart diff file", "index", i+1, "path", fc.Path,
+					"estimated_tokens", diffTokenEst, "budget", tokensPerFile)
 			}
 			
-			if diffChars > tokensPerFile*4 {
+			if diffTokenEst > tokensPerFile {
 				// Extract a summary portion (start of the diff)
 				diffLines := strings.Split(fc.Diff, "\n")
-				if len(diffLines) > 5 {
-					// Include first 5 lines
-					sb.WriteString(strings.Join(diffLines[:5], "\n"))
+				if len(diffLines) > opts.HeadLines {
+					// Include the first lines
+					sb.WriteString(strings.Join(diffLines[:opts.HeadLines], "\n"))
 					sb.WriteString("\n... (diff truncated) ...\n")
+					fileReport.Decision = DecisionTruncated
 					
 					// Also include snippets of functions or significant changes if present
 					// Look for function definitions or significant patterns
-					funcPattern := regexp.MustCompile(`(?m)^[+-](func|def|class|void|export|function)`)
-					importPattern := regexp.MustCompile(`(?m)^[+-](import|from|require|use|using)`)
-					
 					// Find and include important chunks
 					var importantChunks []string
 					chunkStart := -1
@@ -327,9 +946,10 @@ func PrepareSmartDiff(repoRoot string, maxTokens int) (string, error) {
 						}
 					}
 					
-					// Include up to 3 important chunks
-					maxChunks := 3
-					if len(importantChunks) > 0 {
+					// Include up to MaxChunks important chunks
+					maxChunks := opts.MaxChunks
+					if len(importantChunks) > 0 && maxChunks > 0 {
+						fileReport.Decision = DecisionChunks
 						sb.WriteString("\nImportant changes:\n")
 						for i, chunk := range importantChunks {
 							if i >= maxChunks {
@@ -339,22 +959,128 @@ func PrepareSmartDiff(repoRoot string, maxTokens int) (string, error) {
 							sb.WriteString("\n---\n")
 						}
 					}
+
+					// Sample the last hunks so changes at the end of the file are not lost
+					if tail := sampleTailHunks(diffLines, opts); tail != "" {
+						sb.WriteString("\nEnd of diff:\n")
+						sb.WriteString(tail)
+						sb.WriteString("\n")
+					}
 				} else {
 					// Small diff, include it all
 					sb.WriteString(fc.Diff)
+					fileReport.Decision = DecisionWhole
 				}
 			} else {
 				// Entire diff fits in budget
 				sb.WriteString(fc.Diff)
+				fileReport.Decision = DecisionWhole
 			}
 		} else {
 			sb.WriteString("(No diff content available)\n")
+			fileReport.Decision = DecisionEmpty
 		}
+		report.Files = append(report.Files, fileReport)
 	}
 	
 	// Log summary
 	finalOutput := sb.String()
-	log.Printf("Smart diff processing complete: Generated summary of %d characters", len(finalOutput))
+	report.OutputChars = len(finalOutput)
+	slog.Debug("Smart diff processing complete", "characters", len(finalOutput))
 	
-	return finalOutput, nil
-}
\ No newline at end of file
+	return finalOutput, report, nil
+}
+
+// EstimateFileTokens returns the path and estimated tokens of each file in
+// diff, for callers that have the diff but not a smart diff report
+func EstimateFileTokens(diff string, tok tokenizer.Tokenizer) []FileReport {
+	blocks, _ := parseDiffBlocks(strings.NewReader(diff), 0) // Reading a string cannot fail
+	files := make([]FileReport, 0, len(blocks))
+	for _, block := range blocks {
+		files = append(files, FileReport{Path: block.newPath, EstimatedTokens: tokenizer.Count(tok, block.text)})
+	}
+	return files
+}
+
+// minFileBudget is the floor in tokens for each file that receives a budget,
+// enough for the diff header and a few lines
+const minFileBudget = 20
+
+// allocateFileBudgets splits budget across files in proportion to their
+// estimated size. Binary, deleted and empty files get nothing, every other
+// file gets at least minFileBudget. Files are visited smallest first so that
+// budget a small file does not need flows to the larger ones.
+func allocateFileBudgets(fileChanges []FileChange, budget int, tok tokenizer.Tokenizer) []int {
+	budgets := make([]int, len(fileChanges))
+	sizes := make([]int, len(fileChanges))
+
+	var eligible []int
+	remainingSize := 0
+	for i, fc := range fileChanges {
+		if fc.IsBinary || fc.ChangeType == "Deleted" || fc.Diff == "" {
+			continue
+		}
+		eligible = append(eligible, i)
+		sizes[i] = fc.EstimatedTokens(tok)
+		remainingSize += sizes[i]
+	}
+	if len(eligible) == 0 {
+		return budgets
+	}
+
+	floor := minFileBudget
+	if floor*len(eligible) > budget {
+		floor = max(budget/len(eligible), 1)
+	}
+
+	sort.SliceStable(eligible, func(a, b int) bool {
+		return sizes[eligible[a]] < sizes[eligible[b]]
+	})
+
+	remaining := budget
+	for n, i := range eligible {
+		size := sizes[i]
+		// Files that fit in an even share get everything they need, larger
+		// files split what is left in proportion to their size
+		share := remaining / (len(eligible) - n)
+		if size > share && remainingSize > 0 {
+			share = remaining * size / remainingSize
+		}
+		// Never give a file more than it needs, nor less than the floor
+		allotted := max(min(size, share), floor)
+		budgets[i] = allotted
+		remaining = max(remaining-allotted, 0)
+		remainingSize -= size
+	}
+
+	return budgets
+}
+
+// sampleTailHunks returns the headers of the last TailHunks hunks of a diff,
+// each followed by up to TailLines lines of content. Hunks that start inside
+// the head already shown are skipped.
+func sampleTailHunks(diffLines []string, opts DiffOptions) string {
+	if opts.TailHunks <= 0 {
+		return ""
+	}
+
+	var hunkStarts []int
+	for i, line := range diffLines {
+		if i >= opts.HeadLines && strings.HasPrefix(line, "@@") {
+			hunkStarts = append(hunkStarts, i)
+		}
+	}
+	if len(hunkStarts) > opts.TailHunks {
+		hunkStarts = hunkStarts[len(hunkStarts)-opts.TailHunks:]
+	}
+
+	var samples []string
+	for n, start := range hunkStarts {
+		end := min(start+1+opts.TailLines, len(diffLines))
+		if n+1 < len(hunkStarts) {
+			end = min(end, hunkStarts[n+1])
+		}
+		samples = append(samples, strings.Join(diffLines[start:end], "\n"))
+	}
+	return strings.Join(samples, "\n")
+}
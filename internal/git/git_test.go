@@ -0,0 +1,1038 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/testutil"
+	"github.com/cstobie/ai-commit/internal/tokenizer"
+)
+
+func TestParseNameStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    []nameStatusEntry
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{
+			name:   "modified, added and deleted",
+			output: "M\x00main.go\x00A\x00docs/new file.md\x00D\x00old.txt\x00",
+			want: []nameStatusEntry{
+				{status: "M", path: "main.go"},
+				{status: "A", path: "docs/new file.md"},
+				{status: "D", path: "old.txt"},
+			},
+		},
+		{
+			name:   "rename and copy",
+			output: "R094\x00old name.go\x00new name.go\x00C100\x00a.go\x00b.go\x00",
+			want: []nameStatusEntry{
+				{status: "R094", oldPath: "old name.go", path: "new name.go"},
+				{status: "C100", oldPath: "a.go", path: "b.go"},
+			},
+		},
+		{"path with tab", "M\x00a\tb.go\x00", []nameStatusEntry{{status: "M", path: "a\tb.go"}}, false},
+		{"rename missing path", "R100\x00old.go\x00", nil, true},
+		{"missing path", "M\x00", nil, true},
+		{"empty status", "\x00main.go\x00", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNameStatus(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNameStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNameStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDiffHeader(t *testing.T) {
+	tests := []struct {
+		header  string
+		wantOld string
+		wantNew string
+	}{
+		{"a/main.go b/main.go", "main.go", "main.go"},
+		{"a/dir b/x.go b/dir b/x.go", "dir b/x.go", "dir b/x.go"},
+		{"a/old.go b/new.go", "old.go", "new.go"},
+		{`"a/tab\there.go" "b/tab\there.go"`, "tab\there.go", "tab\there.go"},
+	}
+	for _, tt := range tests {
+		oldPath, newPath := parseDiffHeader(tt.header)
+		if oldPath != tt.wantOld || newPath != tt.wantNew {
+			t.Errorf("parseDiffHeader(%q) = %q, %q, want %q, %q", tt.header, oldPath, newPath, tt.wantOld, tt.wantNew)
+		}
+	}
+}
+
+func TestParseDiffBlocks(t *testing.T) {
+	diff := "diff --git a/old name.go b/new name.go\n" +
+		"similarity index 90%\n" +
+		"rename from old name.go\n" +
+		"rename to new name.go\n" +
+		"--- a/old name.go\t\n" +
+		"+++ b/new name.go\t\n" +
+		"@@ -1 +1 @@\n" +
+		"--- a/fake.go\n" +
+		"+++ b/fake.go\n" +
+		"diff --git a/main.go b/main.go\n" +
+		"--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -1 +1 @@\n" +
+		"-a\n" +
+		"+b\n"
+	blocks, err := parseDiffBlocks(strings.NewReader(diff), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{{"old name.go", "new name.go"}, {"main.go", "main.go"}}
+	if len(blocks) != len(want) {
+		t.Fatalf("got %d blocks, want %d", len(blocks), len(want))
+	}
+	for i, block := range blocks {
+		if block.oldPath != want[i][0] || block.newPath != want[i][1] {
+			t.Errorf("block %d paths = %q, %q, want %q, %q", i, block.oldPath, block.newPath, want[i][0], want[i][1])
+		}
+		if !strings.HasPrefix(block.text, "diff --git ") {
+			t.Errorf("block %d does not start at its header: %q", i, block.text)
+		}
+	}
+}
+
+func TestParseDiffBlocksSplitting(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want []string // Text of each block
+	}{
+		{"empty", "", nil},
+		{
+			name: "text before the first header is dropped",
+			diff: "warning: something\ndiff --git a/a.go b/a.go\n+a\n",
+			want: []string{"diff --git a/a.go b/a.go\n+a\n"},
+		},
+		{
+			name: "each header starts a block",
+			diff: "diff --git a/a.go b/a.go\n@@ -1 +1 @@\n+a\ndiff --git a/b.go b/b.go\n@@ -1 +1 @@\n+b\n",
+			want: []string{"diff --git a/a.go b/a.go\n@@ -1 +1 @@\n+a\n", "diff --git a/b.go b/b.go\n@@ -1 +1 @@\n+b\n"},
+		},
+		{
+			name: "trailing block without a newline",
+			diff: "diff --git a/a.go b/a.go\n+a\ndiff --git a/b.go b/b.go\n@@ -1 +1 @@\n+b\n\\ No newline at end of file",
+			want: []string{"diff --git a/a.go b/a.go\n+a\n", "diff --git a/b.go b/b.go\n@@ -1 +1 @@\n+b\n\\ No newline at end of file"},
+		},
+		{
+			name: "header text inside content",
+			diff: "diff --git a/a.md b/a.md\n@@ -1 +1,2 @@\n+diff --git a/b.go b/b.go\n context diff --git a/c.go b/c.go\n",
+			want: []string{"diff --git a/a.md b/a.md\n@@ -1 +1,2 @@\n+diff --git a/b.go b/b.go\n context diff --git a/c.go b/c.go\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks, err := parseDiffBlocks(strings.NewReader(tt.diff), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, block := range blocks {
+				got = append(got, block.text)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseDiffBlocks() blocks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkParseDiffBlocks compares reading the whole diff into a string
+// before splitting it, as GetStagedDiff did, with splitting it as it streams
+func BenchmarkParseDiffBlocks(b *testing.B) {
+	_, diff := syntheticDiff(5000)
+	b.Run("whole", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			output, err := io.ReadAll(strings.NewReader(diff))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := parseDiffBlocks(strings.NewReader(string(output)), 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("streamed", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := parseDiffBlocks(strings.NewReader(diff), 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestEstimateFileTokens(t *testing.T) {
+	diff := "diff --git a/a.go b/a.go\n+one two\ndiff --git a/old.go b/new.go\nrename from old.go\nrename to new.go\n"
+	got := EstimateFileTokens(diff, tokenizer.Words{})
+	want := []FileReport{
+		{Path: "a.go", EstimatedTokens: tokenizer.Count(tokenizer.Words{}, "diff --git a/a.go b/a.go\n+one two\n")},
+		{Path: "new.go", EstimatedTokens: tokenizer.Count(tokenizer.Words{}, "diff --git a/old.go b/new.go\nrename from old.go\nrename to new.go\n")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EstimateFileTokens() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGetStagedDiffFilesRenameAndSpaces(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{
+		"old name.go": "package x\n\nfunc A() {}\nfunc B() {}\nfunc C() {}\n",
+	})
+	testutil.RunGit(t, repo, "mv", "old name.go", "new name.go")
+	testutil.WriteFile(t, repo, "dir with space/file.go", "package y\n")
+	testutil.RunGit(t, repo, "add", "--all")
+
+	fileChanges, err := GetStagedDiffFiles(repo, DiffOptions{ContextLines: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]FileChange)
+	for _, fc := range fileChanges {
+		got[fc.Path] = fc
+	}
+	if len(got) != 2 {
+		t.Fatalf("got files %v, want the rename and the new file", fileChanges)
+	}
+
+	renamed := got["new name.go"]
+	if renamed.ChangeType != "Renamed" || renamed.OldPath != "old name.go" {
+		t.Errorf("rename = %+v, want Renamed from old name.go", renamed)
+	}
+	if !strings.Contains(renamed.Diff, "rename from old name.go") {
+		t.Errorf("rename has the wrong diff: %q", renamed.Diff)
+	}
+	added := got["dir with space/file.go"]
+	if added.ChangeType != "Added" || !strings.Contains(added.Diff, "+package y") {
+		t.Errorf("added file = %+v, want its own diff", added)
+	}
+}
+
+func TestGetStagedDiffFilesSuffixPaths(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{
+		"config.go":     "package main\n\nvar root = 1\n",
+		"app/config.go": "package app\n\nvar nested = 1\n",
+	})
+	testutil.WriteFile(t, repo, "config.go", "package main\n\nvar root = 2\n")
+	testutil.WriteFile(t, repo, "app/config.go", "package app\n\nvar nested = 2\n")
+	testutil.RunGit(t, repo, "add", "--all")
+
+	fileChanges, err := GetStagedDiffFiles(repo, DiffOptions{ContextLines: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"config.go": "+var root = 2", "app/config.go": "+var nested = 2"}
+	if len(fileChanges) != len(want) {
+		t.Fatalf("got %d files, want %d", len(fileChanges), len(want))
+	}
+	for _, fc := range fileChanges {
+		if !strings.Contains(fc.Diff, want[fc.Path]) || !strings.HasPrefix(fc.Diff, "diff --git a/"+fc.Path+" b/"+fc.Path+"\n") {
+			t.Errorf("%s got the diff of another file:\n%s", fc.Path, fc.Diff)
+		}
+	}
+}
+
+func TestStagedDiffArgsContextLines(t *testing.T) {
+	for _, lines := range []int{0, 3, 10} {
+		args := stagedDiffArgs("/repo", DiffOptions{ContextLines: lines})
+		want := fmt.Sprintf("--unified=%d", lines)
+		if !slices.Contains(args, want) {
+			t.Errorf("stagedDiffArgs with %d context lines = %q, want %s", lines, args, want)
+		}
+	}
+}
+
+func TestGetStagedDiffRunsConfiguredCommand(t *testing.T) {
+	var gotArgs []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		gotArgs = args
+		return exec.Command("true")
+	}
+	t.Cleanup(func() { execCommand = exec.Command })
+
+	if _, err := GetStagedDiff("/repo", DiffOptions{ContextLines: 5, Pathspecs: []string{"src"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"-C", "/repo", "diff", "--staged", "--patch", "--unified=5",
+		"--no-color", "--no-ext-diff", "--submodule=short", "--", "src"}
+	if !slices.Equal(gotArgs, want) {
+		t.Errorf("git args = %q, want %q", gotArgs, want)
+	}
+}
+
+func TestStagedDiffArgsWhitespace(t *testing.T) {
+	whitespaceFlags := []string{"--ignore-space-change", "--ignore-all-space", "--ignore-blank-lines"}
+	for _, ignore := range []bool{true, false} {
+		args := stagedDiffArgs("/repo", DiffOptions{ContextLines: 3, IgnoreWhitespace: ignore})
+		for _, flag := range whitespaceFlags {
+			if slices.Contains(args, flag) != ignore {
+				t.Errorf("stagedDiffArgs with IgnoreWhitespace=%v = %q, %s included = %v", ignore, args, flag, !ignore)
+			}
+		}
+	}
+}
+
+func TestEnsureGitAvailable(t *testing.T) {
+	if err := EnsureGitAvailable(); err != nil {
+		t.Fatalf("EnsureGitAvailable() with git on PATH error = %v", err)
+	}
+	t.Setenv("PATH", t.TempDir())
+	if err := EnsureGitAvailable(); !errors.Is(err, ErrGitNotFound) {
+		t.Errorf("EnsureGitAvailable() without git error = %v, want %v", err, ErrGitNotFound)
+	}
+}
+
+// smartDiffOptions returns the default smart diff settings
+func smartDiffOptions() DiffOptions {
+	return DiffOptions{ContextLines: 3, HeadLines: 5, MaxChunks: 3, TailHunks: 2, TailLines: 4}
+}
+
+// numberedLines returns n lines of the form "<prefix> <i>"
+func numberedLines(prefix string, n int) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "%s %d\n", prefix, i)
+	}
+	return sb.String()
+}
+
+func TestPrepareSmartDiffWithReport(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{"gone.txt": "bye\n"})
+	testutil.WriteFile(t, repo, "small.txt", "one line\n")
+	testutil.WriteFile(t, repo, "large.txt", numberedLines("line", 400))
+	testutil.WriteFile(t, repo, "image.bin", "\x00\x01\x02binary")
+	testutil.RunGit(t, repo, "rm", "--quiet", "gone.txt")
+	testutil.RunGit(t, repo, "add", "--all")
+
+	output, report, err := PrepareSmartDiffWithReport(repo, 200, smartDiffOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"small.txt": DecisionWhole,
+		"large.txt": DecisionTruncated,
+		"image.bin": DecisionBinary,
+		"gone.txt":  DecisionDeleted,
+	}
+	if len(report.Files) != len(want) {
+		t.Fatalf("report has %d files, want %d: %+v", len(report.Files), len(want), report.Files)
+	}
+	for _, f := range report.Files {
+		if f.Decision != want[f.Path] {
+			t.Errorf("%s decision = %s, want %s", f.Path, f.Decision, want[f.Path])
+		}
+		if f.EstimatedTokens == 0 && f.Path == "large.txt" {
+			t.Errorf("%s has no token estimate", f.Path)
+		}
+	}
+	if report.MaxTokens != 200 || report.OutputChars != len(output) {
+		t.Errorf("report totals = %d tokens, %d characters, want 200, %d", report.MaxTokens, report.OutputChars, len(output))
+	}
+	if !strings.Contains(report.String(), "truncated") {
+		t.Errorf("report table has no decisions:\n%s", report)
+	}
+}
+
+func TestPrepareSmartDiffMaxFiles(t *testing.T) {
+	repo := testutil.NewRepo(t, nil)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		testutil.WriteFile(t, repo, name, name+"\n")
+	}
+	testutil.RunGit(t, repo, "add", "--all")
+
+	tests := []struct {
+		name        string
+		maxFiles    int
+		wantContent bool
+	}{
+		{"above the cap", 2, false},
+		{"at the cap", 3, true},
+		{"no cap", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := smartDiffOptions()
+			opts.MaxFiles = tt.maxFiles
+			output, report, err := PrepareSmartDiffWithReport(repo, 1000, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range report.Files {
+				if omitted := f.Decision == DecisionOmitted; omitted == tt.wantContent {
+					t.Errorf("%s decision = %s", f.Path, f.Decision)
+				}
+			}
+			if got := strings.Contains(output, "+a.txt"); got != tt.wantContent {
+				t.Errorf("diff content included = %v, want %v:\n%s", got, tt.wantContent, output)
+			}
+			if got := strings.Contains(output, "too many for detailed analysis"); got == tt.wantContent {
+				t.Errorf("too-large note included = %v, want %v:\n%s", got, !tt.wantContent, output)
+			}
+			if !strings.Contains(output, "- Added: c.txt") {
+				t.Errorf("file list missing:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestSampleTailHunks(t *testing.T) {
+	diffLines := strings.Split("diff --git a/f b/f\n--- a/f\n+++ b/f\n@@ -1 +1 @@\n-a\n+b\n"+
+		"@@ -10,2 +10,2 @@\n-c\n+d\n ctx\n"+
+		"@@ -20,3 +20,3 @@\n-e\n+f\n ctx1\n ctx2\n ctx3", "\n")
+	tests := []struct {
+		name string
+		opts DiffOptions
+		want string
+	}{
+		{"disabled", DiffOptions{HeadLines: 5, TailHunks: 0, TailLines: 4}, ""},
+		{"last hunk", DiffOptions{HeadLines: 5, TailHunks: 1, TailLines: 2}, "@@ -20,3 +20,3 @@\n-e\n+f"},
+		{"stops at next hunk", DiffOptions{HeadLines: 5, TailHunks: 2, TailLines: 4},
+			"@@ -10,2 +10,2 @@\n-c\n+d\n ctx\n@@ -20,3 +20,3 @@\n-e\n+f\n ctx1\n ctx2"},
+		{"skips hunks in the head", DiffOptions{HeadLines: 5, TailHunks: 5, TailLines: 0},
+			"@@ -10,2 +10,2 @@\n@@ -20,3 +20,3 @@"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sampleTailHunks(diffLines, tt.opts); got != tt.want {
+				t.Errorf("sampleTailHunks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareSmartDiffKeepsBothEnds(t *testing.T) {
+	original := numberedLines("line", 600)
+	repo := testutil.NewRepo(t, map[string]string{"big.txt": original})
+	changed := strings.Replace(original, "line 2\n", "first change\n", 1)
+	for i := 20; i < 580; i += 20 {
+		changed = strings.Replace(changed, fmt.Sprintf("line %d\n", i), fmt.Sprintf("middle change %d\n", i), 1)
+	}
+	changed = strings.Replace(changed, "line 598\n", "last change\n", 1)
+	testutil.WriteFile(t, repo, "big.txt", changed)
+	testutil.RunGit(t, repo, "add", "big.txt")
+
+	opts := smartDiffOptions()
+	opts.HeadLines = 10
+	output, _, err := PrepareSmartDiffWithReport(repo, 100, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "+first change") {
+		t.Errorf("smart diff lost the start of the file:\n%s", output)
+	}
+	if !strings.Contains(output, "End of diff:") || !strings.Contains(output, "@@ -595,6 +595,6 @@") || !strings.Contains(output, "-line 598") {
+		t.Errorf("smart diff lost the end of the file:\n%s", output)
+	}
+}
+
+func TestAllocateFileBudgets(t *testing.T) {
+	words := func(n int) string { return strings.Repeat("w ", n) }
+	tests := []struct {
+		name   string
+		files  []FileChange
+		budget int
+		want   []int
+	}{
+		{
+			name:   "everything fits",
+			files:  []FileChange{{ChangeType: "Modified", Diff: words(10)}, {ChangeType: "Modified", Diff: words(30)}},
+			budget: 1000,
+			want:   []int{20, 30},
+		},
+		{
+			name:   "large file gets the rest",
+			files:  []FileChange{{ChangeType: "Modified", Diff: words(500)}, {ChangeType: "Modified", Diff: words(5)}},
+			budget: 200,
+			want:   []int{180, 20},
+		},
+		{
+			name:   "proportional between large files",
+			files:  []FileChange{{ChangeType: "Modified", Diff: words(300)}, {ChangeType: "Modified", Diff: words(150)}},
+			budget: 200,
+			want:   []int{134, 66},
+		},
+		{
+			name: "binary, deleted and empty files get nothing",
+			files: []FileChange{
+				{ChangeType: "Modified", Diff: words(50), IsBinary: true},
+				{ChangeType: "Deleted", Diff: words(50)},
+				{ChangeType: "Modified"},
+				{ChangeType: "Added", Diff: words(500)},
+			},
+			budget: 100,
+			want:   []int{0, 0, 0, 100},
+		},
+		{
+			// Long lines make the first file larger in bytes but smaller in
+			// tokens, so it still goes first and leaves the rest to the other
+			name: "smallest in tokens first",
+			files: []FileChange{
+				{ChangeType: "Modified", Diff: strings.Repeat(strings.Repeat("x", 100)+" ", 10)},
+				{ChangeType: "Modified", Diff: words(100)},
+			},
+			budget: 60,
+			want:   []int{20, 40},
+		},
+		{
+			name:   "floor shrinks to fit the budget",
+			files:  []FileChange{{ChangeType: "Modified", Diff: words(50)}, {ChangeType: "Modified", Diff: words(50)}},
+			budget: 10,
+			want:   []int{5, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allocateFileBudgets(tt.files, tt.budget, tokenizer.Words{}); !slices.Equal(got, tt.want) {
+				t.Errorf("allocateFileBudgets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSubmoduleCommits(t *testing.T) {
+	tests := []struct {
+		name     string
+		block    string
+		wantOld  string
+		wantNew  string
+		wantSubm bool
+	}{
+		{
+			name: "updated",
+			block: "diff --git a/lib b/lib\nindex 1111111..2222222 160000\n--- a/lib\n+++ b/lib\n@@ -1 +1 @@\n" +
+				"-Subproject commit 1111111111111111111111111111111111111111\n+Subproject commit 2222222222222222222222222222222222222222\n",
+			wantOld:  "1111111111111111111111111111111111111111",
+			wantNew:  "2222222222222222222222222222222222222222",
+			wantSubm: true,
+		},
+		{
+			name: "added",
+			block: "diff --git a/lib b/lib\nnew file mode 160000\nindex 0000000..2222222\n--- /dev/null\n+++ b/lib\n@@ -0,0 +1 @@\n" +
+				"+Subproject commit 2222222222222222222222222222222222222222\n",
+			wantNew:  "2222222222222222222222222222222222222222",
+			wantSubm: true,
+		},
+		{
+			name:  "file mentioning subprojects",
+			block: "diff --git a/notes.txt b/notes.txt\nindex 1111111..2222222 100644\n--- a/notes.txt\n+++ b/notes.txt\n@@ -1 +1 @@\n-old\n+Subproject commit abc\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOld, gotNew, gotSubm := parseSubmoduleCommits(tt.block)
+			if gotOld != tt.wantOld || gotNew != tt.wantNew || gotSubm != tt.wantSubm {
+				t.Errorf("parseSubmoduleCommits() = %q, %q, %v, want %q, %q, %v",
+					gotOld, gotNew, gotSubm, tt.wantOld, tt.wantNew, tt.wantSubm)
+			}
+		})
+	}
+}
+
+func TestSubmoduleSummary(t *testing.T) {
+	tests := []struct {
+		change FileChange
+		want   string
+	}{
+		{FileChange{Path: "lib", SubmoduleOld: "1111111aaaa", SubmoduleNew: "2222222bbbb"}, "Submodule lib updated from 1111111→2222222"},
+		{FileChange{Path: "lib", SubmoduleNew: "2222222bbbb"}, "Submodule lib added at 2222222"},
+		{FileChange{Path: "lib", SubmoduleOld: "1111111aaaa"}, "Submodule lib removed (was 1111111)"},
+	}
+	for _, tt := range tests {
+		if got := tt.change.SubmoduleSummary(); got != tt.want {
+			t.Errorf("SubmoduleSummary() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestPrepareSmartDiffSubmodule(t *testing.T) {
+	repo := testutil.NewRepo(t, nil)
+	// A gitlink entry is all the index needs to record a submodule pointer
+	testutil.RunGit(t, repo, "update-index", "--add", "--cacheinfo", "160000,1111111111111111111111111111111111111111,lib")
+	testutil.RunGit(t, repo, "commit", "--quiet", "-m", "add submodule")
+	testutil.RunGit(t, repo, "update-index", "--cacheinfo", "160000,2222222222222222222222222222222222222222,lib")
+
+	files, err := GetStagedDiffFiles(repo, smartDiffOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || !files[0].IsSubmodule {
+		t.Fatalf("GetStagedDiffFiles() = %+v, want one submodule change", files)
+	}
+	output, err := PrepareSmartDiff(repo, 1000, smartDiffOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Submodule lib updated from 1111111→2222222"; !strings.Contains(output, want) {
+		t.Errorf("smart diff has no %q line:\n%s", want, output)
+	}
+}
+
+// stubGit makes execCommand print the output chosen for each git invocation
+// instead of running git
+func stubGit(t testing.TB, output func(args []string) string) {
+	t.Helper()
+	dir := t.TempDir()
+	calls := 0
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		calls++
+		path := filepath.Join(dir, strconv.Itoa(calls))
+		if err := os.WriteFile(path, []byte(output(args)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return exec.Command("cat", path)
+	}
+	t.Cleanup(func() { execCommand = exec.Command })
+}
+
+func TestGetStagedDiffFilesStubbed(t *testing.T) {
+	diff := "diff --git a/old name.go b/new name.go\nsimilarity index 90%\nrename from old name.go\nrename to new name.go\n" +
+		"--- a/old name.go\n+++ b/new name.go\n@@ -1 +1 @@\n-package old\n+package renamed\n" +
+		"diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n"
+	nameStatus := "R090\x00old name.go\x00new name.go\x00M\x00main.go\x00"
+	stubGit(t, func(args []string) string {
+		if slices.Contains(args, "--name-status") {
+			return nameStatus
+		}
+		return diff
+	})
+
+	files, err := GetStagedDiffFiles("/repo", smartDiffOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ path, oldPath, changeType, content string }{
+		{"new name.go", "old name.go", "Renamed", "+package renamed"},
+		{"main.go", "", "Modified", "+new"},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("GetStagedDiffFiles() returned %d files, want %d: %+v", len(files), len(want), files)
+	}
+	for i, w := range want {
+		f := files[i]
+		if f.Path != w.path || f.OldPath != w.oldPath || f.ChangeType != w.changeType {
+			t.Errorf("file %d = %q from %q (%s), want %q from %q (%s)", i, f.Path, f.OldPath, f.ChangeType, w.path, w.oldPath, w.changeType)
+		}
+		if !strings.Contains(f.Diff, w.content) {
+			t.Errorf("%s diff does not hold its own change %q:\n%s", f.Path, w.content, f.Diff)
+		}
+	}
+}
+
+func TestGetStagedDiffFilesStatuses(t *testing.T) {
+	diff := "diff --git a/old.go b/new.go\nsimilarity index 100%\nrename from old.go\nrename to new.go\n" +
+		"diff --git a/gone.go b/gone.go\ndeleted file mode 100644\n--- a/gone.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-package gone\n" +
+		"diff --git a/logo.png b/logo.png\nnew file mode 100644\nBinary files /dev/null and b/logo.png differ\n"
+	nameStatus := "R100\x00old.go\x00new.go\x00D\x00gone.go\x00A\x00logo.png\x00"
+	stubGit(t, func(args []string) string {
+		if slices.Contains(args, "--name-status") {
+			return nameStatus
+		}
+		return diff
+	})
+
+	files, err := GetStagedDiffFiles("/repo", smartDiffOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		path, oldPath, changeType, content string
+		binary                             bool
+	}{
+		{"new.go", "old.go", "Renamed", "rename to new.go", false},
+		{"gone.go", "", "Deleted", "-package gone", false},
+		{"logo.png", "", "Added", "Binary files /dev/null and b/logo.png differ", true},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("GetStagedDiffFiles() returned %d files, want %d: %+v", len(files), len(want), files)
+	}
+	for i, w := range want {
+		f := files[i]
+		if f.Path != w.path || f.OldPath != w.oldPath || f.ChangeType != w.changeType || f.IsBinary != w.binary {
+			t.Errorf("file %d = %q from %q (%s, binary %v), want %q from %q (%s, binary %v)",
+				i, f.Path, f.OldPath, f.ChangeType, f.IsBinary, w.path, w.oldPath, w.changeType, w.binary)
+		}
+		if !strings.Contains(f.Diff, w.content) {
+			t.Errorf("%s diff does not hold %q:\n%s", f.Path, w.content, f.Diff)
+		}
+	}
+}
+
+// syntheticDiff returns the name-status and patch output of a change to n files
+func syntheticDiff(n int) (nameStatus, diff string) {
+	var ns, sb strings.Builder
+	for i := range n {
+		path := fmt.Sprintf("pkg%d/file%d.go", i%20, i)
+		fmt.Fprintf(&ns, "M\x00%s\x00", path)
+		fmt.Fprintf(&sb, "diff --git a/%s b/%s\nindex 1111111..2222222 100644\n--- a/%s\n+++ b/%s\n@@ -1,20 +1,20 @@\n", path, path, path, path)
+		for j := range 20 {
+			fmt.Fprintf(&sb, "-old line %d\n+new line %d\n", j, j)
+		}
+	}
+	return ns.String(), sb.String()
+}
+
+func BenchmarkGetStagedDiffFiles(b *testing.B) {
+	nameStatus, diff := syntheticDiff(500)
+	stubGit(b, func(args []string) string {
+		if slices.Contains(args, "--name-status") {
+			return nameStatus
+		}
+		return diff
+	})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		files, err := GetStagedDiffFiles("/repo", smartDiffOptions())
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(files) != 500 {
+			b.Fatalf("got %d files, want 500", len(files))
+		}
+	}
+}
+
+func TestGetRecentCommitMessages(t *testing.T) {
+	log := "feat: add login\n\nAdds a form.\n\n\x00\nfix: handle empty input\n\n\x00\n"
+	stubGit(t, func(args []string) string {
+		if slices.Contains(args, "log") {
+			return log
+		}
+		return ""
+	})
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{0, nil},
+		{2, []string{"feat: add login\n\nAdds a form.", "fix: handle empty input"}},
+	}
+	for _, tt := range tests {
+		got, err := GetRecentCommitMessages("/repo", tt.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("GetRecentCommitMessages(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestGetRangeDiffStubbed(t *testing.T) {
+	diff := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n"
+	var gotArgs [][]string
+	stubGit(t, func(args []string) string {
+		gotArgs = append(gotArgs, args)
+		if slices.Contains(args, "log") {
+			return "feat: first\n\x00\nfix: second\n\n\x00\n"
+		}
+		return diff
+	})
+
+	opts := smartDiffOptions()
+	opts.Pathspecs = []string{"src"}
+	got, err := GetRangeDiff("/repo", "origin/main", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != diff {
+		t.Errorf("GetRangeDiff() = %q, want %q", got, diff)
+	}
+	if args := gotArgs[0]; !slices.Contains(args, "origin/main...HEAD") || !slices.Equal(args[len(args)-2:], []string{"--", "src"}) {
+		t.Errorf("range diff args = %q, want the symmetric range and pathspecs", args)
+	}
+
+	messages, err := GetRangeCommitMessages("/repo", "origin/main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"feat: first", "fix: second"}; !slices.Equal(messages, want) {
+		t.Errorf("GetRangeCommitMessages() = %q, want %q", messages, want)
+	}
+	if args := gotArgs[1]; !slices.Contains(args, "origin/main..HEAD") || !slices.Contains(args, "--reverse") {
+		t.Errorf("log args = %q, want the range oldest first", args)
+	}
+}
+
+func TestGetStagedDiffStat(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{"main.go": "package main\n"})
+	testutil.WriteFile(t, repo, "main.go", "package main\n\nfunc main() {}\n")
+	testutil.WriteFile(t, repo, "logo.png", "\x00\x01\x02binary")
+	testutil.RunGit(t, repo, "add", "--all")
+
+	tests := []struct {
+		name         string
+		ignoreBinary bool
+		want         []string
+		wantMissing  []string
+	}{
+		{"all files", false, []string{"main.go  |   2 ++", "logo.png | Bin", "2 files changed"}, nil},
+		{"without binary files", true, []string{"main.go  |   2 ++", "1 file changed, 2 insertions(+)"}, []string{"logo.png"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := smartDiffOptions()
+			opts.IgnoreBinary = tt.ignoreBinary
+			stat, err := GetStagedDiffStat(repo, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(stat, want) {
+					t.Errorf("stat does not include %q:\n%s", want, stat)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(stat, missing) {
+					t.Errorf("stat includes %q:\n%s", missing, stat)
+				}
+			}
+		})
+	}
+}
+
+func TestWithoutBinaryStat(t *testing.T) {
+	tests := []struct {
+		name string
+		stat string
+		want string
+	}{
+		{"no binary files", " a.go | 2 +-\n 1 file changed, 1 insertion(+), 1 deletion(-)",
+			" a.go | 2 +-\n 1 file changed, 1 insertion(+), 1 deletion(-)"},
+		{"mixed", " a.go | 2 +-\n b.png | Bin 0 -> 10 bytes\n c.go | 1 +\n 3 files changed, 2 insertions(+), 1 deletion(-)",
+			" a.go | 2 +-\n c.go | 1 +\n 2 files changed, 2 insertions(+), 1 deletion(-)"},
+		{"one text file left", " a.go | 1 +\n b.png | Bin 0 -> 10 bytes\n 2 files changed, 1 insertion(+)",
+			" a.go | 1 +\n 1 file changed, 1 insertion(+)"},
+		{"only binary files", " b.png | Bin 0 -> 10 bytes\n 1 file changed", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withoutBinaryStat(tt.stat); got != tt.want {
+				t.Errorf("withoutBinaryStat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDiffBlocksCRLF(t *testing.T) {
+	diff := strings.ReplaceAll("diff --git a/old.go b/new.go\n"+
+		"similarity index 90%\n"+
+		"rename from old.go\n"+
+		"rename to new.go\n"+
+		"--- a/old.go\n"+
+		"+++ b/new.go\n"+
+		"@@ -1 +1 @@\n"+
+		"-package old\n"+
+		"+package renamed\n"+
+		"diff --git a/docs/read me.md b/docs/read me.md\n"+
+		"--- a/docs/read me.md\t\n"+
+		"+++ b/docs/read me.md\t\n"+
+		"@@ -1 +1 @@\n"+
+		"-a\n"+
+		"+b\n", "\n", "\r\n")
+	stubGit(t, func(args []string) string {
+		if slices.Contains(args, "--name-status") {
+			return "R090\x00old.go\x00new.go\x00M\x00docs/read me.md\x00"
+		}
+		return diff
+	})
+
+	files, err := GetStagedDiffFiles("/repo", smartDiffOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ path, oldPath, content string }{
+		{"new.go", "old.go", "+package renamed\r\n"},
+		{"docs/read me.md", "", "+b\r\n"},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("GetStagedDiffFiles() returned %d files, want %d: %+v", len(files), len(want), files)
+	}
+	for i, w := range want {
+		if files[i].Path != w.path || files[i].OldPath != w.oldPath {
+			t.Errorf("file %d = %q from %q, want %q from %q", i, files[i].Path, files[i].OldPath, w.path, w.oldPath)
+		}
+		if !strings.Contains(files[i].Diff, w.content) || strings.Count(files[i].Diff, "diff --git") != 1 {
+			t.Errorf("%s diff is not its own block:\n%q", files[i].Path, files[i].Diff)
+		}
+	}
+}
+
+func TestIgnoreBinary(t *testing.T) {
+	repo := testutil.NewRepo(t, nil)
+	testutil.WriteFile(t, repo, "main.go", "package main\n")
+	testutil.WriteFile(t, repo, "assets/logo.png", "\x00\x01\x02binary")
+	testutil.WriteFile(t, repo, "assets/icon.png", "\x00\x03\x04binary")
+	testutil.RunGit(t, repo, "add", "--all")
+
+	tests := []struct {
+		name         string
+		ignoreBinary bool
+		wantSmart    []string
+		wantRaw      bool // Whether the raw diff mentions the binary files
+	}{
+		{"binary files mentioned by default", false, []string{"Commit includes 3 files:", "- Binary files: 2", "- Added: assets/logo.png"}, true},
+		{"binary files left out", true, []string{"Commit includes 1 files:", "- Added: 1\n", "- Added: main.go"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := smartDiffOptions()
+			opts.IgnoreBinary = tt.ignoreBinary
+			smart, err := PrepareSmartDiff(repo, 1000, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.wantSmart {
+				if !strings.Contains(smart, want) {
+					t.Errorf("smart diff does not include %q:\n%s", want, smart)
+				}
+			}
+			if got := strings.Contains(smart, ".png"); got != !tt.ignoreBinary {
+				t.Errorf("smart diff mentions binary files = %v:\n%s", got, smart)
+			}
+
+			raw, err := GetStagedDiff(repo, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(raw, "logo.png"); got != tt.wantRaw {
+				t.Errorf("raw diff mentions binary files = %v, want %v:\n%s", got, tt.wantRaw, raw)
+			}
+			if !strings.Contains(raw, "+package main") {
+				t.Errorf("raw diff lost the text file:\n%s", raw)
+			}
+		})
+	}
+}
+
+func TestGetStagedChangeSize(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{"main.go": "package main\n"})
+	testutil.WriteFile(t, repo, "main.go", "package app\n\nfunc main() {}\n")
+	testutil.WriteFile(t, repo, "docs/README.md", "# App\n")
+	testutil.WriteFile(t, repo, "logo.png", "\x00\x01\x02binary")
+	testutil.RunGit(t, repo, "add", "--all")
+
+	tests := []struct {
+		name      string
+		pathspecs []string
+		wantFiles int
+		wantLines int
+	}{
+		{"all files", nil, 3, 5},
+		{"pathspec", []string{"docs"}, 1, 1},
+		{"binary file", []string{"logo.png"}, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, lines, err := GetStagedChangeSize(repo, tt.pathspecs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if files != tt.wantFiles || lines != tt.wantLines {
+				t.Errorf("GetStagedChangeSize() = %d files, %d lines, want %d files, %d lines", files, lines, tt.wantFiles, tt.wantLines)
+			}
+		})
+	}
+}
+
+func TestCommitAndVarStubbed(t *testing.T) {
+	var gotArgs [][]string
+	stubGit(t, func(args []string) string {
+		gotArgs = append(gotArgs, args)
+		if slices.Contains(args, "var") {
+			return "code --wait\n"
+		}
+		return "[main abc1234] feat: add a\n"
+	})
+
+	output, err := Commit("/repo", "msg.txt", []string{"-S", "--date=yesterday"}, []string{"src"})
+	if err != nil || !strings.Contains(output, "feat: add a") {
+		t.Errorf("Commit() = %q, %v", output, err)
+	}
+	want := []string{"-C", "/repo", "commit", "-F", "msg.txt", "-S", "--date=yesterday", "--", "src"}
+	if !slices.Equal(gotArgs[0], want) {
+		t.Errorf("commit args = %q, want %q", gotArgs[0], want)
+	}
+
+	editor, err := Var("/repo", "GIT_EDITOR")
+	if err != nil || editor != "code --wait" {
+		t.Errorf("Var() = %q, %v; want code --wait", editor, err)
+	}
+	if want := []string{"-C", "/repo", "var", "GIT_EDITOR"}; !slices.Equal(gotArgs[1], want) {
+		t.Errorf("var args = %q, want %q", gotArgs[1], want)
+	}
+}
+
+func TestDiffPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want []string
+	}{
+		{
+			name: "modified and deleted",
+			diff: "diff --git a/cmd/main.go b/cmd/main.go\n--- a/cmd/main.go\n+++ b/cmd/main.go\n@@ -1 +1 @@\n-a\n+b\n" +
+				"diff --git a/old.txt b/old.txt\ndeleted file mode 100644\n--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-gone\n",
+			want: []string{"cmd/main.go", "old.txt"},
+		},
+		{
+			name: "hunk lines that look like headers",
+			diff: "diff --git a/notes.md b/notes.md\n--- a/notes.md\n+++ b/notes.md\n@@ -1,2 +1,2 @@\n--- a/fake.md\n+++ b/fake.md\n",
+			want: []string{"notes.md"},
+		},
+		{
+			name: "plain unified diff",
+			diff: "--- a/README.md\r\n+++ b/README.md\r\n@@ -1 +1 @@\r\n-x\r\n+y\r\n",
+			want: []string{"README.md"},
+		},
+		{
+			name: "rename without changes",
+			diff: "diff --git a/old name.go b/new name.go\nsimilarity index 100%\nrename from old name.go\nrename to new name.go\n",
+			want: []string{"new name.go"},
+		},
+		{
+			name: "binary file",
+			diff: "diff --git a/logo.png b/logo.png\nindex 1111111..2222222 100644\nBinary files a/logo.png and b/logo.png differ\n",
+			want: []string{"logo.png"},
+		},
+		{
+			name: "plain deleted file",
+			diff: "--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-gone\n",
+			want: []string{"old.txt"},
+		},
+		{
+			name: "empty",
+			diff: "",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffPaths(tt.diff); !slices.Equal(got, tt.want) {
+				t.Errorf("DiffPaths() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
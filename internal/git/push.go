@@ -0,0 +1,47 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrRejected is returned by Push when the remote has commits the local
+// branch does not, so the push would not be a fast-forward
+var ErrRejected = errors.New("push rejected because the remote has commits that are not local; pull or rebase, then push again")
+
+// GetUpstream returns the upstream of the current branch, e.g. "origin/main",
+// or an empty string if it has none or HEAD is detached
+func GetUpstream(repoRoot string) (string, error) {
+	cmd := execCommand("git", "-C", repoRoot, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		// rev-parse fails with a message rather than a distinct exit code
+		if strings.Contains(string(output), "no upstream") || strings.Contains(string(output), "HEAD does not point to a branch") {
+			return "", nil
+		}
+		return "", fmt.Errorf("error getting upstream: %w\n%s", err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Push pushes HEAD. With remote empty it pushes the current branch to its
+// upstream; otherwise it pushes to branch on remote, or to the branch of the
+// same name if branch is empty. The commit is never undone on failure.
+func Push(repoRoot, remote, branch string) error {
+	args := []string{"-C", repoRoot, "push"}
+	switch {
+	case remote != "" && branch != "":
+		args = append(args, remote, "HEAD:"+branch)
+	case remote != "":
+		args = append(args, remote, "HEAD")
+	}
+	output, err := execCommand("git", args...).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if strings.Contains(string(output), "non-fast-forward") || strings.Contains(string(output), "fetch first") {
+		return fmt.Errorf("%w\n%s", ErrRejected, strings.TrimSpace(string(output)))
+	}
+	return fmt.Errorf("error pushing: %w\n%s", err, strings.TrimSpace(string(output)))
+}
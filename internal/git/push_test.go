@@ -0,0 +1,83 @@
+package git
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestPushArgs(t *testing.T) {
+	tests := []struct {
+		remote, branch string
+		want           []string
+	}{
+		{"", "", []string{"-C", "/repo", "push"}},
+		{"origin", "", []string{"-C", "/repo", "push", "origin", "HEAD"}},
+		{"fork", "release", []string{"-C", "/repo", "push", "fork", "HEAD:release"}},
+	}
+	for _, tt := range tests {
+		var gotArgs []string
+		stubGit(t, func(args []string) string {
+			gotArgs = args
+			return ""
+		})
+		if err := Push("/repo", tt.remote, tt.branch); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(gotArgs, tt.want) {
+			t.Errorf("Push(%q, %q) ran git %q, want %q", tt.remote, tt.branch, gotArgs, tt.want)
+		}
+	}
+}
+
+// newTestRemote returns a clone of a new bare repository whose main branch
+// tracks origin/main, and the bare repository
+func newTestRemote(t *testing.T) (clone, remote string) {
+	t.Helper()
+	source := testutil.NewRepo(t, map[string]string{"a.txt": "a\n"})
+	remote = t.TempDir()
+	testutil.RunGit(t, remote, "clone", "--quiet", "--bare", source, ".")
+	clone = t.TempDir()
+	testutil.RunGit(t, clone, "clone", "--quiet", remote, ".")
+	testutil.RunGit(t, clone, "config", "user.name", "Test")
+	testutil.RunGit(t, clone, "config", "user.email", "test@example.com")
+	return clone, remote
+}
+
+func TestPush(t *testing.T) {
+	clone, remote := newTestRemote(t)
+	if upstream, err := GetUpstream(clone); err != nil || upstream != "origin/main" {
+		t.Fatalf("GetUpstream() = %q, %v, want origin/main", upstream, err)
+	}
+
+	testutil.RunGit(t, clone, "commit", "--quiet", "--allow-empty", "-m", "feat: pushed")
+	if err := Push(clone, "", ""); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if got := testutil.RunGit(t, remote, "log", "-1", "--format=%s", "main"); got != "feat: pushed" {
+		t.Errorf("remote main = %q, want the pushed commit", got)
+	}
+
+	// Diverge from the remote, so the next push is not a fast-forward
+	testutil.RunGit(t, clone, "reset", "--quiet", "--hard", "HEAD~1")
+	testutil.RunGit(t, clone, "commit", "--quiet", "--allow-empty", "-m", "feat: diverged")
+	if err := Push(clone, "", ""); !errors.Is(err, ErrRejected) {
+		t.Errorf("Push() after diverging error = %v, want %v", err, ErrRejected)
+	}
+	if got := testutil.RunGit(t, clone, "log", "-1", "--format=%s"); got != "feat: diverged" {
+		t.Errorf("local HEAD = %q after a rejected push, want the commit kept", got)
+	}
+}
+
+func TestGetUpstreamNone(t *testing.T) {
+	repo := testutil.NewRepo(t, nil)
+	if upstream, err := GetUpstream(repo); err != nil || upstream != "" {
+		t.Errorf("GetUpstream() without upstream = %q, %v, want none", upstream, err)
+	}
+	testutil.RunGit(t, repo, "checkout", "--quiet", "--detach")
+	if upstream, err := GetUpstream(repo); err != nil || upstream != "" {
+		t.Errorf("GetUpstream() on a detached HEAD = %q, %v, want none", upstream, err)
+	}
+}
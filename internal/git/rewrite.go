@@ -0,0 +1,229 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrDirtyWorkingTree is returned when history would be rewritten with
+// uncommitted changes to tracked files
+var ErrDirtyWorkingTree = errors.New("working tree has uncommitted changes; commit or stash them first")
+
+// ErrStagedChanges is returned when amending would pick up staged changes
+var ErrStagedChanges = errors.New("index has staged changes; commit or unstage them first")
+
+// IsWorkingTreeClean reports whether tracked files have no staged or unstaged
+// changes. Untracked files are ignored.
+func IsWorkingTreeClean(repoRoot string) (bool, error) {
+	cmd := execCommand("git", "-C", repoRoot, "status", "--porcelain", "--untracked-files=no")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("error getting working tree status: %w", err)
+	}
+	return strings.TrimSpace(string(output)) == "", nil
+}
+
+// ResolveCommit returns the full hash of the commit named by rev
+func ResolveCommit(repoRoot, rev string) (string, error) {
+	cmd := execCommand("git", "-C", repoRoot, "rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("unknown commit '%s'", rev)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetCommitDiff returns the diff introduced by a commit, against its first
+// parent
+func GetCommitDiff(repoRoot, commit string, opts DiffOptions) (string, error) {
+	args := []string{"-C", repoRoot, "show", "--format=", "--patch", "--first-parent",
+		fmt.Sprintf("--unified=%d", opts.ContextLines), "--no-color", "--no-ext-diff", "--submodule=short"}
+	if opts.IgnoreWhitespace {
+		args = append(args, "--ignore-space-change", "--ignore-all-space", "--ignore-blank-lines")
+	}
+	args = append(args, commit)
+	output, err := execCommand("git", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error getting commit diff: %w", err)
+	}
+	return ElideLongLines(string(output), opts.MaxLineChars), nil
+}
+
+// GetRangeDiff returns the changes on HEAD since it diverged from base, as
+// in "git diff base...HEAD"
+func GetRangeDiff(repoRoot, base string, opts DiffOptions) (string, error) {
+	args := []string{"-C", repoRoot, "diff", fmt.Sprintf("--unified=%d", opts.ContextLines),
+		"--no-color", "--no-ext-diff", "--submodule=short"}
+	if opts.IgnoreWhitespace {
+		args = append(args, "--ignore-space-change", "--ignore-all-space", "--ignore-blank-lines")
+	}
+	args = append(args, base+"...HEAD")
+	output, err := execCommand("git", withPathspecs(args, opts.Pathspecs)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error getting range diff: %w\n%s", err, output)
+	}
+	return ElideLongLines(string(output), opts.MaxLineChars), nil
+}
+
+// GetRangeCommitMessages returns the messages of the non-merge commits on
+// HEAD that are not on base, oldest first
+func GetRangeCommitMessages(repoRoot, base string) ([]string, error) {
+	output, err := execCommand("git", "-C", repoRoot, "log", "-z", "--reverse", "--no-merges", "--format=%B", base+"..HEAD").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting commit messages: %w", err)
+	}
+
+	var messages []string
+	for _, message := range strings.Split(string(output), "\x00") {
+		if message = strings.TrimSpace(message); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+// GetCommitMessage returns the full message of a commit
+func GetCommitMessage(repoRoot, commit string) (string, error) {
+	output, err := execCommand("git", "-C", repoRoot, "show", "-s", "--format=%B", commit).Output()
+	if err != nil {
+		return "", fmt.Errorf("error getting commit message: %w", err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// RewordCommit replaces the message of commit, an ancestor of HEAD or HEAD
+// itself, and recreates the commits after it on top. Trees and authors are
+// kept, so the working tree and index are untouched. The commits after it must
+// not be merges. It returns the new HEAD.
+func RewordCommit(repoRoot, commit, message string) (string, error) {
+	return RewordCommits(repoRoot, map[string]string{commit: message})
+}
+
+// RewordCommits is RewordCommit for several commits at once, mapping full
+// commit hashes to their new messages. The commits from the oldest of them to
+// HEAD must not be merges.
+func RewordCommits(repoRoot string, messages map[string]string) (string, error) {
+	head, err := ResolveCommit(repoRoot, "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	for commit := range messages {
+		if err := execCommand("git", "-C", repoRoot, "merge-base", "--is-ancestor", commit, head).Run(); err != nil {
+			return "", fmt.Errorf("commit %.7s is not an ancestor of HEAD", commit)
+		}
+	}
+
+	// Walk back from HEAD along single-parent commits until every commit to
+	// reword was seen; the last one is the oldest
+	var chain []string
+	remaining := len(messages)
+	current := head
+	for {
+		chain = append(chain, current)
+		if _, ok := messages[current]; ok {
+			if remaining--; remaining == 0 {
+				break
+			}
+		}
+		parents, err := commitParents(repoRoot, current)
+		if err != nil {
+			return "", err
+		}
+		if len(parents) != 1 {
+			return "", fmt.Errorf("commit %.7s is not an ancestor of HEAD through single-parent commits; rewrite it with git rebase instead", current)
+		}
+		current = parents[0]
+	}
+
+	oldest := chain[len(chain)-1]
+	parents, err := commitParents(repoRoot, oldest)
+	if err != nil {
+		return "", err
+	}
+	var rewritten string
+	for i := len(chain) - 1; i >= 0; i-- {
+		message, ok := messages[chain[i]]
+		if !ok {
+			if message, err = GetCommitMessage(repoRoot, chain[i]); err != nil {
+				return "", err
+			}
+		}
+		if i < len(chain)-1 {
+			parents = []string{rewritten}
+		}
+		if rewritten, err = recreateCommit(repoRoot, chain[i], message, parents); err != nil {
+			return "", err
+		}
+	}
+
+	// Only move HEAD if nobody else moved it in the meantime
+	cmd := execCommand("git", "-C", repoRoot, "update-ref", "-m", "ai-commit: reword "+oldest[:7], "HEAD", rewritten, head)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("error updating HEAD: %w\n%s", err, output)
+	}
+	return rewritten, nil
+}
+
+// GetRangeCommits returns the full hashes of the non-merge commits in a
+// revision range such as A..B, oldest first
+func GetRangeCommits(repoRoot, revRange string) ([]string, error) {
+	output, err := execCommand("git", "-C", repoRoot, "rev-list", "--reverse", "--no-merges", "--end-of-options", revRange).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("error listing commits in %s: %w\n%s", revRange, err, strings.TrimSpace(string(output)))
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// AmendMessage replaces the message of HEAD, keeping its tree and author. Any
+// staged changes are left out of the amended commit.
+func AmendMessage(repoRoot, message string) error {
+	cmd := execCommand("git", "-C", repoRoot, "commit", "--amend", "--only", "--allow-empty", "--quiet", "-F", "-")
+	cmd.Stdin = strings.NewReader(message + "\n")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error amending commit: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// commitParents returns the parent hashes of a commit
+func commitParents(repoRoot, commit string) ([]string, error) {
+	output, err := execCommand("git", "-C", repoRoot, "rev-list", "--parents", "-n", "1", commit).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error getting parents of %.7s: %w", commit, err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("error getting parents of %.7s: no output", commit)
+	}
+	return fields[1:], nil
+}
+
+// recreateCommit writes a commit with the tree and author of original, the
+// given message and parents, and returns its hash
+func recreateCommit(repoRoot, original, message string, parents []string) (string, error) {
+	author, err := execCommand("git", "-C", repoRoot, "show", "-s", "--format=%an%x00%ae%x00%ad", "--date=raw", original).Output()
+	if err != nil {
+		return "", fmt.Errorf("error reading author of %.7s: %w", original, err)
+	}
+	fields := strings.Split(strings.TrimRight(string(author), "\n"), "\x00")
+	if len(fields) != 3 {
+		return "", fmt.Errorf("error reading author of %.7s: unexpected output", original)
+	}
+
+	args := []string{"-C", repoRoot, "commit-tree", original + "^{tree}"}
+	for _, parent := range parents {
+		args = append(args, "-p", parent)
+	}
+	cmd := execCommand("git", args...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+fields[0], "GIT_AUTHOR_EMAIL="+fields[1], "GIT_AUTHOR_DATE="+fields[2])
+	cmd.Stdin = strings.NewReader(message + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error recreating commit %.7s: %w", original, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,87 @@
+package git
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+// newHistory returns a repository with an initial commit followed by one
+// commit per message, and the hashes of those commits, oldest first
+func newHistory(t *testing.T, messages ...string) (string, []string) {
+	t.Helper()
+	repo := testutil.NewRepo(t, nil)
+	var commits []string
+	for i, message := range messages {
+		testutil.WriteFile(t, repo, "file.txt", strings.Repeat("line\n", i+1))
+		testutil.RunGit(t, repo, "add", "file.txt")
+		testutil.RunGit(t, repo, "commit", "--quiet", "-m", message)
+		commits = append(commits, testutil.RunGit(t, repo, "rev-parse", "HEAD"))
+	}
+	return repo, commits
+}
+
+func TestGetRangeCommits(t *testing.T) {
+	repo, commits := newHistory(t, "one", "two", "three")
+	tests := []struct {
+		revRange string
+		want     []string
+		wantErr  bool
+	}{
+		{"HEAD~2..HEAD", commits[1:], false},
+		{"HEAD~3..HEAD", commits, false},
+		{"HEAD..HEAD", nil, false},
+		{"no-such-branch..HEAD", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.revRange, func(t *testing.T) {
+			got, err := GetRangeCommits(repo, tt.revRange)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetRangeCommits(%q) error = %v, wantErr %v", tt.revRange, err, tt.wantErr)
+			}
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRangeCommits(%q) = %v, want %v", tt.revRange, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewordCommits(t *testing.T) {
+	tests := []struct {
+		name     string
+		reworded []int // Indexes of the commits to reword
+		want     []string
+	}{
+		{"latest", []int{2}, []string{"three!", "two", "one"}},
+		{"oldest", []int{0}, []string{"three", "two", "one!"}},
+		{"two of three", []int{0, 2}, []string{"three!", "two", "one!"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, commits := newHistory(t, "one", "two", "three")
+			tree := testutil.RunGit(t, repo, "rev-parse", "HEAD^{tree}")
+			messages := make(map[string]string)
+			for _, i := range tt.reworded {
+				messages[commits[i]] = testutil.RunGit(t, repo, "log", "-1", "--format=%s", commits[i]) + "!"
+			}
+			head, err := RewordCommits(repo, messages)
+			if err != nil {
+				t.Fatalf("RewordCommits error = %v", err)
+			}
+			if got := testutil.RunGit(t, repo, "rev-parse", "HEAD"); got != head {
+				t.Errorf("HEAD = %s, want %s", got, head)
+			}
+			if got := testutil.RunGit(t, repo, "rev-parse", "HEAD^{tree}"); got != tree {
+				t.Errorf("tree changed from %s to %s", tree, got)
+			}
+			if got := strings.Split(testutil.RunGit(t, repo, "log", "-3", "--format=%s"), "\n"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("history = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Operations that can be in progress in a repository
+const (
+	OperationMerge      = "merge"
+	OperationRebase     = "rebase"
+	OperationCherryPick = "cherry-pick"
+	OperationRevert     = "revert"
+)
+
+// RepoState describes an operation in progress and where HEAD points
+type RepoState struct {
+	Operation    string // One of the Operation constants, or empty if none
+	Detached     bool   // HEAD is not on a branch
+	MergeSubject string // First line of git's prepared merge message, during a merge
+}
+
+// GetRepoState detects a merge, rebase, cherry-pick or revert in progress
+// from the marker files in the git directory, and whether HEAD is detached
+func GetRepoState(repoRoot string) (RepoState, error) {
+	output, err := execCommand("git", "-C", repoRoot, "rev-parse", "--absolute-git-dir").Output()
+	if err != nil {
+		return RepoState{}, fmt.Errorf("error finding git directory: %w", err)
+	}
+	state := repoStateFromGitDir(strings.TrimSpace(string(output)))
+
+	// A rebase detaches HEAD by design, so only report it otherwise
+	if state.Operation != OperationRebase {
+		branch, err := GetCurrentBranch(repoRoot)
+		if err != nil {
+			return RepoState{}, err
+		}
+		state.Detached = branch == ""
+	}
+	return state, nil
+}
+
+// repoStateFromGitDir reads the operation in progress from gitDir
+func repoStateFromGitDir(gitDir string) RepoState {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(gitDir, name))
+		return err == nil
+	}
+
+	var state RepoState
+	switch {
+	case exists("rebase-merge") || exists("rebase-apply"):
+		state.Operation = OperationRebase
+	case exists("MERGE_HEAD"):
+		state.Operation = OperationMerge
+		state.MergeSubject = mergeSubject(filepath.Join(gitDir, "MERGE_MSG"))
+	case exists("CHERRY_PICK_HEAD"):
+		state.Operation = OperationCherryPick
+	case exists("REVERT_HEAD"):
+		state.Operation = OperationRevert
+	}
+	return state
+}
+
+// mergeSubject returns the first line of a prepared merge message that is
+// not a comment, or an empty string
+func mergeSubject(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
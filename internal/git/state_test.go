@@ -0,0 +1,66 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/testutil"
+)
+
+func TestRepoStateFromGitDir(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string // Paths in the git directory; a trailing slash makes a folder
+		want  RepoState
+	}{
+		{"clean", nil, RepoState{}},
+		{"merge", map[string]string{"MERGE_HEAD": "abc\n", "MERGE_MSG": "# Conflicts:\n\nMerge branch 'feature'\n\n# comment\n"},
+			RepoState{Operation: OperationMerge, MergeSubject: "Merge branch 'feature'"}},
+		{"merge without message", map[string]string{"MERGE_HEAD": "abc\n"}, RepoState{Operation: OperationMerge}},
+		{"interactive rebase", map[string]string{"rebase-merge/": ""}, RepoState{Operation: OperationRebase}},
+		{"am-style rebase", map[string]string{"rebase-apply/": ""}, RepoState{Operation: OperationRebase}},
+		{"cherry-pick", map[string]string{"CHERRY_PICK_HEAD": "abc\n"}, RepoState{Operation: OperationCherryPick}},
+		{"revert", map[string]string{"REVERT_HEAD": "abc\n"}, RepoState{Operation: OperationRevert}},
+		{"rebase wins over merge", map[string]string{"rebase-merge/": "", "MERGE_HEAD": "abc\n"}, RepoState{Operation: OperationRebase}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitDir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(gitDir, name)
+				var err error
+				if name[len(name)-1] == '/' {
+					err = os.Mkdir(path, 0o755)
+				} else {
+					err = os.WriteFile(path, []byte(content), 0o644)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := repoStateFromGitDir(gitDir); got != tt.want {
+				t.Errorf("repoStateFromGitDir() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRepoStateDetached(t *testing.T) {
+	repo := testutil.NewRepo(t, map[string]string{"a.txt": "a\n"})
+	state, err := GetRepoState(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Detached {
+		t.Error("GetRepoState() reports a detached HEAD on a branch")
+	}
+
+	testutil.RunGit(t, repo, "checkout", "--quiet", "--detach")
+	if state, err = GetRepoState(repo); err != nil {
+		t.Fatal(err)
+	}
+	if !state.Detached || state.Operation != "" {
+		t.Errorf("GetRepoState() after detaching = %+v, want a detached HEAD and no operation", state)
+	}
+}
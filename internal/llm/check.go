@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// CheckConnection makes a cheap authenticated request to the provider's models
+// list to verify the endpoint is reachable and accepts the API key. Non-2xx
+// responses are returned as *APIError.
+func CheckConnection(ctx context.Context, opts Options) error {
+	provider, err := newProvider(opts)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", provider.modelsURL(), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	setExtraHeaders(req, opts.Headers)
+	provider.setHeaders(req)
+
+	client, err := newHTTPClient(opts.Proxy)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("request timed out: %w", ctx.Err())
+		}
+		return fmt.Errorf("error executing request: %s", redactSecrets(err.Error(), opts.APIKey))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		responseBody := new(bytes.Buffer)
+		_, _ = responseBody.ReadFrom(resp.Body)
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Body:       redactSecrets(responseBody.String(), opts.APIKey),
+		}
+	}
+	return nil
+}
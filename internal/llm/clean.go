@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// preambleLineRegex matches a chatty first line introducing the message,
+// e.g. "Sure! Here is your commit message:"
+var preambleLineRegex = regexp.MustCompile(`(?i)^(?:(?:sure|certainly|okay|ok)\b[,!.]?\s*)?(?:here(?:'s| is| are)|below is)\b.*:$`)
+
+// labelPrefixRegex matches a label in front of the message on the same line,
+// e.g. "Commit message: fix: ..."
+var labelPrefixRegex = regexp.MustCompile(`(?i)^(?:suggested |proposed |generated )?commit message:\s*`)
+
+// quotePairs maps opening quotes that may wrap the whole message to their
+// closing counterparts
+var quotePairs = map[string]string{`"`: `"`, `'`: `'`, "`": "`", "“": "”"}
+
+// CleanMessage removes wrapping added by chatty models: a preamble line or
+// label, code fences around the whole message, and surrounding quotes.
+// Backticks and fences inside the message are left alone.
+func CleanMessage(message string) string {
+	message = strings.TrimSpace(message)
+	for {
+		cleaned := stripWrapping(message)
+		if cleaned == message {
+			return message
+		}
+		message = cleaned
+	}
+}
+
+// stripWrapping removes one layer of wrapping from message
+func stripWrapping(message string) string {
+	first, rest, multiline := strings.Cut(message, "\n")
+	if multiline && preambleLineRegex.MatchString(strings.TrimSpace(first)) {
+		return strings.TrimSpace(rest)
+	}
+	if loc := labelPrefixRegex.FindStringIndex(message); loc != nil {
+		return strings.TrimSpace(message[loc[1]:])
+	}
+
+	// A fence must both open and close the message; the opening line may
+	// name a language such as ```text
+	if multiline && strings.HasPrefix(first, "```") && strings.HasSuffix(rest, "```") {
+		return strings.TrimSpace(strings.TrimSuffix(rest, "```"))
+	}
+
+	// Quotes are only removed if they do not also appear inside the message
+	for open, closing := range quotePairs {
+		inner, ok := strings.CutPrefix(message, open)
+		if !ok {
+			continue
+		}
+		inner, ok = strings.CutSuffix(inner, closing)
+		if ok && !strings.Contains(inner, open) && !strings.Contains(inner, closing) {
+			return strings.TrimSpace(inner)
+		}
+	}
+
+	return message
+}
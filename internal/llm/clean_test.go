@@ -0,0 +1,35 @@
+package llm
+
+import "testing"
+
+func TestCleanMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"plain", "feat: add login", "feat: add login"},
+		{"fenced", "```\nfeat: add login\n```", "feat: add login"},
+		{"fenced with language", "```text\nfeat: add login\n\nAdds a form.\n```", "feat: add login\n\nAdds a form."},
+		{"preamble", "Here is your commit message:\nfeat: add login", "feat: add login"},
+		{"chatty preamble and fence", "Sure! Here's the commit message:\n\n```\nfix: handle nil\n```", "fix: handle nil"},
+		{"label", "Commit message: fix: handle nil", "fix: handle nil"},
+		{"double quotes", `"feat: add login"`, "feat: add login"},
+		{"curly quotes", "“feat: add login”", "feat: add login"},
+		{"backtick span kept", "fix: handle nil in `Parse`", "fix: handle nil in `Parse`"},
+		{"inner quotes kept", `"fix: quote "name" field"`, `"fix: quote "name" field"`},
+		{
+			name:    "fence inside body kept",
+			message: "docs: add example\n\nUsage:\n```\nai-commit gen\n```",
+			want:    "docs: add example\n\nUsage:\n```\nai-commit gen\n```",
+		},
+		{"colon subject is not a preamble", "fix: handle nil\n\nHere is why:", "fix: handle nil\n\nHere is why:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CleanMessage(tt.message); got != tt.want {
+				t.Errorf("CleanMessage(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
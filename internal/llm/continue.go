@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// continuePrompt asks for the rest of a message cut off by the output limit
+const continuePrompt = "Your commit message was cut off by the output limit. Continue it exactly where it " +
+	"stopped. Output only the remaining text, without repeating anything already written."
+
+// Models often restate the end of the cut-off text before continuing it, so
+// the seam is searched for the last seamProbeChars characters, and failing
+// that for a suffix-prefix overlap of at least minSeamOverlap characters.
+// Shorter overlaps are too likely to be coincidence, like a shared "the".
+const (
+	seamProbeChars = 40
+	minSeamOverlap = 8
+)
+
+// continueTruncated requests the rest of reply while it was cut off by
+// MaxOutputTokens, up to MaxContinuations times, and returns the stitched
+// content with the usage summed over all requests. A failed continuation
+// keeps the content so far.
+func continueTruncated(ctx context.Context, opts Options, messages []OpenRouterMessage, reply chatReply) (string, *Usage) {
+	content, usage := reply.Content, reply.Usage
+	for i := 0; reply.FinishReason == "length" && i < opts.MaxContinuations; i++ {
+		slog.Info("Message was cut off by the output limit, requesting the rest",
+			"continuation", i+1, "max_output_tokens", opts.MaxOutputTokens)
+		messages = append(messages,
+			OpenRouterMessage{Role: "assistant", Content: reply.Content},
+			OpenRouterMessage{Role: "user", Content: continuePrompt})
+
+		var err error
+		if reply, err = sendChat(ctx, opts, messages, nil); err != nil {
+			slog.Warn("Continuation request failed, keeping the cut-off message", "error", err)
+			return content, usage
+		}
+		content = stitch(content, reply.Content)
+		usage = AddUsage(usage, reply.Usage)
+	}
+	if reply.FinishReason == "length" {
+		slog.Warn("Message was cut off by the output limit; increase AICOMMIT_MAX_OUTPUT_TOKENS",
+			"max_output_tokens", opts.MaxOutputTokens)
+	}
+	return content, usage
+}
+
+// stitch appends next to content, dropping the part of next that repeats the
+// end of content
+func stitch(content, next string) string {
+	probe := content[max(len(content)-seamProbeChars, 0):]
+	if len(probe) == seamProbeChars {
+		if i := strings.Index(next, probe); i >= 0 {
+			return content + next[i+len(probe):]
+		}
+	}
+	for n := min(len(content), len(next)); n >= minSeamOverlap; n-- {
+		if strings.HasSuffix(content, next[:n]) {
+			return content + next[n:]
+		}
+	}
+	return content + next
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStitch(t *testing.T) {
+	tests := []struct {
+		name, content, next, want string
+	}{
+		{name: "no overlap", content: "feat: add login", next: " form", want: "feat: add login form"},
+		{
+			name:    "restated end",
+			content: "feat(auth): add the login form with remember-me support",
+			next:    "form with remember-me support\n\nStores a signed cookie.",
+			want:    "feat(auth): add the login form with remember-me support\n\nStores a signed cookie.",
+		},
+		{
+			name:    "probe found later in the continuation",
+			content: "feat(auth): add the login form with remember-me support and a",
+			next:    "Sure: login form with remember-me support and a logout link",
+			want:    "feat(auth): add the login form with remember-me support and a logout link",
+		},
+		{name: "short overlap is coincidence", content: "fix the", next: "the parser", want: "fix thethe parser"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stitch(tt.content, tt.next); got != tt.want {
+				t.Errorf("stitch(%q, %q) = %q, want %q", tt.content, tt.next, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddUsage(t *testing.T) {
+	a := &Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, Cost: 0.5}
+	b := &Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3, Cost: 0.25}
+	if got := AddUsage(a, b); *got != (Usage{PromptTokens: 11, CompletionTokens: 7, TotalTokens: 18, Cost: 0.75}) {
+		t.Errorf("AddUsage = %+v", *got)
+	}
+	if AddUsage(nil, b) != b || AddUsage(a, nil) != a || AddUsage(nil, nil) != nil {
+		t.Error("AddUsage should return the other report when one is nil")
+	}
+}
+
+// truncatingServer replies with the given contents in turn, finishing all
+// but the last with finish_reason "length", and counts the requests
+func truncatingServer(t *testing.T, contents ...string) (*httptest.Server, *int) {
+	t.Helper()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := min(requests, len(contents)-1)
+		requests++
+		finish := "length"
+		if i == len(contents)-1 {
+			finish = "stop"
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{
+				"message":       map[string]string{"role": "assistant", "content": contents[i]},
+				"finish_reason": finish,
+			}},
+			"usage": map[string]int{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestGenerateCommitMessageContinues(t *testing.T) {
+	server, requests := truncatingServer(t, "feat: add the login form\n\nStores a signed", " cookie for remember-me.")
+	opts := Options{BaseURL: server.URL, MaxInputTokens: 1000, MaxOutputTokens: 10, MaxContinuations: 2}
+
+	message, usage, err := GenerateCommitMessage(context.Background(), opts, "Describe this change")
+	if err != nil {
+		t.Fatalf("GenerateCommitMessage error = %v", err)
+	}
+	if want := "feat: add the login form\n\nStores a signed cookie for remember-me."; message != want {
+		t.Errorf("message = %q, want %q", message, want)
+	}
+	if *requests != 2 || usage == nil || usage.TotalTokens != 30 {
+		t.Errorf("got %d requests and usage %+v, want 2 requests and 30 total tokens", *requests, usage)
+	}
+}
+
+func TestGenerateCommitMessageWithoutContinuations(t *testing.T) {
+	server, requests := truncatingServer(t, "feat: add the login", " form")
+	opts := Options{BaseURL: server.URL, MaxInputTokens: 1000, MaxOutputTokens: 10}
+
+	message, _, err := GenerateCommitMessage(context.Background(), opts, "Describe this change")
+	if err != nil {
+		t.Fatalf("GenerateCommitMessage error = %v", err)
+	}
+	if message != "feat: add the login" || *requests != 1 {
+		t.Errorf("got %q after %d requests, want the cut-off message after 1", message, *requests)
+	}
+}
+
+func TestGenerateStructuredCommitContinues(t *testing.T) {
+	server, requests := truncatingServer(t, `{"type": "feat", "scope": "auth", "subj`, `ect": "add the login form", "body": ""}`)
+	opts := Options{BaseURL: server.URL, MaxInputTokens: 1000, MaxOutputTokens: 10, MaxContinuations: 1}
+
+	commit, usage, err := GenerateStructuredCommit(context.Background(), opts, "Describe this change")
+	if err != nil {
+		t.Fatalf("GenerateStructuredCommit error = %v", err)
+	}
+	if got := commit.Format(); got != "feat(auth): add the login form" {
+		t.Errorf("message = %q", got)
+	}
+	if *requests != 2 || usage == nil || usage.TotalTokens != 30 {
+		t.Errorf("got %d requests and usage %+v, want 2 requests and 30 total tokens", *requests, usage)
+	}
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Error kinds recognized in API error responses
+var (
+	ErrContextLength = errors.New("prompt exceeds the model's context window")
+	ErrInvalidModel  = errors.New("model not recognized by the API")
+)
+
+// Reasons for a response without a message
+var (
+	ErrNoChoices       = errors.New("LLM returned no choices")
+	ErrTruncated       = errors.New("response truncated by max_tokens before any message text; increase AICOMMIT_MAX_OUTPUT_TOKENS")
+	ErrContentFiltered = errors.New("response blocked by the provider's content filter")
+	ErrEmptyResponse   = errors.New("LLM returned empty response")
+)
+
+// emptyContentError explains an empty message from the choice's finish reason
+func emptyContentError(finishReason string) error {
+	switch finishReason {
+	case "length":
+		return ErrTruncated
+	case "content_filter":
+		return ErrContentFiltered
+	case "":
+		return ErrEmptyResponse
+	default:
+		return fmt.Errorf("%w (finish reason %q)", ErrEmptyResponse, finishReason)
+	}
+}
+
+// OpenRouterError is the error object returned in API responses
+type OpenRouterError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    any    `json:"code"` // Can be string or int
+}
+
+// CodeString normalizes the code, which may be a string or a number, for display
+func (e OpenRouterError) CodeString() string {
+	switch code := e.Code.(type) {
+	case nil:
+		return ""
+	case string:
+		return code
+	case float64:
+		return strconv.FormatFloat(code, 'f', -1, 64)
+	default:
+		return fmt.Sprint(code)
+	}
+}
+
+// kind maps the error type, code and message onto a known error kind, or nil
+func (e OpenRouterError) kind() error {
+	text := strings.ToLower(e.Type + " " + e.CodeString() + " " + e.Message)
+	switch {
+	case strings.Contains(text, "context_length_exceeded"),
+		strings.Contains(text, "context length"),
+		strings.Contains(text, "maximum context"),
+		strings.Contains(text, "context window"):
+		return ErrContextLength
+	case strings.Contains(text, "model_not_found"),
+		strings.Contains(text, "invalid_model"),
+		strings.Contains(text, "not a valid model"),
+		strings.Contains(text, "model not found"),
+		strings.Contains(text, "no endpoints found"):
+		return ErrInvalidModel
+	default:
+		return nil
+	}
+}
+
+// asError converts an error object from a response body into an error with
+// the code and, for known kinds, a suggested fix
+func (e OpenRouterError) asError(apiKey string) error {
+	msg := "API error"
+	if code := e.CodeString(); code != "" {
+		msg += fmt.Sprintf(" (code %s)", code)
+	}
+	msg += ": " + redactSecrets(e.Message, apiKey)
+
+	kind := e.kind()
+	if kind == nil {
+		return errors.New(msg)
+	}
+	return fmt.Errorf("%s: %w\n%s", msg, kind, errorHint(kind))
+}
+
+// errorHint suggests how to fix a known error kind
+func errorHint(kind error) string {
+	switch kind {
+	case ErrContextLength:
+		return "Lower AICOMMIT_MAX_INPUT_TOKENS so the smart diff condenses the changes sooner, or set AICOMMIT_MODEL_LIMITS for this model."
+	case ErrInvalidModel:
+		return "Check that AICOMMIT_LLM_MODEL (or --model) names a model available from the API, e.g. openai/gpt-4o-mini."
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOpenRouterErrorAsError(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		wantKind error
+		wantText string
+	}{
+		{
+			name:     "context length type",
+			payload:  `{"message": "This model's maximum context length is 8192 tokens", "type": "invalid_request_error", "code": "context_length_exceeded"}`,
+			wantKind: ErrContextLength,
+			wantText: "API error (code context_length_exceeded): This model's maximum context length is 8192 tokens",
+		},
+		{
+			name:     "invalid model with numeric code",
+			payload:  `{"message": "openai/gpt-5-mega is not a valid model ID", "code": 400}`,
+			wantKind: ErrInvalidModel,
+			wantText: "API error (code 400): openai/gpt-5-mega is not a valid model ID",
+		},
+		{
+			name:     "no endpoints",
+			payload:  `{"message": "No endpoints found for foo/bar.", "code": 404}`,
+			wantKind: ErrInvalidModel,
+			wantText: "AICOMMIT_LLM_MODEL",
+		},
+		{
+			name:     "unknown error without code",
+			payload:  `{"message": "Internal server error"}`,
+			wantText: "API error: Internal server error",
+		},
+		{
+			name:     "key echoed in message",
+			payload:  `{"message": "Invalid key sk-secretsecret1234", "code": "401"}`,
+			wantText: "API error (code 401): Invalid key ***************1234",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apiErr OpenRouterError
+			if err := json.Unmarshal([]byte(tt.payload), &apiErr); err != nil {
+				t.Fatal(err)
+			}
+			err := apiErr.asError("sk-secretsecret1234")
+			if tt.wantKind != nil && !errors.Is(err, tt.wantKind) {
+				t.Errorf("error = %v, want kind %v", err, tt.wantKind)
+			}
+			if tt.wantKind == nil && (errors.Is(err, ErrContextLength) || errors.Is(err, ErrInvalidModel)) {
+				t.Errorf("error = %v, want no known kind", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantText) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestCodeString(t *testing.T) {
+	tests := []struct {
+		code any
+		want string
+	}{
+		{nil, ""},
+		{"rate_limited", "rate_limited"},
+		{float64(429), "429"},
+		{1.5, "1.5"},
+		{true, "true"},
+	}
+	for _, tt := range tests {
+		if got := (OpenRouterError{Code: tt.code}).CodeString(); got != tt.want {
+			t.Errorf("CodeString(%v) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
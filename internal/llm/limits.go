@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KnownContextWindows maps model IDs to their context window size in tokens.
+// Entries can be overridden or extended with the MODEL_LIMITS setting.
+var KnownContextWindows = map[string]int{
+	"openai/gpt-4o":                   128000,
+	"openai/gpt-4o-mini":              128000,
+	"openai/gpt-4-turbo":              128000,
+	"openai/gpt-3.5-turbo":            16385,
+	"anthropic/claude-3.5-sonnet":     200000,
+	"anthropic/claude-3-haiku":        200000,
+	"anthropic/claude-3-opus":         200000,
+	"google/gemini-flash-1.5":         1000000,
+	"google/gemini-pro-1.5":           2000000,
+	"meta-llama/llama-3-8b-instruct":  8192,
+	"meta-llama/llama-3-70b-instruct": 8192,
+	"mistralai/mistral-7b-instruct":   32768,
+}
+
+// ParseModelLimits parses a "model=tokens,model=tokens" list of context windows
+func ParseModelLimits(spec string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		model, tokens, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid model limit '%s': expected model=tokens", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(tokens))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid model limit '%s': tokens must be a positive integer", entry)
+		}
+		limits[strings.TrimSpace(model)] = n
+	}
+	return limits, nil
+}
+
+// ContextWindow returns the context window for a model, checking overrides
+// before the built-in table. The bool is false if the window is unknown.
+func ContextWindow(model string, overrides map[string]int) (int, bool) {
+	if n, ok := overrides[model]; ok {
+		return n, true
+	}
+	n, ok := KnownContextWindows[model]
+	return n, ok
+}
+
+// WindowInputTokens returns the input budget that fills the model's context
+// window next to maxOutputTokens of output. The bool is false if the window
+// is unknown or too small to leave room for input.
+func WindowInputTokens(model string, maxOutputTokens int, overrides map[string]int) (int, bool) {
+	window, ok := ContextWindow(model, overrides)
+	if !ok || window <= maxOutputTokens {
+		return 0, false
+	}
+	return window - maxOutputTokens, true
+}
+
+// ClampInputTokens reduces maxInputTokens so that input plus output fits in the
+// model's context window. It returns the new budget and whether it changed.
+func ClampInputTokens(model string, maxInputTokens, maxOutputTokens int, overrides map[string]int) (int, bool, error) {
+	window, ok := ContextWindow(model, overrides)
+	if !ok || maxInputTokens+maxOutputTokens <= window {
+		return maxInputTokens, false, nil
+	}
+
+	clamped := window - maxOutputTokens
+	if clamped <= 0 {
+		return 0, false, fmt.Errorf("model %s has a context window of %d tokens, which cannot fit %d output tokens",
+			model, window, maxOutputTokens)
+	}
+	return clamped, true, nil
+}
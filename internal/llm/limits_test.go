@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseModelLimits(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    map[string]int
+		wantErr bool
+	}{
+		{"", map[string]int{}, false},
+		{"a/b=1000", map[string]int{"a/b": 1000}, false},
+		{" a/b = 1000 , c/d=2000,", map[string]int{"a/b": 1000, "c/d": 2000}, false},
+		{"a/b", nil, true},
+		{"a/b=0", nil, true},
+		{"a/b=lots", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseModelLimits(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseModelLimits(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !maps.Equal(got, tt.want) {
+			t.Errorf("ParseModelLimits(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestClampInputTokens(t *testing.T) {
+	overrides := map[string]int{"custom/small": 1000}
+	tests := []struct {
+		name        string
+		model       string
+		maxInput    int
+		maxOutput   int
+		want        int
+		wantClamped bool
+		wantErr     bool
+	}{
+		{"explicit budget is not raised to the window", "openai/gpt-4o", 4000, 200, 4000, false, false},
+		{"large budget fits a large model", "google/gemini-pro-1.5", 500000, 200, 500000, false, false},
+		{"clamped to a large window", "openai/gpt-4o", 200000, 1000, 127000, true, false},
+		{"clamped to a small window", "meta-llama/llama-3-8b-instruct", 10000, 192, 8000, true, false},
+		{"override", "custom/small", 4000, 200, 800, true, false},
+		{"unknown model", "unknown/model", 1000000, 200, 1000000, false, false},
+		{"output fills the window", "custom/small", 4000, 1000, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, clamped, err := ClampInputTokens(tt.model, tt.maxInput, tt.maxOutput, overrides)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ClampInputTokens() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || clamped != tt.wantClamped {
+				t.Errorf("ClampInputTokens() = %d, %v, want %d, %v", got, clamped, tt.want, tt.wantClamped)
+			}
+		})
+	}
+}
+
+func TestWindowInputTokens(t *testing.T) {
+	overrides := map[string]int{"custom/small": 1000}
+	tests := []struct {
+		name      string
+		model     string
+		maxOutput int
+		want      int
+		wantOK    bool
+	}{
+		{"large window", "openai/gpt-4o", 200, 127800, true},
+		{"override", "custom/small", 200, 800, true},
+		{"unknown model", "unknown/model", 200, 0, false},
+		{"output fills the window", "custom/small", 1000, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := WindowInputTokens(tt.model, tt.maxOutput, overrides)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("WindowInputTokens() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// modelsCacheTTL is how long a fetched model list is reused
+const modelsCacheTTL = 24 * time.Hour
+
+// modelsResponse is the body of a models list response
+type modelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// modelsCache is the cached model list for one endpoint
+type modelsCache struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	IDs       []string  `json:"ids"`
+}
+
+// ListModels returns the sorted ids of the models available from the
+// provider. Lists are cached per endpoint for a day. Non-2xx responses are
+// returned as *APIError.
+func ListModels(ctx context.Context, opts Options) ([]string, error) {
+	provider, err := newProvider(opts)
+	if err != nil {
+		return nil, err
+	}
+	url := provider.modelsURL()
+	cachePath := modelsCachePath(url)
+	if ids, ok := readModelsCache(cachePath); ok {
+		return ids, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	setExtraHeaders(req, opts.Headers)
+	provider.setHeaders(req)
+
+	client, err := newHTTPClient(opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("request timed out: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("error executing request: %s", redactSecrets(err.Error(), opts.APIKey))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		responseBody := new(bytes.Buffer)
+		_, _ = responseBody.ReadFrom(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: redactSecrets(responseBody.String(), opts.APIKey)}
+	}
+
+	var response modelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("error decoding models response: %w", err)
+	}
+	ids := make([]string, 0, len(response.Data))
+	for _, model := range response.Data {
+		ids = append(ids, model.ID)
+	}
+	sort.Strings(ids)
+
+	writeModelsCache(cachePath, ids)
+	return ids, nil
+}
+
+// modelsCachePath returns the cache file for the model list at url, or an
+// empty string if there is no cache directory
+func modelsCachePath(url string) string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(cacheDir, "ai-commit", "models-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// readModelsCache returns the cached ids if the cache is fresh
+func readModelsCache(path string) ([]string, bool) {
+	if path == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var cache modelsCache
+	if err := json.Unmarshal(data, &cache); err != nil || now().Sub(cache.FetchedAt) > modelsCacheTTL {
+		return nil, false
+	}
+	return cache.IDs, true
+}
+
+// writeModelsCache saves ids; failures only cost a fetch next time
+func writeModelsCache(path string, ids []string) {
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(modelsCache{FetchedAt: now(), IDs: ids})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o700)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o600)
+	}
+	if err != nil {
+		slog.Debug("Could not cache the model list", "error", err)
+	}
+}
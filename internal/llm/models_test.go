@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestListModels(t *testing.T) {
+	advance, _ := fakeClock(t)
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data":[{"id":"openai/gpt-4o"},{"id":"anthropic/claude-3.5-sonnet"}]}`)
+	}))
+	t.Cleanup(server.Close)
+	opts := Options{BaseURL: server.URL, APIKey: "test-key"}
+
+	want := []string{"anthropic/claude-3.5-sonnet", "openai/gpt-4o"}
+	for _, step := range []struct {
+		name         string
+		advance      time.Duration
+		wantRequests int
+	}{
+		{"first call fetches", 0, 1},
+		{"fresh cache is reused", time.Hour, 1},
+		{"stale cache is refetched", 25 * time.Hour, 2},
+	} {
+		advance(step.advance)
+		ids, err := ListModels(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("%s: ListModels error = %v", step.name, err)
+		}
+		if !slices.Equal(ids, want) {
+			t.Errorf("%s: ListModels() = %q, want %q", step.name, ids, want)
+		}
+		if len(requests) != step.wantRequests {
+			t.Errorf("%s: %d requests made, want %d", step.name, len(requests), step.wantRequests)
+		}
+	}
+	if requests[0] != "/models" {
+		t.Errorf("models requested from %q, want /models", requests[0])
+	}
+}
+
+func TestListModelsError(t *testing.T) {
+	fakeClock(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"bad key test-key"}}`, http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := ListModels(context.Background(), Options{BaseURL: server.URL, APIKey: "test-key"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("ListModels error = %v, want a 401 *APIError", err)
+	}
+	if _, cached := readModelsCache(modelsCachePath(server.URL + "/models")); cached {
+		t.Error("failed response was cached")
+	}
+}
@@ -5,118 +5,329 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"regexp"
 	"strings"
+	"time"
+
+	"github.com/cstobie/ai-commit/internal/secrets"
+	"github.com/cstobie/ai-commit/internal/tokenizer"
 )
 
+// bearerTokenRegex matches bearer tokens echoed back in API responses
+var bearerTokenRegex = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._\-]+`)
+
+// redactSecrets removes the API key and any bearer tokens from text that may
+// end up in error messages or logs
+func redactSecrets(text, apiKey string) string {
+	if apiKey != "" {
+		text = strings.ReplaceAll(text, apiKey, secrets.RedactKey(apiKey))
+	}
+	return bearerTokenRegex.ReplaceAllStringFunc(text, func(match string) string {
+		parts := bearerTokenRegex.FindStringSubmatch(match)
+		return parts[1] + secrets.RedactKey(match[len(parts[1]):])
+	})
+}
+
 type OpenRouterMessage struct {
 	Role    string `json:"role"` // "system" or "user"
 	Content string `json:"content"`
 }
 
 type OpenRouterChatRequest struct {
-	Model       string              `json:"model"`
-	Messages    []OpenRouterMessage `json:"messages"`
-	Temperature *float64            `json:"temperature,omitempty"` // Pointer to allow omission
-	MaxTokens   *int                `json:"max_tokens,omitempty"`  // Pointer for completion tokens
+	Model          string              `json:"model"`
+	Messages       []OpenRouterMessage `json:"messages"`
+	Temperature    *float64            `json:"temperature,omitempty"`     // Pointer to allow omission
+	Seed           *int                `json:"seed,omitempty"`            // Sampling seed, for providers that support it
+	MaxTokens      *int                `json:"max_tokens,omitempty"`      // Pointer for completion tokens
+	Usage          *UsageOptions       `json:"usage,omitempty"`           // Request usage accounting
+	ResponseFormat *ResponseFormat     `json:"response_format,omitempty"` // Structured output mode
+}
+
+// ResponseFormat constrains the model output, e.g. {"type": "json_object"}
+type ResponseFormat struct {
+	Type string `json:"type"`
+}
+
+// UsageOptions asks OpenRouter to include usage accounting in the response
+type UsageOptions struct {
+	Include bool `json:"include"`
+}
+
+// Usage holds the token counts and cost reported for a request
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"` // In credits (USD), zero if not reported
+}
+
+// AddUsage sums usage across several requests, treating nil as unreported
+func AddUsage(total, next *Usage) *Usage {
+	if total == nil {
+		return next
+	}
+	if next == nil {
+		return total
+	}
+	return &Usage{
+		PromptTokens:     total.PromptTokens + next.PromptTokens,
+		CompletionTokens: total.CompletionTokens + next.CompletionTokens,
+		TotalTokens:      total.TotalTokens + next.TotalTokens,
+		Cost:             total.Cost + next.Cost,
+	}
 }
 
 type OpenRouterChoice struct {
-	Message OpenRouterMessage `json:"message"`
+	Message      OpenRouterMessage `json:"message"`
+	FinishReason string            `json:"finish_reason"` // e.g. "stop", "length" or "content_filter"
 }
 
 type OpenRouterChatResponse struct {
 	ID      string             `json:"id"`
+	Model   string             `json:"model"`
 	Choices []OpenRouterChoice `json:"choices"`
-	Error   *struct {
-		Message string `json:"message"`
-		Type    string `json:"type"`
-		Code    any    `json:"code"` // Can be string or int
-	} `json:"error,omitempty"`
+	Usage   *Usage             `json:"usage,omitempty"`
+	Error   *OpenRouterError   `json:"error,omitempty"`
 }
 
-// EstimateTokens provides a simple word-based token estimation
-func EstimateTokens(text string) int {
-	return len(strings.Fields(text))
-}
+// TruncationMarker replaces the middle of prompts that exceed the token limit
+const TruncationMarker = "[...truncated...]"
 
-// TruncateInput truncates the prompt to fit within maxTokens
-func TruncateInput(prompt string, maxTokens int) (string, bool) {
-	tokens := EstimateTokens(prompt)
+// TruncateInput truncates the prompt to fit within maxTokens as counted by
+// tok, keeping words from its start and end around TruncationMarker. Line
+// breaks are lost, so diffs are cut with TruncateDiff instead.
+func TruncateInput(prompt string, maxTokens int, tok tokenizer.Tokenizer) (string, bool) {
+	tokens := tokenizer.Count(tok, prompt)
 	if tokens <= maxTokens {
 		return prompt, false
 	}
 
+	// Keep the share of words that matches the share of tokens allowed, then
+	// drop more until the marker fits too
 	words := strings.Fields(prompt)
-	keepTokens := maxTokens / 2
-	
-	// Keep the first and last parts of the prompt
-	if len(words) > maxTokens {
-		truncated := append(
-			words[:keepTokens],
-			append(
-				[]string{"[...truncated...]"},
-				words[len(words)-keepTokens:]...,
-			)...,
-		)
-		return strings.Join(truncated, " "), true
-	}
-	
-	return prompt, false
-}
-
-// GenerateCommitMessage calls the OpenRouter API to generate a commit message
-func GenerateCommitMessage(ctx context.Context, apiKey, model string, maxOutputTokens int, 
-	temperature float64, fullPrompt string, maxInputTokens int) (string, error) {
-	
-	// Truncate input if needed
-	truncatedPrompt, wasTruncated := TruncateInput(fullPrompt, maxInputTokens)
+	keepWords := len(words) * maxTokens / tokens / 2
+	for {
+		truncated := strings.Join(words[:keepWords], " ") + " " + TruncationMarker + " " +
+			strings.Join(words[len(words)-keepWords:], " ")
+		truncated = strings.TrimSpace(truncated)
+		if keepWords == 0 || tokenizer.Count(tok, truncated) <= maxTokens {
+			return truncated, true
+		}
+		keepWords--
+	}
+}
+
+// DeterministicSeed is the seed sent in deterministic mode
+const DeterministicSeed = 42
+
+// DefaultBaseURL is the OpenRouter API base used when no override is configured
+const DefaultBaseURL = "https://openrouter.ai/api/v1"
+
+// Options holds the provider and generation settings for a request
+type Options struct {
+	Provider        string // A name from ProviderNames; ProviderOpenRouter if empty
+	APIKey          string
+	BaseURL         string // API base, e.g. https://openrouter.ai/api/v1; the provider's default if empty
+	Model           string
+	MaxInputTokens  int
+	MaxOutputTokens int
+	Temperature     float64
+	Proxy           string        // Proxy URL, ProxyNone, or empty to use the environment
+	MaxRPM          int           // Requests per minute across invocations; 0 for no limit
+	Timeout         time.Duration // Bounds each chat request after any rate limit wait; 0 for none
+	Seed            *int          // Sampling seed for reproducible output; nil to let the provider choose
+	PromptRole      string        // "system" sends the instructions as a system message; otherwise one user message
+
+	// Counts tokens against MaxInputTokens; tokenizer.Default if nil
+	Tokenizer tokenizer.Tokenizer
+
+	// Extra request headers; authentication and Content-Type always win
+	Headers map[string]string
+
+	// Azure OpenAI deployment, used with ProviderAzure
+	AzureEndpoint   string // e.g. https://my-resource.openai.azure.com
+	AzureDeployment string
+	AzureAPIVersion string
+
+	// Continuation requests for a message cut off by MaxOutputTokens; 0
+	// returns the cut-off message as is
+	MaxContinuations int
+
+	// Example exchanges sent before the prompt: alternating user and
+	// assistant messages. Later pairs are dropped if they do not fit in
+	// MaxInputTokens.
+	Examples []OpenRouterMessage
+}
+
+// APIError is returned when the API responds with a non-2xx status code
+type APIError struct {
+	StatusCode int
+	Body       string // Response body with secrets redacted
+	Kind       error  // ErrContextLength, ErrInvalidModel, or nil if not recognized
+}
+
+func (e *APIError) Error() string {
+	var msg string
+	switch {
+	case e.StatusCode == 401:
+		msg = fmt.Sprintf("API authentication error (code %d): %s", e.StatusCode, e.Body)
+	case e.StatusCode == 429:
+		msg = fmt.Sprintf("API rate limit exceeded (code %d): %s", e.StatusCode, e.Body)
+	case e.StatusCode >= 500:
+		msg = fmt.Sprintf("API server error (code %d): %s", e.StatusCode, e.Body)
+	default:
+		msg = fmt.Sprintf("API error (code %d): %s", e.StatusCode, e.Body)
+	}
+	if hint := errorHint(e.Kind); hint != "" {
+		msg += "\n" + hint
+	}
+	return msg
+}
+
+// Unwrap exposes the recognized error kind to errors.Is
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// GenerateCommitMessage calls the OpenRouter API to generate a commit message.
+// The returned usage is nil when the API does not report it.
+func GenerateCommitMessage(ctx context.Context, opts Options, fullPrompt string) (string, *Usage, error) {
+	messages := requestMessages(opts, fullPrompt)
+	reply, err := sendChat(ctx, opts, messages, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	content, usage := continueTruncated(ctx, opts, messages, reply)
+	return CleanMessage(strings.TrimSpace(content)), usage, nil
+}
+
+// diffFenceStart and diffFenceEnd delimit the diff in the built-in templates
+const (
+	diffFenceStart = "```diff\n"
+	diffFenceEnd   = "\n```\n"
+)
+
+// requestMessages builds the messages for a prompt: the diff in it is cut if
+// needed, keeping the instructions, and as many examples as fit in the
+// remaining budget are laid out with it for the configured role
+func requestMessages(opts Options, prompt string) []OpenRouterMessage {
+	truncatedPrompt, wasTruncated := fitPrompt(prompt, opts.MaxInputTokens, opts.Tokenizer)
 	if wasTruncated {
-		log.Println("Warning: Prompt was truncated to fit within token limits")
+		slog.Warn("Prompt was truncated to fit within token limits", "max_input_tokens", opts.MaxInputTokens)
 	}
+	examples := fewShotMessages(opts.Examples, opts.MaxInputTokens-tokenizer.Count(opts.Tokenizer, truncatedPrompt), opts.Tokenizer)
+	return promptMessages(truncatedPrompt, opts.PromptRole, examples)
+}
 
-	// Build request
-	messages := []OpenRouterMessage{
-		{Role: "user", Content: truncatedPrompt},
+// promptMessages lays out the prompt and examples for the given role. With
+// the system role the instructions go in a leading system message and the
+// user message carries only the diff; prompts without a fenced diff block,
+// e.g. after truncation, are sent whole as the user message.
+func promptMessages(prompt, role string, examples []OpenRouterMessage) []OpenRouterMessage {
+	if role == "system" {
+		if start := strings.Index(prompt, diffFenceStart); start >= 0 {
+			diffStart := start + len(diffFenceStart)
+			if end := strings.Index(prompt[diffStart:], diffFenceEnd); end >= 0 {
+				diff := prompt[diffStart : diffStart+end]
+				instructions := prompt[:start] + "(the diff is in the user message)" + prompt[diffStart+end+len(diffFenceEnd)-1:]
+				messages := []OpenRouterMessage{{Role: "system", Content: instructions}}
+				messages = append(messages, examples...)
+				return append(messages, OpenRouterMessage{Role: "user", Content: diff})
+			}
+		}
+		slog.Debug("No diff block found in the prompt, sending it as the user message")
+	}
+	return append(append([]OpenRouterMessage{}, examples...), OpenRouterMessage{Role: "user", Content: prompt})
+}
+
+// fewShotMessages returns the leading example exchanges whose combined
+// estimated size fits in budget tokens
+func fewShotMessages(examples []OpenRouterMessage, budget int, tok tokenizer.Tokenizer) []OpenRouterMessage {
+	used := 0
+	for i := 0; i+1 < len(examples); i += 2 {
+		used += tokenizer.Count(tok, examples[i].Content) + tokenizer.Count(tok, examples[i+1].Content)
+		if used > budget {
+			slog.Info("Dropped examples to fit within token limits", "kept", i/2, "total", len(examples)/2)
+			return examples[:i]
+		}
+	}
+	return examples[:len(examples)/2*2]
+}
+
+// chatReply is the first choice of a chat completion response
+type chatReply struct {
+	Content      string // As returned, without trimming
+	Usage        *Usage
+	FinishReason string
+}
+
+// sendChat posts a chat completion request and returns the first choice
+func sendChat(ctx context.Context, opts Options, messages []OpenRouterMessage,
+	responseFormat *ResponseFormat) (chatReply, error) {
+	apiKey := opts.APIKey
+	provider, err := newProvider(opts)
+	if err != nil {
+		return chatReply{}, err
+	}
+
+	// Wait for the rate limit before the timeout starts, so the wait does
+	// not use up the time the request itself is given
+	if err := throttle(ctx, opts.MaxRPM); err != nil {
+		return chatReply{}, fmt.Errorf("request cancelled: %w", err)
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
 	}
 
 	requestBody := OpenRouterChatRequest{
-		Model:       model,
-		Messages:    messages,
-		MaxTokens:   &maxOutputTokens,
-		Temperature: &temperature,
+		Model:          opts.Model,
+		Messages:       messages,
+		MaxTokens:      &opts.MaxOutputTokens,
+		Temperature:    &opts.Temperature,
+		Seed:           opts.Seed,
+		ResponseFormat: responseFormat,
 	}
+	provider.adaptRequest(&requestBody)
 
 	requestBodyBytes, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("error marshaling request: %w", err)
+		return chatReply{}, fmt.Errorf("error marshaling request: %w", err)
 	}
 
 	// Create request
 	req, err := http.NewRequestWithContext(
 		ctx,
 		"POST",
-		"https://openrouter.ai/api/v1/chat/completions",
+		provider.chatCompletionsURL(),
 		bytes.NewBuffer(requestBodyBytes),
 	)
 	if err != nil {
-		return "", fmt.Errorf("error creating request: %w", err)
+		return chatReply{}, fmt.Errorf("error creating request: %w", err)
 	}
 
 	// Set headers
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+	setExtraHeaders(req, opts.Headers)
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("HTTP-Referer", "github.com/cstobie/ai-commit")
-	req.Header.Set("X-Title", "AI-Commit CLI")
+	provider.setHeaders(req)
 
 	// Execute request
-	resp, err := http.DefaultClient.Do(req)
+	client, err := newHTTPClient(opts.Proxy)
+	if err != nil {
+		return chatReply{}, err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("request timed out: %w", ctx.Err())
+			return chatReply{}, fmt.Errorf("request timed out: %w", ctx.Err())
+		}
+		if ctx.Err() == context.Canceled {
+			return chatReply{}, fmt.Errorf("request cancelled: %w", ctx.Err())
 		}
-		return "", fmt.Errorf("error executing request: %w", err)
+		return chatReply{}, fmt.Errorf("error executing request: %s", redactSecrets(err.Error(), apiKey))
 	}
 	defer resp.Body.Close()
 
@@ -124,36 +335,37 @@ func GenerateCommitMessage(ctx context.Context, apiKey, model string, maxOutputT
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		responseBody := new(bytes.Buffer)
 		_, _ = responseBody.ReadFrom(resp.Body)
-		
-		switch resp.StatusCode {
-		case 401:
-			return "", fmt.Errorf("API authentication error (code %d): %s", resp.StatusCode, responseBody.String())
-		case 429:
-			return "", fmt.Errorf("API rate limit exceeded (code %d): %s", resp.StatusCode, responseBody.String())
-		default:
-			if resp.StatusCode >= 500 {
-				return "", fmt.Errorf("API server error (code %d): %s", resp.StatusCode, responseBody.String())
-			}
-			return "", fmt.Errorf("API error (code %d): %s", resp.StatusCode, responseBody.String())
+		apiErr := &APIError{
+			StatusCode: resp.StatusCode,
+			Body:       redactSecrets(responseBody.String(), apiKey),
+		}
+		// Error bodies usually carry the same error object as successful responses
+		var errorResponse OpenRouterChatResponse
+		if json.Unmarshal(responseBody.Bytes(), &errorResponse) == nil && errorResponse.Error != nil {
+			apiErr.Kind = errorResponse.Error.kind()
 		}
+		return chatReply{}, apiErr
 	}
 
 	// Parse response
 	var response OpenRouterChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("error decoding response: %w", err)
+		return chatReply{}, fmt.Errorf("error decoding response: %w", err)
 	}
 
 	// Check for API errors in response body
 	if response.Error != nil && response.Error.Message != "" {
-		return "", fmt.Errorf("API error: %s", response.Error.Message)
+		return chatReply{}, response.Error.asError(apiKey)
 	}
 
 	// Extract and validate response content
-	if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
-		return "", fmt.Errorf("LLM returned empty response")
+	if len(response.Choices) == 0 {
+		return chatReply{}, ErrNoChoices
+	}
+	if strings.TrimSpace(response.Choices[0].Message.Content) == "" {
+		return chatReply{}, emptyContentError(response.Choices[0].FinishReason)
 	}
 
-	// Return the generated commit message
-	return strings.TrimSpace(response.Choices[0].Message.Content), nil
-}
\ No newline at end of file
+	choice := response.Choices[0]
+	return chatReply{Content: choice.Message.Content, Usage: response.Usage, FinishReason: choice.FinishReason}, nil
+}
@@ -0,0 +1,168 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Supported API providers
+const (
+	ProviderOpenRouter = "openrouter"
+	ProviderAzure      = "azure"
+)
+
+// ProviderSpec describes how to reach a provider and authenticate with it.
+// The request and response bodies follow the OpenAI chat completions shape;
+// adaptRequest adjusts the request for providers that differ.
+type ProviderSpec struct {
+	APIKeyName     string            // Setting holding the API key, without the AICOMMIT_ prefix
+	DefaultBaseURL string            // API base when Options.BaseURL is empty
+	AuthHeader     string            // Header carrying the API key
+	AuthScheme     string            // Prefix of the key in AuthHeader, e.g. "Bearer"; empty for the bare key
+	Headers        map[string]string // Fixed headers sent with every request
+
+	// defaultBaseURL returns the API base for opts when Options.BaseURL is
+	// empty, for providers whose base depends on other options; nil to use
+	// DefaultBaseURL
+	defaultBaseURL func(opts Options) string
+	// chatPath and modelsPath are appended to the API base. The models list
+	// is used as a cheap connectivity check.
+	chatPath   func(opts Options) string
+	modelsPath func(opts Options) string
+	// adaptRequest adjusts the request body before it is sent; nil to send
+	// it as is
+	adaptRequest func(req *OpenRouterChatRequest)
+}
+
+// providers maps each supported provider name to its spec. Adding a provider
+// that speaks the OpenAI chat completions API only takes an entry here; its
+// key is read from AICOMMIT_<APIKeyName>.
+var providers = map[string]ProviderSpec{
+	ProviderOpenRouter: {
+		APIKeyName:     "OPENROUTER_API_KEY",
+		DefaultBaseURL: DefaultBaseURL,
+		AuthHeader:     "Authorization",
+		AuthScheme:     "Bearer",
+		Headers: map[string]string{
+			"HTTP-Referer": "github.com/cstobie/ai-commit",
+			"X-Title":      "AI-Commit CLI",
+		},
+		chatPath:   staticPath("/chat/completions"),
+		modelsPath: staticPath("/models"),
+		adaptRequest: func(req *OpenRouterChatRequest) {
+			req.Usage = &UsageOptions{Include: true}
+		},
+	},
+	ProviderAzure: {
+		APIKeyName: "AZURE_API_KEY",
+		AuthHeader: "api-key",
+		defaultBaseURL: func(opts Options) string {
+			return opts.AzureEndpoint
+		},
+		chatPath: func(opts Options) string {
+			return "/openai/deployments/" + url.PathEscape(opts.AzureDeployment) +
+				"/chat/completions?api-version=" + url.QueryEscape(opts.AzureAPIVersion)
+		},
+		modelsPath: func(opts Options) string {
+			return "/openai/models?api-version=" + url.QueryEscape(opts.AzureAPIVersion)
+		},
+	},
+}
+
+// staticPath returns a path function that ignores the options
+func staticPath(path string) func(Options) string {
+	return func(Options) string {
+		return path
+	}
+}
+
+// ProviderNames returns the names of the supported providers, sorted
+func ProviderNames() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupProvider returns the spec registered for name
+func LookupProvider(name string) (ProviderSpec, bool) {
+	spec, ok := providers[name]
+	return spec, ok
+}
+
+// Endpoint returns the API base requests for opts are sent to
+func Endpoint(opts Options) string {
+	p, err := newProvider(opts)
+	if err != nil {
+		return ""
+	}
+	return p.base
+}
+
+// provider is a ProviderSpec bound to the options of a request
+type provider struct {
+	spec ProviderSpec
+	opts Options
+	base string // Resolved API base, without a trailing slash
+}
+
+// newProvider returns the provider selected in opts; OpenRouter if none is
+func newProvider(opts Options) (provider, error) {
+	name := opts.Provider
+	if name == "" {
+		name = ProviderOpenRouter
+	}
+	spec, ok := providers[name]
+	if !ok {
+		return provider{}, fmt.Errorf("unknown provider '%s': must be one of %s",
+			name, strings.Join(ProviderNames(), ", "))
+	}
+
+	base := opts.BaseURL
+	if base == "" && spec.defaultBaseURL != nil {
+		base = spec.defaultBaseURL(opts)
+	} else if base == "" {
+		base = spec.DefaultBaseURL
+	}
+	return provider{spec: spec, opts: opts, base: strings.TrimSuffix(base, "/")}, nil
+}
+
+func (p provider) chatCompletionsURL() string {
+	return p.base + p.spec.chatPath(p.opts)
+}
+
+func (p provider) modelsURL() string {
+	return p.base + p.spec.modelsPath(p.opts)
+}
+
+// setHeaders sets the authentication and fixed headers of the provider
+func (p provider) setHeaders(req *http.Request) {
+	credential := p.opts.APIKey
+	if p.spec.AuthScheme != "" {
+		credential = p.spec.AuthScheme + " " + credential
+	}
+	req.Header.Set(p.spec.AuthHeader, credential)
+	for name, value := range p.spec.Headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// adaptRequest applies the provider's adjustments to the request body
+func (p provider) adaptRequest(req *OpenRouterChatRequest) {
+	if p.spec.adaptRequest != nil {
+		p.spec.adaptRequest(req)
+	}
+}
+
+// setExtraHeaders adds the configured extra headers to req. It is called
+// before the provider's own headers are set, so those cannot be overridden.
+func setExtraHeaders(req *http.Request, headers map[string]string) {
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+}
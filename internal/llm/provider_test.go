@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// capturedRequest is what a fake provider saw of a request
+type capturedRequest struct {
+	method string
+	uri    string
+	header http.Header
+	body   map[string]any
+}
+
+// fakeProvider answers chat and models requests and records the last one
+func fakeProvider(t *testing.T) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+	captured := &capturedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*captured = capturedRequest{method: r.Method, uri: r.URL.RequestURI(), header: r.Header.Clone()}
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			if err := json.Unmarshal(data, &captured.body); err != nil {
+				t.Errorf("request body is not JSON: %v", err)
+			}
+		}
+		w.Write([]byte(`{"data":[],"choices":[{"message":{"content":"feat: add login"},"finish_reason":"stop"}]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, captured
+}
+
+func TestProviderRequests(t *testing.T) {
+	tests := map[string]struct {
+		chatURI    string
+		modelsURI  string
+		authHeader string
+		authValue  string
+		headers    map[string]string
+		wantUsage  bool
+	}{
+		ProviderOpenRouter: {
+			chatURI:    "/api/v1/chat/completions",
+			modelsURI:  "/api/v1/models",
+			authHeader: "Authorization",
+			authValue:  "Bearer secret",
+			headers:    map[string]string{"HTTP-Referer": "github.com/cstobie/ai-commit", "X-Title": "AI-Commit CLI"},
+			wantUsage:  true,
+		},
+		ProviderAzure: {
+			chatURI:    "/openai/deployments/my%20deploy/chat/completions?api-version=2024-06-01",
+			modelsURI:  "/openai/models?api-version=2024-06-01",
+			authHeader: "api-key",
+			authValue:  "secret",
+		},
+	}
+
+	for _, name := range ProviderNames() {
+		want, ok := tests[name]
+		if !ok {
+			t.Errorf("provider %s is registered but has no test case", name)
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			server, captured := fakeProvider(t)
+			opts := Options{
+				Provider:        name,
+				APIKey:          "secret",
+				Model:           "test/model",
+				MaxInputTokens:  1000,
+				MaxOutputTokens: 100,
+				Headers:         map[string]string{"X-Team": "core", "Content-Type": "text/plain"},
+				AzureDeployment: "my deploy",
+				AzureAPIVersion: "2024-06-01",
+			}
+			if name == ProviderAzure {
+				opts.AzureEndpoint = server.URL + "/"
+			} else {
+				opts.BaseURL = server.URL + "/api/v1/"
+			}
+
+			message, _, err := GenerateCommitMessage(context.Background(), opts, "Describe this change")
+			if err != nil {
+				t.Fatalf("GenerateCommitMessage error = %v", err)
+			}
+			if message != "feat: add login" {
+				t.Errorf("message = %q", message)
+			}
+			if captured.method != http.MethodPost || captured.uri != want.chatURI {
+				t.Errorf("request = %s %s, want POST %s", captured.method, captured.uri, want.chatURI)
+			}
+			if got := captured.header.Get(want.authHeader); got != want.authValue {
+				t.Errorf("%s = %q, want %q", want.authHeader, got, want.authValue)
+			}
+			for header, value := range want.headers {
+				if got := captured.header.Get(header); got != value {
+					t.Errorf("%s = %q, want %q", header, got, value)
+				}
+			}
+			if got := captured.header.Get("X-Team"); got != "core" {
+				t.Errorf("extra header X-Team = %q, want core", got)
+			}
+			if got := captured.header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json despite the extra header", got)
+			}
+			if captured.body["model"] != "test/model" || captured.body["max_tokens"] != float64(100) {
+				t.Errorf("body model and max_tokens = %v, %v", captured.body["model"], captured.body["max_tokens"])
+			}
+			if messages, _ := captured.body["messages"].([]any); len(messages) != 1 {
+				t.Errorf("body messages = %v, want one user message", captured.body["messages"])
+			}
+			if _, gotUsage := captured.body["usage"]; gotUsage != want.wantUsage {
+				t.Errorf("body has usage option = %v, want %v", gotUsage, want.wantUsage)
+			}
+
+			if err := CheckConnection(context.Background(), opts); err != nil {
+				t.Fatalf("CheckConnection error = %v", err)
+			}
+			if captured.method != http.MethodGet || captured.uri != want.modelsURI {
+				t.Errorf("models request = %s %s, want GET %s", captured.method, captured.uri, want.modelsURI)
+			}
+		})
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{name: "openrouter default", opts: Options{}, want: DefaultBaseURL},
+		{name: "openrouter gateway", opts: Options{BaseURL: "https://gateway.example/v1/"}, want: "https://gateway.example/v1"},
+		{
+			name: "azure endpoint",
+			opts: Options{Provider: ProviderAzure, AzureEndpoint: "https://res.openai.azure.com/"},
+			want: "https://res.openai.azure.com",
+		},
+		{
+			name: "base URL replaces the azure endpoint",
+			opts: Options{Provider: ProviderAzure, BaseURL: "https://gateway.example", AzureEndpoint: "https://res.openai.azure.com"},
+			want: "https://gateway.example",
+		},
+		{name: "unknown provider", opts: Options{Provider: "nope"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Endpoint(tt.opts); got != tt.want {
+				t.Errorf("Endpoint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownProvider(t *testing.T) {
+	_, _, err := GenerateCommitMessage(context.Background(), Options{Provider: "nope"}, "prompt")
+	if err == nil || err.Error() != "unknown provider 'nope': must be one of azure, openrouter" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestChatCompletionsURL(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{name: "openrouter", opts: Options{}, want: DefaultBaseURL + "/chat/completions"},
+		{
+			name: "azure",
+			opts: Options{Provider: ProviderAzure, AzureEndpoint: "https://res.openai.azure.com/", AzureDeployment: "gpt-4o", AzureAPIVersion: "2024-06-01"},
+			want: "https://res.openai.azure.com/openai/deployments/gpt-4o/chat/completions?api-version=2024-06-01",
+		},
+		{
+			name: "azure escapes the deployment and version",
+			opts: Options{Provider: ProviderAzure, AzureEndpoint: "https://res.openai.azure.com", AzureDeployment: "a/b c", AzureAPIVersion: "v&1"},
+			want: "https://res.openai.azure.com/openai/deployments/a%2Fb%20c/chat/completions?api-version=v%261",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newProvider(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.chatCompletionsURL(); got != tt.want {
+				t.Errorf("chatCompletionsURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// now and sleep are replaced in tests to fake the clock
+var (
+	now   = time.Now
+	sleep = defaultSleep
+)
+
+// defaultSleep waits for d, or until ctx is done
+func defaultSleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimitFile returns the file holding the time of the latest API call,
+// shared by all invocations of the tool
+func rateLimitFile() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "ai-commit", "last-request"), nil
+}
+
+// throttle waits until at least a minute divided by maxRPM has passed since
+// the previous API call, across invocations, and records this call. It does
+// nothing when maxRPM is zero. Problems with the state file only disable the
+// throttling. A cancelled wait gives its slot back, unless a later call has
+// already queued behind it.
+func throttle(ctx context.Context, maxRPM int) error {
+	if maxRPM <= 0 {
+		return nil
+	}
+	path, err := rateLimitFile()
+	if err != nil {
+		slog.Debug("Rate limiting disabled, no cache directory", "error", err)
+		return nil
+	}
+
+	interval := time.Minute / time.Duration(maxRPM)
+	next := now()
+	previous, err := os.ReadFile(path)
+	if err != nil {
+		previous = nil
+	} else if last, err := strconv.ParseInt(strings.TrimSpace(string(previous)), 10, 64); err == nil {
+		if earliest := time.Unix(0, last).Add(interval); earliest.After(next) {
+			next = earliest
+		}
+	}
+
+	// Claim the slot before waiting so concurrent invocations queue behind it
+	claimed := []byte(strconv.FormatInt(next.UnixNano(), 10))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err == nil {
+		err = os.WriteFile(path, claimed, 0o600)
+		if err != nil {
+			slog.Debug("Could not record API call time", "error", err)
+		}
+	}
+
+	wait := next.Sub(now())
+	if wait <= 0 {
+		return nil
+	}
+	slog.Info("Waiting to stay under the request rate limit", "wait", wait.Round(time.Millisecond), "max_rpm", maxRPM)
+	if err := sleep(ctx, wait); err != nil {
+		releaseSlot(path, claimed, previous)
+		return err
+	}
+	return nil
+}
+
+// releaseSlot restores the previous state file after a cancelled wait, so the
+// slot that was never used does not delay the next call. A file that no
+// longer holds the claimed slot belongs to a later call and is left alone.
+func releaseSlot(path string, claimed, previous []byte) {
+	if current, err := os.ReadFile(path); err != nil || string(current) != string(claimed) {
+		return
+	}
+	var err error
+	if previous == nil {
+		err = os.Remove(path)
+	} else {
+		err = os.WriteFile(path, previous, 0o600)
+	}
+	if err != nil {
+		slog.Debug("Could not release the API call slot", "error", err)
+	}
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// fakeClock replaces now and sleep with a clock that only moves when slept
+// on or advanced, and returns the waits
+func fakeClock(t *testing.T) (advance func(time.Duration), waits *[]time.Duration) {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	current := time.Unix(1_700_000_000, 0)
+	var slept []time.Duration
+	now = func() time.Time { return current }
+	sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		current = current.Add(d)
+		return nil
+	}
+	t.Cleanup(func() {
+		now = time.Now
+		sleep = defaultSleep
+	})
+	return func(d time.Duration) { current = current.Add(d) }, &slept
+}
+
+func TestThrottle(t *testing.T) {
+	tests := []struct {
+		name   string
+		maxRPM int
+		gap    time.Duration // Time between the two calls
+		want   []time.Duration
+	}{
+		{"off", 0, 0, nil},
+		{"rapid calls", 30, 0, []time.Duration{2 * time.Second}},
+		{"partly elapsed", 30, 500 * time.Millisecond, []time.Duration{1500 * time.Millisecond}},
+		{"interval elapsed", 30, 3 * time.Second, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advance, waits := fakeClock(t)
+			for i := 0; i < 2; i++ {
+				if i > 0 {
+					advance(tt.gap)
+				}
+				if err := throttle(context.Background(), tt.maxRPM); err != nil {
+					t.Fatalf("throttle call %d error = %v", i+1, err)
+				}
+			}
+			if len(*waits) != len(tt.want) {
+				t.Fatalf("waits = %v, want %v", *waits, tt.want)
+			}
+			for i := range tt.want {
+				if (*waits)[i] != tt.want[i] {
+					t.Errorf("wait %d = %v, want %v", i, (*waits)[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestThrottleQueuesCalls(t *testing.T) {
+	_, waits := fakeClock(t)
+	// Without sleeping, e.g. concurrent invocations, each call queues behind
+	// the slot claimed by the previous one
+	sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	for i := 0; i < 3; i++ {
+		if err := throttle(context.Background(), 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []time.Duration{time.Second, 2 * time.Second}
+	if len(*waits) != len(want) || (*waits)[0] != want[0] || (*waits)[1] != want[1] {
+		t.Errorf("waits = %v, want %v", *waits, want)
+	}
+}
+
+func TestThrottleWaitIsNotTimed(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server, _ := fakeProvider(t)
+	// The second request waits 200ms for its slot, longer than the timeout
+	opts := Options{APIKey: "secret", BaseURL: server.URL, MaxRPM: 300, Timeout: 100 * time.Millisecond}
+	for i := 0; i < 2; i++ {
+		if _, _, err := GenerateCommitMessage(context.Background(), opts, "prompt"); err != nil {
+			t.Fatalf("request %d error = %v", i+1, err)
+		}
+	}
+}
+
+func TestThrottleCancelledWaitReleasesSlot(t *testing.T) {
+	fakeClock(t)
+	if err := throttle(context.Background(), 30); err != nil {
+		t.Fatal(err)
+	}
+	path, err := rateLimitFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sleep = func(ctx context.Context, d time.Duration) error { return context.Canceled }
+	if err := throttle(context.Background(), 30); !errors.Is(err, context.Canceled) {
+		t.Fatalf("throttle error = %v, want cancelled", err)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("state file = %s after the cancelled wait, want the previous call %s", after, before)
+	}
+}
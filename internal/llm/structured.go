@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// structuredInstructions is appended to the prompt in structured mode so the
+// model returns a JSON object instead of free text
+const structuredInstructions = `
+
+Respond ONLY with a JSON object of this exact shape, and no other text:
+{"type": "<commit type, e.g. feat or fix>", "scope": "<optional scope or empty string>", "subject": "<imperative summary without trailing period>", "body": "<optional body or empty string>"}`
+
+// StructuredCommit is a commit message returned by the model as JSON
+type StructuredCommit struct {
+	Type    string `json:"type"`
+	Scope   string `json:"scope"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Validate checks that the required fields are present
+func (c StructuredCommit) Validate() error {
+	var missing []string
+	if strings.TrimSpace(c.Type) == "" {
+		missing = append(missing, "type")
+	}
+	if strings.TrimSpace(c.Subject) == "" {
+		missing = append(missing, "subject")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("structured commit is missing required fields: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Format renders the commit as a conventional commit message
+func (c StructuredCommit) Format() string {
+	header := strings.TrimSpace(c.Type)
+	if scope := strings.TrimSpace(c.Scope); scope != "" {
+		header += "(" + scope + ")"
+	}
+	header += ": " + strings.TrimSuffix(strings.TrimSpace(c.Subject), ".")
+
+	if body := strings.TrimSpace(c.Body); body != "" {
+		return header + "\n\n" + body
+	}
+	return header
+}
+
+// GenerateStructuredCommit asks the model for a JSON commit message and
+// parses it. Models without JSON mode support are retried without
+// response_format, and malformed output is re-prompted once. Like
+// GenerateCommitMessage, replies cut off by the output limit are continued.
+func GenerateStructuredCommit(ctx context.Context, opts Options, fullPrompt string) (StructuredCommit, *Usage, error) {
+	// The JSON instructions follow the diff, so only the diff is ever cut,
+	// and go with the other instructions in the system role
+	messages := requestMessages(opts, fullPrompt+structuredInstructions)
+
+	responseFormat := &ResponseFormat{Type: "json_object"}
+	content, usage, err := sendStructured(ctx, opts, messages, responseFormat)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest && apiErr.Kind == nil {
+		slog.Warn("Model rejected JSON mode, retrying without response_format", "model", opts.Model)
+		responseFormat = nil
+		content, usage, err = sendStructured(ctx, opts, messages, responseFormat)
+	}
+	if err != nil {
+		return StructuredCommit{}, nil, err
+	}
+
+	commit, parseErr := parseStructuredCommit(content)
+	if parseErr == nil {
+		return commit, usage, nil
+	}
+
+	// Re-prompt once with the parse error so the model can correct itself
+	slog.Info("Structured response was invalid, re-prompting", "error", parseErr)
+	messages = append(messages,
+		OpenRouterMessage{Role: "assistant", Content: content},
+		OpenRouterMessage{Role: "user", Content: fmt.Sprintf(
+			"That response was invalid: %v. Reply again with only the JSON object.", parseErr)},
+	)
+	content, retryUsage, err := sendStructured(ctx, opts, messages, responseFormat)
+	if err != nil {
+		return StructuredCommit{}, nil, err
+	}
+	usage = AddUsage(usage, retryUsage)
+	commit, parseErr = parseStructuredCommit(content)
+	if parseErr != nil {
+		return StructuredCommit{}, nil, fmt.Errorf("model returned invalid structured output: %w", parseErr)
+	}
+	return commit, usage, nil
+}
+
+// sendStructured sends messages and continues a reply cut off by the output
+// limit. Continuations go without responseFormat, since only the stitched
+// reply is a whole JSON object.
+func sendStructured(ctx context.Context, opts Options, messages []OpenRouterMessage,
+	responseFormat *ResponseFormat) (string, *Usage, error) {
+	reply, err := sendChat(ctx, opts, messages, responseFormat)
+	if err != nil {
+		return "", nil, err
+	}
+	content, usage := continueTruncated(ctx, opts, messages, reply)
+	return strings.TrimSpace(content), usage, nil
+}
+
+// parseStructuredCommit decodes the JSON object in content, tolerating code
+// fences or surrounding text from models without JSON mode
+func parseStructuredCommit(content string) (StructuredCommit, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return StructuredCommit{}, fmt.Errorf("no JSON object found in response")
+	}
+
+	var commit StructuredCommit
+	if err := json.Unmarshal([]byte(content[start:end+1]), &commit); err != nil {
+		return StructuredCommit{}, fmt.Errorf("malformed JSON: %w", err)
+	}
+	if err := commit.Validate(); err != nil {
+		return StructuredCommit{}, err
+	}
+	return commit, nil
+}
@@ -0,0 +1,162 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseStructuredCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string // Formatted message
+		wantErr string
+	}{
+		{
+			name:    "plain object",
+			content: `{"type": "feat", "scope": "api", "subject": "add pagination.", "body": "Pages hold 50 items."}`,
+			want:    "feat(api): add pagination\n\nPages hold 50 items.",
+		},
+		{
+			name:    "fenced without scope or body",
+			content: "```json\n{\"type\": \"fix\", \"scope\": \"\", \"subject\": \"handle empty input\", \"body\": \"\"}\n```",
+			want:    "fix: handle empty input",
+		},
+		{name: "no object", content: "fix: handle empty input", wantErr: "no JSON object"},
+		{name: "malformed", content: `{"type": "fix", "subject": }`, wantErr: "malformed JSON"},
+		{name: "missing fields", content: `{"scope": "api"}`, wantErr: "missing required fields: type, subject"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit, err := parseStructuredCommit(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseStructuredCommit error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseStructuredCommit error = %v", err)
+			}
+			if got := commit.Format(); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// chatRecorder replies with the given contents in turn and records the
+// decoded request bodies
+type chatRecorder struct {
+	replies []string
+	status  []int // Status for each request; 200 if missing
+	bodies  []OpenRouterChatRequest
+}
+
+func (c *chatRecorder) serve(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body OpenRouterChatRequest
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		i := len(c.bodies)
+		c.bodies = append(c.bodies, body)
+		if i < len(c.status) && c.status[i] != http.StatusOK {
+			w.WriteHeader(c.status[i])
+			w.Write([]byte(`{"error": {"message": "response_format is not supported"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{
+				"message":       map[string]string{"role": "assistant", "content": c.replies[min(i, len(c.replies)-1)]},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGenerateStructuredCommitFallsBackWithoutJSONMode(t *testing.T) {
+	recorder := &chatRecorder{
+		replies: []string{`{"type": "fix", "subject": "handle empty input"}`},
+		status:  []int{http.StatusBadRequest},
+	}
+	opts := Options{BaseURL: recorder.serve(t).URL, MaxInputTokens: 1000}
+
+	commit, _, err := GenerateStructuredCommit(context.Background(), opts, "Describe this change")
+	if err != nil {
+		t.Fatalf("GenerateStructuredCommit error = %v", err)
+	}
+	if commit.Format() != "fix: handle empty input" {
+		t.Errorf("message = %q", commit.Format())
+	}
+	if len(recorder.bodies) != 2 || recorder.bodies[0].ResponseFormat == nil || recorder.bodies[1].ResponseFormat != nil {
+		t.Errorf("want a JSON mode request followed by one without response_format, got %d requests", len(recorder.bodies))
+	}
+}
+
+func TestGenerateStructuredCommitRepromptsOnce(t *testing.T) {
+	recorder := &chatRecorder{replies: []string{`{"type": "fix"}`, `{"type": "fix", "subject": "handle empty input"}`}}
+	opts := Options{BaseURL: recorder.serve(t).URL, MaxInputTokens: 1000}
+
+	commit, _, err := GenerateStructuredCommit(context.Background(), opts, "Describe this change")
+	if err != nil {
+		t.Fatalf("GenerateStructuredCommit error = %v", err)
+	}
+	if commit.Subject != "handle empty input" || len(recorder.bodies) != 2 {
+		t.Fatalf("got %+v after %d requests", commit, len(recorder.bodies))
+	}
+	retry := recorder.bodies[1].Messages
+	if last := retry[len(retry)-1]; last.Role != "user" || !strings.Contains(last.Content, "missing required fields: subject") {
+		t.Errorf("re-prompt = %+v, want the parse error as a user message", last)
+	}
+}
+
+func TestGenerateStructuredCommitMatchesPlainLayout(t *testing.T) {
+	prompt := "Write a commit message.\n\n" + diffFenceStart + "+added line" + diffFenceEnd + "\nRules: be brief."
+	examples := []OpenRouterMessage{{Role: "user", Content: "+example diff"}, {Role: "assistant", Content: "feat: example"}}
+
+	for _, role := range []string{"user", "system"} {
+		t.Run(role, func(t *testing.T) {
+			recorder := &chatRecorder{replies: []string{`{"type": "feat", "subject": "add line"}`}}
+			opts := Options{BaseURL: recorder.serve(t).URL, MaxInputTokens: 1000, PromptRole: role, Examples: examples}
+			if _, _, err := GenerateCommitMessage(context.Background(), opts, prompt); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := GenerateStructuredCommit(context.Background(), opts, prompt); err != nil {
+				t.Fatal(err)
+			}
+
+			plain, structured := recorder.bodies[0].Messages, recorder.bodies[1].Messages
+			if len(plain) != len(structured) {
+				t.Fatalf("plain mode sent %d messages, structured mode %d", len(plain), len(structured))
+			}
+			for i := range plain {
+				if plain[i].Role != structured[i].Role {
+					t.Errorf("message %d role: plain %s, structured %s", i, plain[i].Role, structured[i].Role)
+				}
+			}
+			instructions, examplesAt := structured[len(structured)-1], 0
+			if role == "system" {
+				instructions, examplesAt = structured[0], 1
+				if last := structured[len(structured)-1]; last.Content != "+added line" {
+					t.Errorf("user message = %q, want only the diff", last.Content)
+				}
+			}
+			if structured[examplesAt] != examples[0] || structured[examplesAt+1] != examples[1] {
+				t.Errorf("structured mode did not send the examples: %+v", structured)
+			}
+			if !strings.Contains(instructions.Content, "Respond ONLY with a JSON object") {
+				t.Errorf("JSON instructions are not with the other instructions in the %s message", instructions.Role)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyNone disables proxy use entirely, including HTTP_PROXY/HTTPS_PROXY
+const ProxyNone = "none"
+
+// ProxyFunc returns the proxy selector for a configured proxy setting. An empty
+// setting defers to the HTTP_PROXY/HTTPS_PROXY environment, ProxyNone
+// disables proxies, and anything else must be a proxy URL.
+func ProxyFunc(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	switch strings.ToLower(strings.TrimSpace(proxy)) {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case ProxyNone:
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL '%s'", proxy)
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
+// newHTTPClient builds the client used for API requests with the proxy applied
+func newHTTPClient(proxy string) (*http.Client, error) {
+	proxyFunc, err := ProxyFunc(proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc
+	return &http.Client{Transport: transport}, nil
+}
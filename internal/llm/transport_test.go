@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	request, err := http.NewRequest(http.MethodPost, "https://openrouter.ai/api/v1/chat/completions", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		proxy   string
+		want    string // Empty for no proxy, "env" for the environment's
+		wantErr bool
+	}{
+		{"http://proxy.corp:8080", "http://proxy.corp:8080", false},
+		{"none", "", false},
+		{"NONE", "", false},
+		{"", "env", false},
+		{"proxy.corp:8080", "", true},
+		{"://bad", "", true},
+	}
+	for _, tt := range tests {
+		client, err := newHTTPClient(tt.proxy)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newHTTPClient(%q) error = %v, wantErr %v", tt.proxy, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+
+		proxyFunc := client.Transport.(*http.Transport).Proxy
+		got := ""
+		// The environment is read once per process, so compare the function
+		if proxyFunc != nil && reflect.ValueOf(proxyFunc).Pointer() == reflect.ValueOf(http.ProxyFromEnvironment).Pointer() {
+			got = "env"
+		} else if proxyFunc != nil {
+			proxyURL, err := proxyFunc(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proxyURL != nil {
+				got = proxyURL.String()
+			}
+		}
+		if got != tt.want {
+			t.Errorf("newHTTPClient(%q) proxies through %q, want %q", tt.proxy, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"strings"
+
+	"github.com/cstobie/ai-commit/internal/tokenizer"
+)
+
+// TruncateDiff truncates diff to fit within maxTokens as counted by tok.
+// Whole lines are kept from its start and end around a TruncationMarker
+// line. The end part starts at a file or hunk header, or is left out if it
+// would have none, so the model still sees the diff's line structure and no
+// hunk without its header.
+func TruncateDiff(diff string, maxTokens int, tok tokenizer.Tokenizer) (string, bool) {
+	tokens := tokenizer.Count(tok, diff)
+	if tokens <= maxTokens {
+		return diff, false
+	}
+
+	lines := strings.SplitAfter(diff, "\n")
+	marker := TruncationMarker + "\n"
+	budget := maxTokens - tokenizer.Count(tok, marker)
+	for budget > 0 {
+		// The end gets up to half the budget, from the first header in it
+		tail, tailUsed := len(lines), 0
+		for tail > 0 {
+			n := tokenizer.Count(tok, lines[tail-1])
+			if tailUsed+n > budget/2 {
+				break
+			}
+			tailUsed += n
+			tail--
+		}
+		for tail < len(lines) && !isDiffHeader(lines[tail]) {
+			tailUsed -= tokenizer.Count(tok, lines[tail])
+			tail++
+		}
+
+		// The start gets the rest
+		head, used := 0, tailUsed
+		for ; head < tail; head++ {
+			n := tokenizer.Count(tok, lines[head])
+			if used+n > budget {
+				break
+			}
+			used += n
+		}
+
+		truncated := strings.Join(lines[:head], "") + marker + strings.Join(lines[tail:], "")
+		// Counts of single lines can add up to less than the count of the
+		// joined text, so check and retry with what is left
+		over := tokenizer.Count(tok, truncated) - maxTokens
+		if over <= 0 {
+			return truncated, true
+		}
+		budget -= over
+	}
+	return TruncationMarker, true
+}
+
+// isDiffHeader reports whether line starts a file or a hunk in a unified diff
+func isDiffHeader(line string) bool {
+	return strings.HasPrefix(line, "diff --git ") || strings.HasPrefix(line, "@@ ")
+}
+
+// fitPrompt truncates prompt to fit within maxTokens. Only the diff in its
+// fenced diff block is cut, so the instructions around it and any feedback
+// appended for a retry are kept; a prompt without a diff block, or whose
+// instructions alone are too long, is cut as a whole with TruncateInput.
+func fitPrompt(prompt string, maxTokens int, tok tokenizer.Tokenizer) (string, bool) {
+	overflow := tokenizer.Count(tok, prompt) - maxTokens
+	if overflow <= 0 {
+		return prompt, false
+	}
+	if start := strings.Index(prompt, diffFenceStart); start >= 0 {
+		diffStart := start + len(diffFenceStart)
+		if end := strings.Index(prompt[diffStart:], diffFenceEnd); end >= 0 {
+			diff := prompt[diffStart : diffStart+end]
+			if budget := tokenizer.Count(tok, diff) - overflow; budget > 0 {
+				truncated, _ := TruncateDiff(diff, budget, tok)
+				fitted := prompt[:diffStart] + truncated + prompt[diffStart+end:]
+				if tokenizer.Count(tok, fitted) <= maxTokens {
+					return fitted, true
+				}
+			}
+		}
+	}
+	return TruncateInput(prompt, maxTokens, tok)
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cstobie/ai-commit/internal/tokenizer"
+)
+
+// longDiff returns a diff of files files with a hunk of lines lines each
+func longDiff(files, lines int) string {
+	var b strings.Builder
+	for f := 0; f < files; f++ {
+		fmt.Fprintf(&b, "diff --git a/file%d.go b/file%d.go\n--- a/file%d.go\n+++ b/file%d.go\n@@ -1,%d +1,%d @@\n",
+			f, f, f, f, lines, lines)
+		for l := 0; l < lines; l++ {
+			fmt.Fprintf(&b, "+line %d of file %d\n", l, f)
+		}
+	}
+	return b.String()
+}
+
+func TestTruncateDiff(t *testing.T) {
+	diff := longDiff(20, 30)
+	for _, tok := range []tokenizer.Tokenizer{tokenizer.Chars{}, tokenizer.Words{}} {
+		for _, maxTokens := range []int{50, 300, 1000} {
+			t.Run(fmt.Sprintf("%T/%d", tok, maxTokens), func(t *testing.T) {
+				got, truncated := TruncateDiff(diff, maxTokens, tok)
+				if !truncated {
+					t.Fatal("TruncateDiff did not truncate")
+				}
+				if n := tok.Count(got); n > maxTokens {
+					t.Errorf("truncated diff has %d tokens, want at most %d", n, maxTokens)
+				}
+
+				head, tail, ok := strings.Cut(got, "\n"+TruncationMarker+"\n")
+				if !ok {
+					t.Fatalf("truncated diff has no marker line:\n%s", got)
+				}
+				if !strings.HasPrefix(diff, head+"\n") {
+					t.Errorf("start of the truncated diff is not whole lines of the diff:\n%s", head)
+				}
+				if !strings.HasSuffix(diff, tail) {
+					t.Errorf("end of the truncated diff is not whole lines of the diff:\n%s", tail)
+				}
+				if tail != "" && !isDiffHeader(tail) {
+					t.Errorf("end of the truncated diff starts mid-hunk: %q", tail[:min(len(tail), 40)])
+				}
+			})
+		}
+	}
+}
+
+func TestTruncateDiffFits(t *testing.T) {
+	diff := longDiff(1, 3)
+	got, truncated := TruncateDiff(diff, 1000, tokenizer.Words{})
+	if truncated || got != diff {
+		t.Errorf("TruncateDiff changed a diff within the budget: %q", got)
+	}
+}
+
+func TestFitPromptKeepsInstructions(t *testing.T) {
+	const instructions = "Write a commit message for this diff:\n\n"
+	const rules = "\nRules:\n1. Use the imperative mood.\n2. Keep the subject under 50 characters.\n"
+	const feedback = "\n\nYour previous commit message was rejected because it does not match the pattern."
+	prompt := instructions + diffFenceStart + longDiff(10, 40) + diffFenceEnd + rules + feedback
+
+	tok := tokenizer.Words{}
+	got, truncated := fitPrompt(prompt, 400, tok)
+	if !truncated {
+		t.Fatal("fitPrompt did not truncate")
+	}
+	if n := tok.Count(got); n > 400 {
+		t.Errorf("prompt has %d tokens, want at most 400", n)
+	}
+	for _, part := range []string{instructions + diffFenceStart, diffFenceEnd + rules + feedback, "\n" + TruncationMarker + "\n"} {
+		if !strings.Contains(got, part) {
+			t.Errorf("fitted prompt lost %q:\n%s", part, got)
+		}
+	}
+}
+
+func TestFitPromptWithoutDiffBlock(t *testing.T) {
+	prompt := strings.Repeat("word ", 100)
+	got, truncated := fitPrompt(prompt, 20, tokenizer.Words{})
+	if !truncated || !strings.Contains(got, TruncationMarker) || (tokenizer.Words{}).Count(got) > 20 {
+		t.Errorf("fitPrompt(%d words, 20) = %q, %v", 100, got, truncated)
+	}
+}
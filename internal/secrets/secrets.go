@@ -0,0 +1,230 @@
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Pattern is a named regular expression matching a kind of secret
+type Pattern struct {
+	Name  string
+	Regex *regexp.Regexp
+	// Ignore, if set, drops matches that are not secrets after all
+	Ignore func(match string) bool
+}
+
+// DefaultPatterns are the secret patterns checked by every Scanner
+var DefaultPatterns = []Pattern{
+	{Name: "AWS access key", Regex: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "private key", Regex: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`)},
+	{Name: "GitHub token", Regex: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{Name: "Slack token", Regex: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{Name: "secret assignment", Regex: regexp.MustCompile(
+		`(?i)\b[a-z0-9_.-]*(?:api[_-]?key|secret|token|passw(?:or)?d)[a-z0-9_.-]*["']?\s*(?::=|[:=])\s*` +
+			`(?:"[^\s"]{8,}"?|'[^\s']{8,}'?|[^\s"'.(]{8,}[.(]?)`),
+		Ignore: isCodeValue},
+}
+
+// isCodeValue reports whether a secret assignment assigns code rather than a
+// literal: an unquoted value followed by a field access or a call, like
+// settings.Password or readPassword(prompt)
+func isCodeValue(match string) bool {
+	return strings.HasSuffix(match, ".") || strings.HasSuffix(match, "(")
+}
+
+// highEntropyToken matches candidate tokens for the entropy check
+var highEntropyToken = regexp.MustCompile(`[A-Za-z0-9+/=_-]{32,}`)
+
+// minTokenEntropy is the Shannon entropy in bits per character above which a
+// long token is reported as a likely secret
+const minTokenEntropy = 4.5
+
+// ignoredFiles hold long random-looking hashes that are not secrets
+var ignoredFiles = []string{"go.sum", "package-lock.json", "yarn.lock", "pnpm-lock.yaml", "Cargo.lock", "poetry.lock"}
+
+// Finding is a likely secret on an added line of a diff
+type Finding struct {
+	Pattern string // Name of the matching pattern
+	Path    string // File the line was added to
+	Line    int    // Line number in the new file, 0 if unknown
+	Match   string // The matched text, redacted
+}
+
+func (f Finding) String() string {
+	location := f.Path
+	if f.Line > 0 {
+		location += ":" + strconv.Itoa(f.Line)
+	}
+	return fmt.Sprintf("%s: %s (%s)", location, f.Pattern, f.Match)
+}
+
+// Scanner checks diffs for likely secrets
+type Scanner struct {
+	patterns  []Pattern
+	allowlist []*regexp.Regexp
+}
+
+// NewScanner returns a Scanner using DefaultPatterns plus the extra patterns.
+// Matches, or files, matching an allowlist expression are not reported.
+func NewScanner(extra []string, allowlist []string) (*Scanner, error) {
+	s := &Scanner{patterns: append([]Pattern(nil), DefaultPatterns...)}
+	for _, expr := range extra {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret pattern %q: %w", expr, err)
+		}
+		s.patterns = append(s.patterns, Pattern{Name: "custom pattern", Regex: re})
+	}
+	for _, expr := range allowlist {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret allowlist entry %q: %w", expr, err)
+		}
+		s.allowlist = append(s.allowlist, re)
+	}
+	return s, nil
+}
+
+// hunkHeaderRegex captures the starting line in the new file from a hunk header
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)`)
+
+// Scan reports likely secrets on the lines added by a unified diff
+func (s *Scanner) Scan(diff string) []Finding {
+	var findings []Finding
+	var path string
+	line := 0
+	for _, text := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			path = strings.TrimPrefix(strings.TrimPrefix(text, "+++ "), "b/")
+			line = 0
+		case strings.HasPrefix(text, "@@"):
+			if m := hunkHeaderRegex.FindStringSubmatch(text); m != nil {
+				line, _ = strconv.Atoi(m[1])
+			}
+		case strings.HasPrefix(text, "+"):
+			if !s.allowed(path) && !isIgnoredFile(path) {
+				findings = append(findings, s.scanLine(text[1:], path, line)...)
+			}
+			if line > 0 {
+				line++
+			}
+		case strings.HasPrefix(text, " "):
+			if line > 0 {
+				line++
+			}
+		}
+	}
+	return findings
+}
+
+// Redact masks every match of the secret patterns and every high-entropy
+// token in text, on any line, so text can be shown or saved safely.
+// Allowlisted matches are kept.
+func (s *Scanner) Redact(text string) string {
+	mask := func(match string) string {
+		if s.allowed(match) {
+			return match
+		}
+		return redact(match)
+	}
+	for _, pattern := range s.patterns {
+		text = pattern.Regex.ReplaceAllStringFunc(text, func(match string) string {
+			if pattern.Ignore != nil && pattern.Ignore(match) {
+				return match
+			}
+			return mask(match)
+		})
+	}
+	return highEntropyToken.ReplaceAllStringFunc(text, func(token string) string {
+		if entropy(token) < minTokenEntropy {
+			return token
+		}
+		return mask(token)
+	})
+}
+
+// scanLine reports the secrets on one added line
+func (s *Scanner) scanLine(text, path string, line int) []Finding {
+	var findings []Finding
+	for _, pattern := range s.patterns {
+		for _, match := range pattern.Regex.FindAllString(text, -1) {
+			if pattern.Ignore != nil && pattern.Ignore(match) {
+				continue
+			}
+			if !s.allowed(match) {
+				findings = append(findings, Finding{Pattern: pattern.Name, Path: path, Line: line, Match: redact(match)})
+			}
+		}
+	}
+	if len(findings) > 0 {
+		return findings
+	}
+
+	for _, token := range highEntropyToken.FindAllString(text, -1) {
+		if entropy(token) >= minTokenEntropy && !s.allowed(token) {
+			findings = append(findings, Finding{Pattern: "high-entropy string", Path: path, Line: line, Match: redact(token)})
+		}
+	}
+	return findings
+}
+
+// allowed reports whether text matches the allowlist
+func (s *Scanner) allowed(text string) bool {
+	for _, re := range s.allowlist {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIgnoredFile reports whether path is a lock file full of hashes
+func isIgnoredFile(path string) bool {
+	name := path[strings.LastIndex(path, "/")+1:]
+	for _, ignored := range ignoredFiles {
+		if name == ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	var bits float64
+	n := float64(len(s))
+	for _, count := range counts {
+		p := float64(count) / n
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
+
+// redact keeps only the first 4 characters of a match so findings can be
+// shown without repeating the secret
+func redact(match string) string {
+	if len(match) <= 8 {
+		return strings.Repeat("*", len(match))
+	}
+	return match[:4] + strings.Repeat("*", len(match)-4)
+}
+
+// RedactKey masks all but the last 4 characters of a secret. Short keys are
+// fully masked.
+func RedactKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", len(key)-4) + key[len(key)-4:]
+}
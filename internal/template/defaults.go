@@ -0,0 +1,89 @@
+package template
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Markers of the optional config block at the very start of a template, e.g.
+// {{/* config: max_output_tokens=400 temperature=0.3 */}}
+const (
+	frontMatterStart = "{{/* config:"
+	frontMatterEnd   = "*/}}"
+)
+
+// Defaults are settings a template suggests for itself. Zero values mean the
+// template does not set them.
+type Defaults struct {
+	Model           string
+	MaxOutputTokens int
+	Temperature     *float64
+}
+
+// LoadDefaults returns the settings from the config block of the named
+// template, or zero Defaults if it has none
+func LoadDefaults(templateName string) (Defaults, error) {
+	templateContent, err := templateFS.ReadFile(fmt.Sprintf("templates/%s.tmpl", templateName))
+	if err != nil {
+		return Defaults{}, fmt.Errorf("failed to load template '%s': %w", templateName, err)
+	}
+
+	frontMatter, _, err := splitFrontMatter(string(templateContent))
+	if err != nil {
+		return Defaults{}, fmt.Errorf("template '%s': %w", templateName, err)
+	}
+	defaults, err := ParseDefaults(frontMatter)
+	if err != nil {
+		return Defaults{}, fmt.Errorf("template '%s': %w", templateName, err)
+	}
+	return defaults, nil
+}
+
+// splitFrontMatter separates the config block, without its markers, from
+// the rest of the template. Templates without a config block are returned
+// unchanged with an empty front matter.
+func splitFrontMatter(content string) (string, string, error) {
+	if !strings.HasPrefix(content, frontMatterStart) {
+		return "", content, nil
+	}
+	end := strings.Index(content, frontMatterEnd)
+	if end < 0 {
+		return "", "", fmt.Errorf("config block is not closed with %q", frontMatterEnd)
+	}
+	frontMatter := content[len(frontMatterStart):end]
+	body := strings.TrimPrefix(content[end+len(frontMatterEnd):], "\n")
+	return frontMatter, body, nil
+}
+
+// ParseDefaults parses whitespace-separated key=value settings. Supported
+// keys are model, max_output_tokens and temperature.
+func ParseDefaults(frontMatter string) (Defaults, error) {
+	var defaults Defaults
+	for _, field := range strings.Fields(frontMatter) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || value == "" {
+			return Defaults{}, fmt.Errorf("invalid config setting '%s': expected key=value", field)
+		}
+
+		switch key {
+		case "model":
+			defaults.Model = value
+		case "max_output_tokens":
+			tokens, err := strconv.Atoi(value)
+			if err != nil || tokens <= 0 {
+				return Defaults{}, fmt.Errorf("invalid max_output_tokens '%s': must be a positive integer", value)
+			}
+			defaults.MaxOutputTokens = tokens
+		case "temperature":
+			temperature, err := strconv.ParseFloat(value, 64)
+			if err != nil || temperature < 0 || temperature > 2 {
+				return Defaults{}, fmt.Errorf("invalid temperature '%s': must be between 0 and 2", value)
+			}
+			defaults.Temperature = &temperature
+		default:
+			return Defaults{}, fmt.Errorf("unknown config setting '%s'", key)
+		}
+	}
+	return defaults, nil
+}
@@ -0,0 +1,120 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDefaults(t *testing.T) {
+	tests := []struct {
+		name        string
+		frontMatter string
+		want        Defaults
+		temperature float64 // Expected *want.Temperature, if set
+		wantErr     string
+	}{
+		{name: "empty", frontMatter: "  "},
+		{
+			name:        "all settings",
+			frontMatter: " model=openai/gpt-4o max_output_tokens=400\ttemperature=0.3 ",
+			want:        Defaults{Model: "openai/gpt-4o", MaxOutputTokens: 400, Temperature: new(float64)},
+			temperature: 0.3,
+		},
+		{name: "missing value", frontMatter: "model=", wantErr: "expected key=value"},
+		{name: "missing equals", frontMatter: "temperature", wantErr: "expected key=value"},
+		{name: "unknown key", frontMatter: "top_p=0.9", wantErr: "unknown config setting 'top_p'"},
+		{name: "zero tokens", frontMatter: "max_output_tokens=0", wantErr: "positive integer"},
+		{name: "non-numeric tokens", frontMatter: "max_output_tokens=lots", wantErr: "positive integer"},
+		{name: "temperature out of range", frontMatter: "temperature=2.5", wantErr: "between 0 and 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDefaults(tt.frontMatter)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseDefaults(%q) error = %v, want one containing %q", tt.frontMatter, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDefaults(%q) error = %v", tt.frontMatter, err)
+			}
+			if got.Model != tt.want.Model || got.MaxOutputTokens != tt.want.MaxOutputTokens {
+				t.Errorf("ParseDefaults(%q) = %+v, want %+v", tt.frontMatter, got, tt.want)
+			}
+			if (got.Temperature == nil) != (tt.want.Temperature == nil) ||
+				(got.Temperature != nil && *got.Temperature != tt.temperature) {
+				t.Errorf("ParseDefaults(%q) temperature = %v, want %v", tt.frontMatter, got.Temperature, tt.temperature)
+			}
+		})
+	}
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		wantFrontMatter string
+		wantBody        string
+		wantErr         bool
+	}{
+		{name: "none", content: "Describe {{.Diff}}", wantBody: "Describe {{.Diff}}"},
+		{
+			name:            "block",
+			content:         "{{/* config: temperature=0.3 */}}\nDescribe {{.Diff}}",
+			wantFrontMatter: " temperature=0.3 ",
+			wantBody:        "Describe {{.Diff}}",
+		},
+		{name: "not at the start", content: "x{{/* config: model=m */}}", wantBody: "x{{/* config: model=m */}}"},
+		{name: "unclosed", content: "{{/* config: model=m\nDescribe", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frontMatter, body, err := splitFrontMatter(tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("splitFrontMatter(%q) succeeded, want an error", tt.content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitFrontMatter(%q) error = %v", tt.content, err)
+			}
+			if frontMatter != tt.wantFrontMatter || body != tt.wantBody {
+				t.Errorf("splitFrontMatter(%q) = %q, %q, want %q, %q",
+					tt.content, frontMatter, body, tt.wantFrontMatter, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestExplainTemplateDefaults(t *testing.T) {
+	defaults, err := LoadDefaults("explain")
+	if err != nil {
+		t.Fatalf("LoadDefaults(explain) error = %v", err)
+	}
+	if defaults.Temperature == nil || *defaults.Temperature != 0.3 {
+		t.Errorf("explain temperature = %v, want 0.3", defaults.Temperature)
+	}
+
+	prompt, err := Execute("explain", Data{Diff: "+added"})
+	if err != nil {
+		t.Fatalf("Execute(explain) error = %v", err)
+	}
+	if strings.Contains(prompt, "config:") || !strings.HasPrefix(prompt, "Explain") {
+		t.Errorf("explain prompt starts with %q, want the config block left out", prompt[:min(len(prompt), 40)])
+	}
+}
+
+func TestBuiltinTemplateDefaultsParse(t *testing.T) {
+	entries, err := templateFS.ReadDir("templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		if _, err := LoadDefaults(name); err != nil {
+			t.Errorf("LoadDefaults(%s) error = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package template
+
+import (
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Funcs are the helper functions available in templates, a subset of the
+// Sprig library with the same names and argument order, so values can be
+// piped in as the last argument: {{.Scope | upper}}, {{.Diff | trunc 200}}.
+// None of them do I/O or read the environment.
+var Funcs = template.FuncMap{
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"title":      title,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"splitList":  func(sep, s string) []string { return strings.Split(s, sep) },
+	"join":       func(sep string, list []string) string { return strings.Join(list, sep) },
+	"first":      first,
+	"last":       last,
+	"trunc":      trunc,
+	"indent":     indent,
+	"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+	"quote":      strconv.Quote,
+	"default":    defaultValue,
+}
+
+// title capitalizes the first letter of each word
+func title(s string) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		word := unicode.IsSpace(prev)
+		prev = r
+		if word {
+			return unicode.ToUpper(r)
+		}
+		return r
+	}, s)
+}
+
+// first returns the first element of list, or an empty string
+func first(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	return list[0]
+}
+
+// last returns the last element of list, or an empty string
+func last(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	return list[len(list)-1]
+}
+
+// trunc keeps the first n characters of s, or the last -n if n is negative
+func trunc(n int, s string) string {
+	runes := []rune(s)
+	count := len(runes)
+	switch {
+	case n >= 0 && n < count:
+		return string(runes[:n])
+	case n < 0 && -n < count:
+		return string(runes[count+n:])
+	}
+	return s
+}
+
+// indent prefixes every line of s with n spaces
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", max(n, 0))
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// defaultValue returns value, or def if value is empty: an empty string or
+// list, zero, false or nil
+func defaultValue(def, value any) any {
+	switch v := value.(type) {
+	case nil:
+		return def
+	case string:
+		if v == "" {
+			return def
+		}
+	case []string:
+		if len(v) == 0 {
+			return def
+		}
+	case int:
+		if v == 0 {
+			return def
+		}
+	case bool:
+		if !v {
+			return def
+		}
+	}
+	return value
+}
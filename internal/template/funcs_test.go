@@ -0,0 +1,49 @@
+package template
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestFuncs(t *testing.T) {
+	data := Data{Diff: "+added line\n-removed line", Files: []string{"web/app.ts", "api/server.go"}}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"upper", `{{"feat" | upper}}`, "FEAT"},
+		{"title", `{{title "add login form"}}`, "Add Login Form"},
+		{"trimPrefix", `{{"web/app.ts" | trimPrefix "web/"}}`, "app.ts"},
+		{"splitList and first", `{{splitList "/" "web/src/app.ts" | first}}`, "web"},
+		{"splitList and last", `{{splitList "/" "web/src/app.ts" | last}}`, "app.ts"},
+		{"join", `{{join ", " .Files}}`, "web/app.ts, api/server.go"},
+		{"trunc", `{{.Diff | trunc 6}}`, "+added"},
+		{"trunc from the end", `{{.Diff | trunc -4}}`, "line"},
+		{"trunc shorter text", `{{"ab" | trunc 5}}`, "ab"},
+		{"indent", `{{.Diff | indent 2}}`, "  +added line\n  -removed line"},
+		{"nindent", `x{{"a" | nindent 2}}`, "x\n  a"},
+		{"quote", `{{quote "say \"hi\""}}`, `"say \"hi\""`},
+		{"default on empty", `{{.Hint | default "no hint"}}`, "no hint"},
+		{"default on value", `{{"set" | default "no hint"}}`, "set"},
+		{"default on empty list", `{{len (default (splitList "," "a") .RecentCommits)}}`, "1"},
+		{"contains", `{{if contains "removed" .Diff}}yes{{end}}`, "yes"},
+		{"replace", `{{replace "-" "+" "a-b-c"}}`, "a+b+c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New("test").Funcs(Funcs).Parse(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			if err := tmpl.Execute(&out, data); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("%s = %q, want %q", tt.text, out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// smartCommitTemplate is the template formatting a message as a Jira smart
+// commit
+const smartCommitTemplate = "smart-commit"
+
+// SmartCommitVars are the variables available in the smart commit template
+type SmartCommitVars struct {
+	Issue   string // Jira issue key, e.g. PROJ-123
+	Subject string // First line of the generated message
+	Body    string // Rest of the message, without the blank line after the subject
+}
+
+// SmartCommit formats message as a Jira smart commit for issue, e.g.
+// "PROJ-123 #comment feat: add login", so Jira adds the subject as a comment
+// on the issue
+func SmartCommit(issue, message string) (string, error) {
+	subject, body, _ := strings.Cut(message, "\n")
+	content, err := templateFS.ReadFile(fmt.Sprintf("templates/%s.tmpl", smartCommitTemplate))
+	if err != nil {
+		return "", fmt.Errorf("failed to load smart commit template: %w", err)
+	}
+	tmpl, err := template.New(smartCommitTemplate).Funcs(Funcs).Parse(string(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse smart commit template: %w", err)
+	}
+	var sb strings.Builder
+	vars := SmartCommitVars{Issue: issue, Subject: strings.TrimSpace(subject), Body: strings.TrimSpace(body)}
+	if err := tmpl.Execute(&sb, vars); err != nil {
+		return "", fmt.Errorf("failed to execute smart commit template: %w", err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
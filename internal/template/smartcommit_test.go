@@ -0,0 +1,25 @@
+package template
+
+import "testing"
+
+func TestSmartCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"subject only", "feat: add login", "PROJ-123 #comment feat: add login"},
+		{"with body", "feat: add login\n\nAdds a login form.\n", "PROJ-123 #comment feat: add login\n\nAdds a login form."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SmartCommit("PROJ-123", tt.message)
+			if err != nil {
+				t.Fatalf("SmartCommit error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SmartCommit() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
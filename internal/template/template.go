@@ -10,8 +10,85 @@ import (
 //go:embed templates
 var templateFS embed.FS
 
+// Data is the data available to templates
+type Data struct {
+	Diff  string   // Staged diff or smart-diff summary
+	Files []string // Paths of the files the template should cover, if any
+	// "git diff --stat" summary shown before the diff; empty if not available
+	DiffStat string
+	// Why the change was made, in the author's words; empty if not given
+	Hint string
+
+	// Token budgets for the subject line and body; zero if not configured
+	SubjectMaxTokens int
+	BodyMaxTokens    int
+
+	// Number of changed files and the directory they share, for stats.tmpl
+	FileCount int
+	Scope     string
+
+	// Conventional type implied by the kind of files changed, e.g. docs when
+	// only documentation changed; empty for mixed changes
+	SuggestedType string
+	// Scopes the message may use, and the one the changed directories map to;
+	// empty if scopes are not restricted
+	AllowedScopes  []string
+	SuggestedScope string
+
+	// The diff removes or changes a public declaration, so the change may be
+	// breaking
+	PossibleBreaking bool
+	// Declarations the diff adds, each followed by its file
+	APIAdditions []string
+
+	// Rules from the repository's commitlint config, one instruction each
+	LintRules []string
+
+	// Commit guidelines from the repository, e.g. .gitmessage, trimmed to a budget
+	Guidelines string
+
+	// Messages of the commits being squashed into one, oldest first
+	SquashedCommits []string
+
+	// Recent commit messages from the repository, newest first, as style examples
+	RecentCommits []string
+
+	// Natural language for the output; empty means English
+	Language string
+	// Translate the conventional commit type and scope too, not just the text
+	LocalizeType bool
+}
+
 // LoadAndExecuteTemplate loads and executes a template with the given diff data
 func LoadAndExecuteTemplate(templateName string, diffData string) (string, error) {
+	return Execute(templateName, Data{Diff: diffData})
+}
+
+// Exists reports whether a built-in template with the given name exists
+func Exists(templateName string) bool {
+	_, err := templateFS.ReadFile(fmt.Sprintf("templates/%s.tmpl", templateName))
+	return err == nil
+}
+
+// auxiliaryTemplates are used by other commands and modes, not as the
+// commit message template
+var auxiliaryTemplates = map[string]bool{"bullets": true, "explain": true, "pr": true, "smart-commit": true, "stats": true}
+
+// Names returns the names of the built-in commit message templates, sorted
+func Names() []string {
+	entries, _ := templateFS.ReadDir("templates")
+	var names []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".tmpl")
+		if ok && !auxiliaryTemplates[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Execute loads the named template and executes it with data
+func Execute(templateName string, data Data) (string, error) {
 	// Construct the template path
 	templatePath := fmt.Sprintf("templates/%s.tmpl", templateName)
 	
@@ -21,17 +98,16 @@ func LoadAndExecuteTemplate(templateName string, diffData string) (string, error
 		return "", fmt.Errorf("failed to load template '%s': %w", templateName, err)
 	}
 	
-	// Parse the template
-	tmpl, err := template.New("commit").Parse(string(templateContent))
+	// The config block is read by LoadDefaults, not rendered
+	_, body, err := splitFrontMatter(string(templateContent))
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
-	
-	// Define data to pass to the template
-	data := struct {
-		Diff string
-	}{
-		Diff: diffData,
+
+	// Parse the template
+	tmpl, err := template.New("commit").Funcs(Funcs).Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
 	
 	// Execute the template
@@ -41,4 +117,4 @@ func LoadAndExecuteTemplate(templateName string, diffData string) (string, error
 	}
 	
 	return builder.String(), nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,79 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExecuteLanguage(t *testing.T) {
+	const keepPrefix = `Keep the type and scope prefix (e.g. "feat(api):") in English.`
+	tests := []struct {
+		name       string
+		template   string
+		data       Data
+		want       string // Language instruction; empty for none
+		wantPrefix bool
+	}{
+		{"default is English", "conventional", Data{Diff: "d"}, "", false},
+		{"language", "conventional", Data{Diff: "d", Language: "Japanese"}, "Write the commit message in Japanese.", true},
+		{"localized type", "angular", Data{Diff: "d", Language: "Japanese", LocalizeType: true}, "Write the commit message in Japanese.", false},
+		{"template without types", "simple", Data{Diff: "d", Language: "German"}, "Write the commit message in German.", false},
+		{"pull request", "pr", Data{Diff: "d", Language: "French"}, "Write the title and description in French.", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt, err := Execute(tt.template, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" && strings.Contains(prompt, "Write the commit message in") {
+				t.Errorf("prompt has a language instruction:\n%s", prompt)
+			}
+			if tt.want != "" && !strings.Contains(prompt, tt.want) {
+				t.Errorf("prompt has no %q:\n%s", tt.want, prompt)
+			}
+			if got := strings.Contains(prompt, keepPrefix); got != tt.wantPrefix {
+				t.Errorf("prompt keeps English prefix = %v, want %v:\n%s", got, tt.wantPrefix, prompt)
+			}
+		})
+	}
+}
+
+func TestExecuteHint(t *testing.T) {
+	const hint = "Switch to the new auth service before the old one is retired"
+	for _, name := range []string{"conventional", "angular", "karma", "simple", "subject-only", "pr"} {
+		t.Run(name, func(t *testing.T) {
+			prompt, err := Execute(name, Data{Diff: "d", Hint: hint})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(prompt, "Author's intent: "+hint) {
+				t.Errorf("prompt has no hint:\n%s", prompt)
+			}
+			without, err := Execute(name, Data{Diff: "d"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(without, "Author's intent") {
+				t.Errorf("prompt without a hint mentions one:\n%s", without)
+			}
+		})
+	}
+}
+
+func TestNames(t *testing.T) {
+	names := Names()
+	want := []string{"angular", "conventional", "karma", "simple", "subject-only"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Names() = %v, want %v", names, want)
+	}
+	for _, name := range append(names, "pr", "explain", "smart-commit") {
+		if !Exists(name) {
+			t.Errorf("Exists(%q) = false, want true", name)
+		}
+	}
+	if Exists("missing") {
+		t.Error(`Exists("missing") = true, want false`)
+	}
+}
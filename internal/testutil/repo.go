@@ -0,0 +1,50 @@
+// Package testutil holds fixtures shared by the tests of several packages
+package testutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// NewRepo creates a git repository whose first commit holds files, and
+// returns its root. Global git settings are ignored.
+func NewRepo(t testing.TB, files map[string]string) string {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	dir := t.TempDir()
+	RunGit(t, dir, "init", "--quiet", "--initial-branch=main")
+	RunGit(t, dir, "config", "user.name", "Test")
+	RunGit(t, dir, "config", "user.email", "test@example.com")
+	for path, content := range files {
+		WriteFile(t, dir, path, content)
+	}
+	RunGit(t, dir, "add", "--all")
+	RunGit(t, dir, "commit", "--quiet", "--allow-empty", "-m", "initial")
+	return dir
+}
+
+// RunGit runs git in dir and returns its trimmed output
+func RunGit(t testing.TB, dir string, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// WriteFile writes content to path in dir, creating directories as needed
+func WriteFile(t testing.TB, dir, path, content string) {
+	t.Helper()
+	path = filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
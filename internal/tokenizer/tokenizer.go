@@ -0,0 +1,62 @@
+// Package tokenizer estimates how many tokens a model sees in a text, so that
+// diff budgets and prompt limits are measured the same way everywhere
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tokenizer counts the tokens in a text
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// Names of the available tokenizers
+const (
+	NameChars = "chars"
+	NameWords = "words"
+)
+
+// charsPerToken is the usual rule of thumb for English text and code
+const charsPerToken = 4
+
+// Chars estimates one token per four bytes of text
+type Chars struct{}
+
+func (Chars) Count(text string) int {
+	return len(text) / charsPerToken
+}
+
+// Words counts whitespace-separated words
+type Words struct{}
+
+func (Words) Count(text string) int {
+	return len(strings.Fields(text))
+}
+
+// Default is used when no tokenizer is configured. Counting words is what
+// the input limit has always done; Chars is opt-in.
+var Default Tokenizer = Words{}
+
+// New returns the tokenizer with the given name; an empty name gives Default
+func New(name string) (Tokenizer, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return Default, nil
+	case NameChars:
+		return Chars{}, nil
+	case NameWords:
+		return Words{}, nil
+	default:
+		return nil, fmt.Errorf("unknown tokenizer '%s': must be chars or words", name)
+	}
+}
+
+// Count counts the tokens in text with t, or with Default if t is nil
+func Count(t Tokenizer, text string) int {
+	if t == nil {
+		t = Default
+	}
+	return t.Count(text)
+}
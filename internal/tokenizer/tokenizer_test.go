@@ -0,0 +1,46 @@
+package tokenizer
+
+import "testing"
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name string
+		tok  Tokenizer
+		text string
+		want int
+	}{
+		{"words", Words{}, "func main() {\n\tfmt.Println(x)\n}", 5},
+		{"words empty", Words{}, "", 0},
+		{"chars", Chars{}, "func main() {\n\tfmt.Println(x)\n}", 7},
+		{"chars rounds down", Chars{}, "abc", 0},
+		{"nil is the default", nil, "one two three", 3},
+	}
+	for _, tt := range tests {
+		if got := Count(tt.tok, tt.text); got != tt.want {
+			t.Errorf("%s: Count(%q) = %d, want %d", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Tokenizer
+		wantErr bool
+	}{
+		{"", Words{}, false},
+		{"words", Words{}, false},
+		{"CHARS", Chars{}, false},
+		{"bpe", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := New(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("New(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("New(%q) = %T, want %T", tt.name, got, tt.want)
+		}
+	}
+}
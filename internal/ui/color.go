@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"os"
+	"strings"
+)
+
+// ANSI escape sequences used for colored output
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	ansiCyan  = "\x1b[36m"
+)
+
+// Colors formats output with ANSI colors when enabled and leaves it
+// unchanged otherwise
+type Colors struct {
+	enabled bool
+}
+
+// NewColors returns the colors for output written to f. Colors are off when
+// disabled is true, when the NO_COLOR environment variable is set to a
+// non-empty value (https://no-color.org), or when f is not a terminal.
+func NewColors(f *os.File, disabled bool) Colors {
+	return Colors{enabled: !disabled && os.Getenv("NO_COLOR") == "" && IsTerminal(f)}
+}
+
+// Fence returns the --- line shown around messages
+func (c Colors) Fence() string {
+	return c.wrap(ansiDim, "---")
+}
+
+// Path formats a file path
+func (c Colors) Path(path string) string {
+	return c.wrap(ansiCyan, path)
+}
+
+// Message formats a commit message with its subject line in bold
+func (c Colors) Message(message string) string {
+	subject, body, hasBody := strings.Cut(message, "\n")
+	subject = c.wrap(ansiBold, subject)
+	if !hasBody {
+		return subject
+	}
+	return subject + "\n" + body
+}
+
+// wrap surrounds text with an escape sequence and a reset
+func (c Colors) wrap(code, text string) string {
+	if !c.enabled || text == "" {
+		return text
+	}
+	return code + text + ansiReset
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	prompttemplate "github.com/cstobie/ai-commit/internal/template"
+)
+
+// Output presets for printing a generated message
+const (
+	OutputFenced = "fenced" // Heading and the message between --- lines, the default
+	OutputPlain  = "plain"  // The message alone
+)
+
+// outputPresets maps preset names to their templates
+var outputPresets = map[string]string{
+	OutputFenced: "{{.Heading}}:\n{{.Fence}}\n{{.Message}}\n{{.Fence}}\n",
+	OutputPlain:  "{{.Message}}\n",
+}
+
+// OutputVars are the variables available in output templates
+type OutputVars struct {
+	Heading string // e.g. "Generated commit message"
+	Message string // The message, with its subject in bold when colors are on
+	Fence   string // The --- line
+}
+
+// ParseOutput returns the template of the preset named spec, or parses spec
+// itself as a template. An empty spec selects the fenced preset.
+func ParseOutput(spec string) (*template.Template, error) {
+	if spec == "" {
+		spec = OutputFenced
+	}
+	text, ok := outputPresets[spec]
+	if !ok {
+		text = spec
+	}
+	tmpl, err := template.New("output").Funcs(prompttemplate.Funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OUTPUT_TEMPLATE: %w", err)
+	}
+	// Unknown fields only fail on execution, so catch them before printing
+	if err := tmpl.Execute(io.Discard, OutputVars{}); err != nil {
+		return nil, fmt.Errorf("invalid OUTPUT_TEMPLATE: %w", err)
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOutput(t *testing.T) {
+	vars := OutputVars{Heading: "Generated commit message", Message: "feat: add login\n\nAdds a form.", Fence: "---"}
+	tests := []struct {
+		name    string
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{"default", "", "Generated commit message:\n---\nfeat: add login\n\nAdds a form.\n---\n", false},
+		{"fenced", OutputFenced, "Generated commit message:\n---\nfeat: add login\n\nAdds a form.\n---\n", false},
+		{"plain", OutputPlain, "feat: add login\n\nAdds a form.\n", false},
+		{"custom", "=== {{upper .Heading}} ===\n{{.Message}}\n", "=== GENERATED COMMIT MESSAGE ===\nfeat: add login\n\nAdds a form.\n", false},
+		{"unknown field", "{{.Subject}}", "", true},
+		{"unknown function", "{{shout .Message}}", "", true},
+		{"unclosed action", "{{.Message", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseOutput(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOutput(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var out strings.Builder
+			if err := tmpl.Execute(&out, vars); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
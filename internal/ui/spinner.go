@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// spinnerFrames are the animation frames drawn by the spinner
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// Spinner draws an animated progress line on a terminal while work is in flight
+type Spinner struct {
+	out     io.Writer
+	message string
+	enabled bool
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewSpinner creates a spinner that writes to stderr. It is disabled unless
+// enabled is true and both stdout and stderr are terminals, so piped output
+// is never polluted with control characters.
+func NewSpinner(message string, enabled bool) *Spinner {
+	return &Spinner{
+		out:     os.Stderr,
+		message: message,
+		enabled: enabled && IsTerminal(os.Stdout) && IsTerminal(os.Stderr),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// IsTerminal reports whether f is attached to a terminal
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Start runs the animation on a goroutine until Stop is called or ctx is done
+func (s *Spinner) Start(ctx context.Context) {
+	if !s.enabled {
+		close(s.done)
+		return
+	}
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+
+		for i := 0; ; i++ {
+			fmt.Fprintf(s.out, "\r%s %s", spinnerFrames[i%len(spinnerFrames)], s.message)
+			select {
+			case <-s.stop:
+				s.clear()
+				return
+			case <-ctx.Done():
+				s.clear()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop halts the animation and erases the spinner line. It is safe to call
+// more than once.
+func (s *Spinner) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
+
+// clear erases the current spinner line
+func (s *Spinner) clear() {
+	fmt.Fprintf(s.out, "\r%*s\r", len(s.message)+2, "")
+}
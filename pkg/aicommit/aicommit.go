@@ -0,0 +1,56 @@
+// Package aicommit generates commit messages for staged git changes without
+// the CLI around it: it never prints, prompts or commits.
+//
+//	cfg, err := aicommit.LoadConfig("")
+//	if err != nil {
+//		return err
+//	}
+//	result, err := aicommit.NewGenerator(cfg).Generate(ctx, aicommit.GenerateOptions{Dir: repoDir})
+//	if errors.Is(err, aicommit.ErrNoStagedChanges) {
+//		// Nothing to describe
+//	}
+//
+// Messages that fail the configured checks are returned together with an
+// *InvalidMessageError so callers can decide whether to use them.
+package aicommit
+
+import (
+	"github.com/cstobie/ai-commit/internal/app"
+	"github.com/cstobie/ai-commit/internal/config"
+	"github.com/cstobie/ai-commit/internal/llm"
+)
+
+type (
+	// Config holds the generation settings, normally loaded from AICOMMIT_
+	// environment variables by LoadConfig
+	Config = config.Config
+	// Generator produces commit messages for staged changes
+	Generator = app.Generator
+	// GenerateOptions controls a single call to Generator.Generate
+	GenerateOptions = app.GenerateOptions
+	// GenerateResult is a generated commit message and its metadata
+	GenerateResult = app.GenerateResult
+	// Prepared is a staged diff and prompt that can be reused across calls
+	Prepared = app.Prepared
+	// Usage holds the token counts and cost reported by the API
+	Usage = llm.Usage
+	// InvalidMessageError is returned for messages that look like placeholders
+	InvalidMessageError = app.InvalidMessageError
+)
+
+// ErrNoStagedChanges is returned when there is nothing staged to describe
+var ErrNoStagedChanges = app.ErrNoStagedChanges
+
+// ErrEmptyDiff is returned by Generator.PrepareDiff for an empty diff
+var ErrEmptyDiff = app.ErrEmptyDiff
+
+// LoadConfig reads the configuration the same way the CLI does. An empty
+// envFile loads .env from the current directory if it exists.
+func LoadConfig(envFile string) (Config, error) {
+	return config.LoadConfig(envFile)
+}
+
+// NewGenerator returns a Generator using the given configuration
+func NewGenerator(cfg Config) *Generator {
+	return app.NewGenerator(cfg)
+}